@@ -2,25 +2,41 @@ package tetris
 
 import (
 	"fmt"
+	"math/bits"
 	"strconv"
 	"strings"
 )
 
+// Boards are stored as one bitmask per row where bit n of a row is set when
+// column n is filled.  Rows on boards up to 64 columns wide fit in a single
+// uint64 and the hot paths (collision checks, full line detection and line
+// clearing) operate on whole rows at a time.  Wider boards fall back to
+// several words per row.  Alongside the bitmasks each block records the Cell
+// that filled it, and a Zobrist hash of which blocks are set is kept up to
+// date as they change.
 type Board struct {
 	width  int
 	height int
-	data   [][]bool
+	words  int      // number of uint64 words used by each row
+	full   []uint64 // the mask of a completely filled row, one entry per word
+	data   []uint64 // row-major occupancy bits, words entries per row
+	cells  []Cell   // row-major cell types, width entries per row
+	hash   uint64   // XOR of the zobristKey of every set block
 }
 
+const wordBits = 64
+
 func NewBoard(width, height int) (*Board, error) {
 	if (width < 1) || (height < 1) {
 		err := fmt.Errorf("Width and Height must both be greater than 0")
 		return nil, err
 	}
 
-	board := &Board{width, height, make([][]bool, height)}
-	for i := 0; i < height; i++ {
-		board.data[i] = make([]bool, width)
+	words := (width + wordBits - 1) / wordBits
+	board := &Board{width, height, words, make([]uint64, words), make([]uint64, words*height),
+		make([]Cell, width*height), 0}
+	for col := 0; col < width; col++ {
+		board.full[col/wordBits] |= 1 << uint(col%wordBits)
 	}
 
 	return board, nil
@@ -45,6 +61,76 @@ func (b *Board) checkBlockRange(row, col int) error {
 	return nil
 }
 
+// Returns the words making up the given row.  No bounds checking is done.
+func (b *Board) row(row int) []uint64 {
+	return b.data[row*b.words : (row+1)*b.words]
+}
+
+// Returns whether the given block is set.  No bounds checking is done.
+func (b *Board) isSet(row, col int) bool {
+	return b.data[row*b.words+col/wordBits]&(1<<uint(col%wordBits)) != 0
+}
+
+// Fills the given block with a cell, setting or clearing its occupancy bit to
+// match.  No bounds checking is done.
+func (b *Board) set(row, col int, cell Cell) {
+	idx := row*b.words + col/wordBits
+	bit := uint64(1) << uint(col%wordBits)
+	if cell.Filled() != (b.data[idx]&bit != 0) {
+		b.hash ^= zobristKey(row, col)
+	}
+	if cell.Filled() {
+		b.data[idx] |= bit
+	} else {
+		b.data[idx] &^= bit
+	}
+	b.cells[row*b.width+col] = cell
+}
+
+// Updates the hash for the given row's bits becoming those of words, or all
+// unset if words is nil.  Must be called before the row is changed.
+func (b *Board) rehashRow(row int, words []uint64) {
+	r := b.row(row)
+	for w := range r {
+		diff := r[w]
+		if words != nil {
+			diff ^= words[w]
+		}
+		for ; diff != 0; diff &= diff - 1 {
+			b.hash ^= zobristKey(row, w*wordBits+bits.TrailingZeros64(diff))
+		}
+	}
+}
+
+// Returns the cell used to represent a plain set or unset block.
+func valueCell(value bool) Cell {
+	if value {
+		return CellGarbage
+	}
+	return CellEmpty
+}
+
+// Returns true when no block on the board is set.
+func (b *Board) isEmpty() bool {
+	for _, w := range b.data {
+		if w != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns true when every block in the given row is set.
+func (b *Board) rowFull(row int) bool {
+	r := b.row(row)
+	for w := 0; w < b.words; w++ {
+		if r[w] != b.full[w] {
+			return false
+		}
+	}
+	return true
+}
+
 // Returns the set value for a block (true if set, false if not).
 func (b *Board) Block(row, col int) (bool, error) {
 	err := b.checkBlockRange(row, col)
@@ -52,21 +138,38 @@ func (b *Board) Block(row, col int) (bool, error) {
 		return false, err
 	}
 
-	return b.data[row][col], nil
+	return b.isSet(row, col), nil
 }
 
-// Sets the given block to the supplied set value.
-func (b *Board) SetBlock(row, col int, value bool) error {
+// Returns the cell filling a block.
+func (b *Board) Cell(row, col int) (Cell, error) {
+	err := b.checkBlockRange(row, col)
+	if err != nil {
+		return CellEmpty, err
+	}
+
+	return b.cells[row*b.width+col], nil
+}
+
+// Fills the given block with the supplied cell.  Setting CellEmpty unsets the
+// block.
+func (b *Board) SetCell(row, col int, cell Cell) error {
 	err := b.checkBlockRange(row, col)
 	if err != nil {
 		return err
 	}
 
-	b.data[row][col] = value
+	b.set(row, col, cell)
 
 	return nil
 }
 
+// Sets the given block to the supplied set value.  Set blocks are filled with
+// CellGarbage.
+func (b *Board) SetBlock(row, col int, value bool) error {
+	return b.SetCell(row, col, valueCell(value))
+}
+
 // Sets an entire rwo to the given value.
 func (b *Board) SetRow(row int, value bool) error {
 	err := b.checkBlockRange(row, 0)
@@ -74,8 +177,21 @@ func (b *Board) SetRow(row int, value bool) error {
 		return err
 	}
 
-	for col := 0; col < b.width; col++ {
-		b.data[row][col] = value
+	if value {
+		b.rehashRow(row, b.full)
+		copy(b.row(row), b.full)
+	} else {
+		r := b.row(row)
+		b.rehashRow(row, nil)
+		for w := range r {
+			r[w] = 0
+		}
+	}
+
+	cell := valueCell(value)
+	cells := b.cells[row*b.width : (row+1)*b.width]
+	for col := range cells {
+		cells[col] = cell
 	}
 
 	return nil
@@ -89,7 +205,7 @@ func (b *Board) SetCol(col int, value bool) error {
 	}
 
 	for row := 0; row < b.height; row++ {
-		b.data[row][col] = value
+		b.set(row, col, valueCell(value))
 	}
 
 	return nil
@@ -98,33 +214,50 @@ func (b *Board) SetCol(col int, value bool) error {
 // Copies one row (from) to another (to).
 func (b *Board) CopyRow(from, to int) {
 	// TODO check bounds
-	for col := 0; col < b.Width(); col++ {
-		b.data[to][col] = b.data[from][col]
-	}
+	b.rehashRow(to, b.row(from))
+	copy(b.row(to), b.row(from))
+	copy(b.cells[to*b.width:(to+1)*b.width], b.cells[from*b.width:(from+1)*b.width])
 }
 
 // Creates a copy of the current board.
 func (b *Board) Copy() *Board {
-	c, _ := NewBoard(b.width, b.height)
-	for row := 0; row < b.height; row++ {
-		for col := 0; col < b.width; col++ {
-			c.data[row][col] = b.data[row][col]
-		}
-	}
+	c := &Board{b.width, b.height, b.words, b.full, make([]uint64, len(b.data)), make([]Cell, len(b.cells)), b.hash}
+	copy(c.data, b.data)
+	copy(c.cells, b.cells)
 	return c
 }
 
-// Determines equality between two boards.
+// Returns a Zobrist hash of which blocks are set.  Boards that are Equal have
+// the same hash, whatever fills their blocks.
+func (b *Board) Hash() uint64 {
+	return b.hash
+}
+
+// Determines equality between two boards.  Only whether blocks are set is
+// compared, see EqualCells to also compare what fills them.
 func (b *Board) Equal(other *Board) bool {
-	if b.width != other.width || b.height != other.height {
+	if b.width != other.width || b.height != other.height || b.hash != other.hash {
 		return false
 	}
 
-	for row := 0; row < b.height; row++ {
-		for col := 0; col < b.width; col++ {
-			if b.data[row][col] != other.data[row][col] {
-				return false
-			}
+	for i := range b.data {
+		if b.data[i] != other.data[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Determines equality between two boards including the cell filling each
+// block.
+func (b *Board) EqualCells(other *Board) bool {
+	if !b.Equal(other) {
+		return false
+	}
+
+	for i := range b.cells {
+		if b.cells[i] != other.cells[i] {
+			return false
 		}
 	}
 	return true
@@ -144,7 +277,7 @@ func (b *Board) String() string {
 
 		out += "│"
 		for col := 0; col < b.width; col++ {
-			if b.data[row][col] {
+			if b.isSet(row, col) {
 				out += "X"
 			} else {
 				out += " "
@@ -178,3 +178,133 @@ func TestCopy(t *testing.T) {
 		t.Error("Changes to the copy should not affect the source")
 	}
 }
+
+func BenchmarkCopy(b *testing.B) {
+	board, _ := NewBoard(10, 20)
+	board.SetRow(19, true)
+	for i := 0; i < b.N; i++ {
+		board.Copy()
+	}
+}
+
+func TestWideBoardBlocks(t *testing.T) {
+	board, _ := NewBoard(70, 3)
+	board.SetBlock(1, 63, true)
+	board.SetBlock(1, 64, true)
+	board.SetBlock(2, 69, true)
+
+	for _, c := range [][2]int{{1, 63}, {1, 64}, {2, 69}} {
+		if set, _ := board.Block(c[0], c[1]); !set {
+			t.Errorf("Block (%d,%d) should be set", c[0], c[1])
+		}
+	}
+	if set, _ := board.Block(1, 65); set {
+		t.Error("Block (1,65) should not be set")
+	}
+
+	cpy := board.Copy()
+	if !cpy.Equal(board) {
+		t.Error("Copied wide board should equal its source")
+	}
+	cpy.SetBlock(2, 69, false)
+	if cpy.Equal(board) {
+		t.Error("Wide boards differing in the last word should be unequal")
+	}
+}
+
+func TestWideBoardSetRowAndCol(t *testing.T) {
+	board, _ := NewBoard(100, 4)
+	board.SetRow(3, true)
+	board.SetCol(99, true)
+
+	for col := 0; col < 100; col++ {
+		if set, _ := board.Block(3, col); !set {
+			t.Errorf("Block (3,%d) should be set by SetRow", col)
+		}
+	}
+	for row := 0; row < 4; row++ {
+		if set, _ := board.Block(row, 99); !set {
+			t.Errorf("Block (%d,99) should be set by SetCol", row)
+		}
+	}
+
+	board.SetRow(3, false)
+	if set, _ := board.Block(3, 50); set {
+		t.Error("Block (3,50) should be cleared by SetRow")
+	}
+}
+
+func TestSetCell(t *testing.T) {
+	board, _ := NewBoard(5, 5)
+	if err := board.SetCell(2, 3, CellJ); err != nil {
+		t.Error("No error should be returned")
+	}
+
+	cell, _ := board.Cell(2, 3)
+	if cell != CellJ {
+		t.Errorf("Cell should be J, was %s", cell)
+	}
+	if set, _ := board.Block(2, 3); !set {
+		t.Error("A typed cell should be reported as set")
+	}
+
+	board.SetCell(2, 3, CellEmpty)
+	if set, _ := board.Block(2, 3); set {
+		t.Error("An empty cell should be reported as unset")
+	}
+}
+
+func TestSetCellOutOfRange(t *testing.T) {
+	board, _ := NewBoard(5, 5)
+	if err := board.SetCell(5, 0, CellI); err == nil {
+		t.Error("An error should be returned when row above height")
+	}
+	if _, err := board.Cell(0, -1); err == nil {
+		t.Error("An error should be returned when column below 0")
+	}
+}
+
+func TestSetBlockFillsGarbage(t *testing.T) {
+	board, _ := NewBoard(5, 5)
+	board.SetBlock(0, 0, true)
+	board.SetRow(4, true)
+
+	if cell, _ := board.Cell(0, 0); cell != CellGarbage {
+		t.Error("SetBlock should fill with garbage")
+	}
+	if cell, _ := board.Cell(4, 2); cell != CellGarbage {
+		t.Error("SetRow should fill with garbage")
+	}
+
+	board.SetRow(4, false)
+	if cell, _ := board.Cell(4, 2); cell != CellEmpty {
+		t.Error("Unset rows should be empty")
+	}
+}
+
+func TestCopyKeepsCells(t *testing.T) {
+	a, _ := NewBoard(5, 5)
+	a.SetCell(1, 1, CellUser+1)
+
+	b := a.Copy()
+	if cell, _ := b.Cell(1, 1); cell != CellUser+1 {
+		t.Error("Copied board should keep cell types")
+	}
+	if !b.EqualCells(a) {
+		t.Error("Copied board should equal its source including cells")
+	}
+}
+
+func TestEqualCells(t *testing.T) {
+	a, _ := NewBoard(3, 3)
+	b, _ := NewBoard(3, 3)
+	a.SetCell(0, 0, CellS)
+	b.SetCell(0, 0, CellZ)
+
+	if !a.Equal(b) {
+		t.Error("Boards with the same blocks set should be equal")
+	}
+	if a.EqualCells(b) {
+		t.Error("Boards with different cells should not be equal including cells")
+	}
+}
@@ -0,0 +1,76 @@
+package tetris
+
+import (
+	"strconv"
+)
+
+// Describes what fills a single block on the board.  Besides the seven
+// tetromino kinds and garbage, any value from CellUser upwards is free for
+// applications to use for their own purposes (e.g. bombs or power-ups); the
+// board treats all non-empty cells alike when checking for collisions and full
+// lines.
+type Cell uint8
+
+const (
+	CellEmpty Cell = iota
+	CellI
+	CellO
+	CellT
+	CellS
+	CellZ
+	CellJ
+	CellL
+	CellGarbage
+
+	// The first value available for user-defined cells.
+	CellUser Cell = 16
+)
+
+var cellKinds = [...]string{
+	CellI: "I",
+	CellO: "O",
+	CellT: "T",
+	CellS: "S",
+	CellZ: "Z",
+	CellJ: "J",
+	CellL: "L",
+}
+
+// Returns the cell filled by the given kind of tetromino or CellGarbage if the
+// kind is not known.
+func KindCell(kind string) Cell {
+	for c, k := range cellKinds {
+		if k != "" && k == kind {
+			return Cell(c)
+		}
+	}
+	return CellGarbage
+}
+
+// Returns true if the cell is filled by anything.
+func (c Cell) Filled() bool {
+	return c != CellEmpty
+}
+
+// Returns the kind of tetromino that filled the cell or "" if the cell is
+// empty, garbage or user-defined.
+func (c Cell) Kind() string {
+	if int(c) < len(cellKinds) {
+		return cellKinds[c]
+	}
+	return ""
+}
+
+func (c Cell) String() string {
+	switch {
+	case c == CellEmpty:
+		return "Empty"
+	case c == CellGarbage:
+		return "Garbage"
+	case c.Kind() != "":
+		return c.Kind()
+	case c >= CellUser:
+		return "User" + strconv.Itoa(int(c-CellUser))
+	}
+	return "Cell" + strconv.Itoa(int(c))
+}
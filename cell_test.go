@@ -0,0 +1,49 @@
+package tetris
+
+import (
+	"testing"
+)
+
+func TestKindCellRoundTrip(t *testing.T) {
+	for _, kind := range []string{"I", "O", "T", "S", "Z", "J", "L"} {
+		cell := KindCell(kind)
+		if cell.Kind() != kind {
+			t.Errorf("Cell for kind %s should report kind %s, got %s", kind, kind, cell.Kind())
+		}
+	}
+}
+
+func TestKindCellUnknownIsGarbage(t *testing.T) {
+	if KindCell("X") != CellGarbage {
+		t.Error("Unknown kinds should map to garbage")
+	}
+}
+
+func TestCellFilled(t *testing.T) {
+	if CellEmpty.Filled() {
+		t.Error("Empty cells should not be filled")
+	}
+	for _, cell := range []Cell{CellI, CellL, CellGarbage, CellUser, CellUser + 5} {
+		if !cell.Filled() {
+			t.Errorf("Cell %s should be filled", cell)
+		}
+	}
+}
+
+func TestCellString(t *testing.T) {
+	if CellEmpty.String() != "Empty" {
+		t.Error("Empty cell string should be Empty")
+	}
+	if CellGarbage.String() != "Garbage" {
+		t.Error("Garbage cell string should be Garbage")
+	}
+	if CellT.String() != "T" {
+		t.Error("T cell string should be T")
+	}
+	if (CellUser + 2).String() != "User2" {
+		t.Error("User cell strings should be numbered from CellUser")
+	}
+	if (CellUser + 2).Kind() != "" {
+		t.Error("User cells should have no kind")
+	}
+}
@@ -0,0 +1,44 @@
+// Command tbp-bot plays as a Tetris Bot Protocol bot on stdin and stdout,
+// suggesting the moves found by a beam search.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/paulcoyle/tetris"
+	"github.com/paulcoyle/tetris/search"
+	"github.com/paulcoyle/tetris/tbp"
+)
+
+func main() {
+	config := search.DefaultConfig()
+	flag.IntVar(&config.BeamWidth, "beam", config.BeamWidth, "positions kept after each piece")
+	flag.IntVar(&config.Depth, "depth", config.Depth, "pieces placed ahead, including the active one")
+	flag.IntVar(&config.Workers, "workers", config.Workers, "goroutines searching at once")
+	weights := flag.String("weights", "eltetris", "evaluator weights: eltetris or dellacherie")
+	flag.Parse()
+
+	switch *weights {
+	case "eltetris":
+		config.Evaluator = tetris.NewEvaluator(tetris.ElTetrisWeights)
+	case "dellacherie":
+		config.Evaluator = tetris.NewEvaluator(tetris.DellacherieWeights)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown weights %q\n", *weights)
+		os.Exit(2)
+	}
+
+	searcher, err := search.NewSearcher(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	info := tbp.Info{Name: "tbp-bot", Version: "1", Author: "paulcoyle/tetris"}
+	if err := tbp.Run(os.Stdin, os.Stdout, &tbp.SearchBot{Searcher: searcher}, info); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
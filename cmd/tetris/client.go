@@ -0,0 +1,238 @@
+package main
+
+import (
+	"github.com/paulcoyle/tetris"
+)
+
+// How held keys repeat, in frames at 60 frames per second.
+type Handling struct {
+	// Delayed auto shift: how long a direction is held before the piece
+	// starts moving on its own.
+	DAS int
+
+	// Auto repeat rate: frames between moves once DAS has charged.  Zero
+	// moves the piece to the wall at once.
+	ARR int
+
+	// Frames between rows fallen while soft drop is held.  Zero drops the
+	// piece to the floor at once.
+	SoftDrop int
+
+	// Terminals send no key releases, only the repeats of a held key, and
+	// they wait a while before the first repeat.  A key counts as released
+	// once no repeat has come for RepeatDelay frames after it was pressed, or
+	// for Release frames once it has started repeating.  RepeatDelay should
+	// cover the terminal's delay before repeating and Release the gap
+	// between its repeats.
+	RepeatDelay int
+	Release     int
+}
+
+// Returns a DAS of 10 frames, an ARR of 2 and a soft drop of a row every 2
+// frames.  Keys wait 40 frames for their first repeat, enough for repeat
+// delays up to about 660 ms, and are released after 6 frames without one
+// once repeating.
+func DefaultHandling() Handling {
+	return Handling{DAS: 10, ARR: 2, SoftDrop: 2, RepeatDelay: 40, Release: 6}
+}
+
+// A key press being held down.  Until the terminal repeats it the key may
+// just have been tapped, so it only shifts or drops the piece on its own
+// once repeating.
+type heldKey struct {
+	start     int  // frame the key was first pressed
+	seen      int  // frame the key was last pressed or repeated
+	repeating bool // whether a repeat has come since the press
+	shifted   int  // frame of the last automatic shift, -1 for none
+}
+
+// Plays a game from key presses: it turns presses and their repeats into
+// game actions according to its bindings and handling, and runs the game a
+// frame at a time.
+type Client struct {
+	seed     int64
+	handling Handling
+	bindings Bindings
+
+	game   *tetris.Game
+	held   map[Command]*heldKey
+	frame  int
+	paused bool
+	quit   bool
+}
+
+func NewClient(seed int64, handling Handling, bindings Bindings) (*Client, error) {
+	c := &Client{seed: seed, handling: handling, bindings: bindings}
+	if err := c.restart(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Starts a new game.  Every restart deals a new sequence of pieces that
+// follows from the seed.
+func (c *Client) restart() error {
+	game, err := tetris.NewGame(tetris.DefaultGameConfig(c.seed))
+	if err != nil {
+		return err
+	}
+	c.seed++
+	c.game = game
+	c.held = make(map[Command]*heldKey)
+	c.paused = false
+	return nil
+}
+
+func (c *Client) Game() *tetris.Game {
+	return c.game
+}
+
+func (c *Client) Paused() bool {
+	return c.paused
+}
+
+// Returns true once the player has asked to quit.
+func (c *Client) Quit() bool {
+	return c.quit
+}
+
+// Handles a key press, or a repeat of it while the key is held, as named by
+// parseKeys.  Rotations, hard drops and holds act on every press; shifts and
+// soft drops tell repeats from new presses by their timing.  Unbound keys are
+// ignored.
+func (c *Client) Press(key string) error {
+	if key == "ctrl-c" {
+		c.quit = true
+		return nil
+	}
+	cmd, ok := c.bindings[key]
+	if !ok {
+		return nil
+	}
+
+	switch cmd {
+	case CmdQuit:
+		c.quit = true
+		return nil
+	case CmdRestart:
+		return c.restart()
+	case CmdPause:
+		if !c.game.Over() {
+			c.paused = !c.paused
+		}
+		return nil
+	}
+	if c.paused || c.game.Over() {
+		return nil
+	}
+
+	switch cmd {
+	case CmdHardDrop:
+		c.game.Apply(tetris.HardDrop)
+		return nil
+	case CmdRotateCW:
+		c.game.Apply(tetris.RotateCW)
+		return nil
+	case CmdRotateCCW:
+		c.game.Apply(tetris.RotateCCW)
+		return nil
+	case CmdRotate180:
+		c.game.Apply(tetris.Rotate180)
+		return nil
+	case CmdHold:
+		c.game.Apply(tetris.Hold)
+		return nil
+	}
+
+	// Only shifts and soft drop repeat while held, so only they tell a
+	// repeat from a new press.
+	if k, ok := c.held[cmd]; ok && c.frame-k.seen <= c.releaseAfter(k) {
+		k.seen = c.frame
+		k.repeating = true
+		return nil
+	}
+	c.held[cmd] = &heldKey{start: c.frame, seen: c.frame, shifted: -1}
+
+	switch cmd {
+	case CmdLeft:
+		delete(c.held, CmdRight)
+		c.game.Apply(tetris.MoveLeft)
+	case CmdRight:
+		delete(c.held, CmdLeft)
+		c.game.Apply(tetris.MoveRight)
+	case CmdSoftDrop:
+		c.softDrop(true)
+	}
+	return nil
+}
+
+// Advances a frame: releases keys that stopped repeating, moves the piece
+// for the keys still held and ticks the game.  Nothing happens while paused
+// or once the game is over.
+func (c *Client) Step() {
+	if c.quit || c.paused || c.game.Over() {
+		return
+	}
+
+	for cmd, k := range c.held {
+		if c.frame-k.seen > c.releaseAfter(k) {
+			delete(c.held, cmd)
+		}
+	}
+
+	if k, ok := c.held[CmdLeft]; ok {
+		c.autoShift(k, tetris.MoveLeft)
+	}
+	if k, ok := c.held[CmdRight]; ok {
+		c.autoShift(k, tetris.MoveRight)
+	}
+	if k, ok := c.held[CmdSoftDrop]; ok && k.repeating {
+		c.softDrop(c.handling.SoftDrop == 0 || (c.frame-k.start)%c.handling.SoftDrop == 0)
+	}
+
+	c.game.Tick()
+	c.frame++
+}
+
+// Returns how many frames the key may go without a repeat before it counts
+// as released.  DAS charges while waiting for the first repeat, so the wait
+// is never shorter than DAS.
+func (c *Client) releaseAfter(k *heldKey) int {
+	if k.repeating {
+		return c.handling.Release
+	}
+	if c.handling.RepeatDelay < c.handling.DAS {
+		return c.handling.DAS
+	}
+	return c.handling.RepeatDelay
+}
+
+// Shifts the piece for a held direction once DAS has charged and the key
+// has started repeating.  A repeat arriving after DAS has charged shifts at
+// once.
+func (c *Client) autoShift(k *heldKey, move tetris.Action) {
+	if !k.repeating || c.frame-k.start < c.handling.DAS {
+		return
+	}
+	switch {
+	case c.handling.ARR == 0:
+		for c.game.Apply(move).OK {
+		}
+	case k.shifted < 0 || c.frame-k.shifted >= c.handling.ARR:
+		c.game.Apply(move)
+		k.shifted = c.frame
+	}
+}
+
+// Soft drops a row if due, or to the floor with an instant soft drop.
+func (c *Client) softDrop(due bool) {
+	if !due {
+		return
+	}
+	if c.handling.SoftDrop > 0 {
+		c.game.Apply(tetris.SoftDrop)
+		return
+	}
+	for c.game.Apply(tetris.SoftDrop).OK {
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/paulcoyle/tetris"
+)
+
+func newTestClient(t *testing.T, handling Handling) *Client {
+	c, err := NewClient(1, handling, DefaultBindings())
+	if err != nil {
+		t.Fatalf("Client should be created: %s", err)
+	}
+	return c
+}
+
+func activeCol(c *Client) int {
+	_, _, col := c.Game().Active()
+	return col
+}
+
+func activeRow(c *Client) int {
+	_, row, _ := c.Game().Active()
+	return row
+}
+
+// Presses a key every frame for the given number of frames, like a terminal
+// repeating a held key.
+func holdKey(c *Client, key string, frames int) {
+	for i := 0; i < frames; i++ {
+		c.Press(key)
+		c.Step()
+	}
+}
+
+func TestClientDAS(t *testing.T) {
+	c := newTestClient(t, Handling{DAS: 5, ARR: 2, SoftDrop: 2, Release: 3})
+	start := activeCol(c)
+
+	holdKey(c, "right", 5)
+	if activeCol(c) != start+1 {
+		t.Errorf("A press should move once until DAS charges, moved %d", activeCol(c)-start)
+	}
+	holdKey(c, "right", 1)
+	if activeCol(c) != start+2 {
+		t.Errorf("The piece should move once DAS charges, moved %d", activeCol(c)-start)
+	}
+	holdKey(c, "right", 2)
+	if activeCol(c) != start+3 {
+		t.Errorf("The piece should then move every ARR frames, moved %d", activeCol(c)-start)
+	}
+}
+
+func TestClientARRZero(t *testing.T) {
+	c := newTestClient(t, Handling{DAS: 3, ARR: 0, SoftDrop: 2, Release: 3})
+	holdKey(c, "left", 4)
+	if c.Game().Apply(tetris.MoveLeft).OK {
+		t.Error("An ARR of zero should move the piece to the wall")
+	}
+}
+
+func TestClientRelease(t *testing.T) {
+	c := newTestClient(t, Handling{DAS: 5, ARR: 1, SoftDrop: 2, RepeatDelay: 8, Release: 2})
+	start := activeCol(c)
+
+	c.Press("right")
+	for i := 0; i < 10; i++ {
+		c.Step()
+	}
+	if activeCol(c) != start+1 {
+		t.Error("A tapped key should move once and be released")
+	}
+
+	c.Press("right")
+	if activeCol(c) != start+2 {
+		t.Error("Pressing a released key again should move at once")
+	}
+}
+
+func TestClientSoftDrop(t *testing.T) {
+	c := newTestClient(t, Handling{DAS: 10, ARR: 2, SoftDrop: 2, Release: 3})
+	start := activeRow(c)
+	holdKey(c, "down", 5)
+	if activeRow(c) != start+3 {
+		t.Errorf("Soft drop should fall a row on the press and every 2 frames, fell %d", activeRow(c)-start)
+	}
+
+	c = newTestClient(t, Handling{DAS: 10, ARR: 2, SoftDrop: 0, Release: 3})
+	c.Press("down")
+	if c.Game().Apply(tetris.SoftDrop).OK {
+		t.Error("A soft drop of zero should drop the piece to the floor")
+	}
+}
+
+func TestClientPauseRestartQuit(t *testing.T) {
+	c := newTestClient(t, DefaultHandling())
+	c.Press("p")
+	frame := c.Game().Frame()
+	c.Press("space")
+	c.Step()
+	if !c.Paused() || c.Game().Frame() != frame || c.Game().Scorer().Score() != 0 {
+		t.Error("A paused game should not advance or take moves")
+	}
+	c.Press("p")
+	c.Step()
+	if c.Paused() || c.Game().Frame() != frame+1 {
+		t.Error("Unpausing should resume the game")
+	}
+
+	old := c.Game()
+	c.Press("r")
+	if c.Game() == old || c.Game().Frame() != 0 {
+		t.Error("Restarting should start a new game")
+	}
+
+	c.Press("q")
+	if !c.Quit() {
+		t.Error("The client should quit")
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Something the player can ask for.
+type Command int
+
+const (
+	CmdLeft Command = iota
+	CmdRight
+	CmdSoftDrop
+	CmdHardDrop
+	CmdRotateCW
+	CmdRotateCCW
+	CmdRotate180
+	CmdHold
+	CmdPause
+	CmdRestart
+	CmdQuit
+	numCommands
+)
+
+var commandNames = [...]string{
+	CmdLeft:      "left",
+	CmdRight:     "right",
+	CmdSoftDrop:  "softdrop",
+	CmdHardDrop:  "harddrop",
+	CmdRotateCW:  "cw",
+	CmdRotateCCW: "ccw",
+	CmdRotate180: "180",
+	CmdHold:      "hold",
+	CmdPause:     "pause",
+	CmdRestart:   "restart",
+	CmdQuit:      "quit",
+}
+
+func (c Command) String() string {
+	if c < 0 || c >= numCommands {
+		return "Command?"
+	}
+	return commandNames[c]
+}
+
+// Maps key names, as returned by parseKeys, to commands.
+type Bindings map[string]Command
+
+// Returns the arrow keys to move and soft drop, space to hard drop, x and up
+// to rotate clockwise, z to rotate counter-clockwise, a to turn around, c to
+// hold, p to pause, r to restart and q to quit.
+func DefaultBindings() Bindings {
+	return Bindings{
+		"left":  CmdLeft,
+		"right": CmdRight,
+		"down":  CmdSoftDrop,
+		"space": CmdHardDrop,
+		"up":    CmdRotateCW,
+		"x":     CmdRotateCW,
+		"z":     CmdRotateCCW,
+		"a":     CmdRotate180,
+		"c":     CmdHold,
+		"p":     CmdPause,
+		"r":     CmdRestart,
+		"q":     CmdQuit,
+	}
+}
+
+// Changes bindings from a list like "left=h,right=l,cw=k".  Each command
+// named is bound to the given key instead of its old keys.
+func (b Bindings) Parse(spec string) error {
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return fmt.Errorf("Binding %q should look like command=key!", pair)
+		}
+
+		cmd := Command(-1)
+		for c, name := range commandNames {
+			if name == parts[0] {
+				cmd = Command(c)
+			}
+		}
+		if cmd < 0 {
+			return fmt.Errorf("Unknown command %q, expected one of %s!", parts[0], strings.Join(commandNames[:], ", "))
+		}
+
+		for key, c := range b {
+			if c == cmd {
+				delete(b, key)
+			}
+		}
+		b[parts[1]] = cmd
+	}
+	return nil
+}
+
+// Returns the bindings as "command=key" pairs in command order.
+func (b Bindings) String() string {
+	var pairs []string
+	for key, cmd := range b {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", cmd, key))
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		ci, cj := strings.SplitN(pairs[i], "=", 2)[0], strings.SplitN(pairs[j], "=", 2)[0]
+		if ci != cj {
+			return commandOrder(ci) < commandOrder(cj)
+		}
+		return pairs[i] < pairs[j]
+	})
+	return strings.Join(pairs, ",")
+}
+
+func commandOrder(name string) int {
+	for c, n := range commandNames {
+		if n == name {
+			return c
+		}
+	}
+	return len(commandNames)
+}
+
+// The escape sequences terminals send for the arrow keys.
+var arrowKeys = map[byte]string{'A': "up", 'B': "down", 'C': "right", 'D': "left"}
+
+// Splits bytes read from a terminal in raw mode into key names: "left",
+// "right", "up" and "down" for the arrows, "space", "enter", "esc", "tab" and
+// "backspace", and the character itself for anything else.
+func parseKeys(data []byte) []string {
+	var keys []string
+	for i := 0; i < len(data); i++ {
+		b := data[i]
+		switch {
+		case b == 0x1b && i+2 < len(data) && (data[i+1] == '[' || data[i+1] == 'O') && arrowKeys[data[i+2]] != "":
+			keys = append(keys, arrowKeys[data[i+2]])
+			i += 2
+		case b == 0x1b:
+			keys = append(keys, "esc")
+		case b == ' ':
+			keys = append(keys, "space")
+		case b == '\r' || b == '\n':
+			keys = append(keys, "enter")
+		case b == '\t':
+			keys = append(keys, "tab")
+		case b == 0x7f || b == 0x08:
+			keys = append(keys, "backspace")
+		case b == 0x03:
+			// Ctrl-C, as raw mode stops it interrupting.
+			keys = append(keys, "ctrl-c")
+		default:
+			keys = append(keys, string(rune(b)))
+		}
+	}
+	return keys
+}
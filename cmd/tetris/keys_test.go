@@ -0,0 +1,45 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	keys := parseKeys([]byte("\x1b[D\x1b[Cx \r\x1bq\x1bOA\x03"))
+	expected := []string{"left", "right", "x", "space", "enter", "esc", "q", "up", "ctrl-c"}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Keys should be %v, were %v", expected, keys)
+	}
+}
+
+func TestBindingsParse(t *testing.T) {
+	b := DefaultBindings()
+	if err := b.Parse("left=h, right=l,cw=k"); err != nil {
+		t.Fatalf("Bindings should parse: %s", err)
+	}
+	if b["h"] != CmdLeft || b["l"] != CmdRight || b["k"] != CmdRotateCW {
+		t.Error("Keys should be bound to the commands given")
+	}
+	for _, key := range []string{"left", "right", "up", "x"} {
+		if _, ok := b[key]; ok {
+			t.Errorf("Rebinding a command should unbind %s", key)
+		}
+	}
+	if b["z"] != CmdRotateCCW {
+		t.Error("Commands not mentioned should keep their keys")
+	}
+
+	for _, spec := range []string{"left", "left=", "jump=j"} {
+		if err := DefaultBindings().Parse(spec); err == nil {
+			t.Errorf("Bindings %q should be an error", spec)
+		}
+	}
+}
+
+func TestBindingsString(t *testing.T) {
+	b := Bindings{"q": CmdQuit, "h": CmdLeft, "x": CmdRotateCW, "up": CmdRotateCW}
+	if s := b.String(); s != "left=h,cw=up,cw=x,quit=q" {
+		t.Errorf("Bindings should list in command order, were %s", s)
+	}
+}
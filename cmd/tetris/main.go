@@ -0,0 +1,123 @@
+// Command tetris plays tetris in a terminal, drawing with ANSI escape codes
+// and reading keys in raw mode.  With -script it plays headless from a script
+// of key presses instead, writing or checking the frames it renders.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+func main() {
+	handling := DefaultHandling()
+	bindings := DefaultBindings()
+	seed := flag.Int64("seed", 0, "seed of the piece sequence, 0 picks one from the clock")
+	flag.IntVar(&handling.DAS, "das", handling.DAS, "frames a direction is held before it repeats")
+	flag.IntVar(&handling.ARR, "arr", handling.ARR, "frames between repeated moves, 0 to move to the wall")
+	flag.IntVar(&handling.SoftDrop, "softdrop", handling.SoftDrop, "frames per row of soft drop, 0 to drop to the floor")
+	flag.IntVar(&handling.RepeatDelay, "repeatdelay", handling.RepeatDelay, "frames to wait for a key's first repeat before it counts as released")
+	flag.IntVar(&handling.Release, "release", handling.Release, "frames without a repeat before a repeating key counts as released")
+	keys := flag.String("keys", "", "key bindings to change, e.g. left=h,right=l (default "+bindings.String()+")")
+	script := flag.String("script", "", "play headless from a script file, - for stdin")
+	plain := flag.Bool("plain", false, "draw without colours")
+	flag.Parse()
+
+	if err := bindings.Parse(*keys); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *seed == 0 && *script == "" {
+		*seed = time.Now().UnixNano()
+	}
+
+	client, err := NewClient(*seed, handling, bindings)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if *script != "" {
+		err = playScript(client, *script)
+	} else {
+		err = play(client, !*plain)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func playScript(client *Client, path string) error {
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	return runScript(client, in, os.Stdout)
+}
+
+// Plays in the terminal at 60 frames per second until the player quits.
+func play(client *Client, color bool) error {
+	restore, err := makeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	// Switch to the alternate screen and hide the cursor while playing.
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+
+	keys := make(chan string, 64)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			for _, key := range parseKeys(buf[:n]) {
+				keys <- key
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(time.Second / 60)
+	defer ticker.Stop()
+	last := ""
+	for !client.Quit() {
+		<-ticker.C
+	pending:
+		for {
+			select {
+			case key, ok := <-keys:
+				if !ok {
+					return nil
+				}
+				if err := client.Press(key); err != nil {
+					return err
+				}
+			default:
+				break pending
+			}
+		}
+		client.Step()
+
+		if frame := client.Render(color); frame != last {
+			last = frame
+			// Clear to the end of each line and below so shorter lines leave
+			// nothing behind.
+			fmt.Print("\x1b[H" + strings.ReplaceAll(frame, "\n", "\x1b[K\n") + "\x1b[J")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/paulcoyle/tetris"
+)
+
+// The background colours of each kind of cell, as ANSI SGR parameters.
+var cellColors = map[tetris.Cell]string{
+	tetris.CellI:       "46",
+	tetris.CellO:       "43",
+	tetris.CellT:       "45",
+	tetris.CellS:       "42",
+	tetris.CellZ:       "41",
+	tetris.CellJ:       "44",
+	tetris.CellL:       "48;5;208",
+	tetris.CellGarbage: "100",
+}
+
+// Draws cells two characters wide, either with ANSI colours or as plain text
+// that tests can compare.
+type painter struct {
+	color bool
+}
+
+func (p painter) cell(c tetris.Cell) string {
+	if !c.Filled() {
+		if p.color {
+			return "  "
+		}
+		return " ."
+	}
+	if p.color {
+		code, ok := cellColors[c]
+		if !ok {
+			code = cellColors[tetris.CellGarbage]
+		}
+		return "\x1b[" + code + "m  \x1b[0m"
+	}
+	if kind := c.Kind(); kind != "" {
+		return kind + kind
+	}
+	return "##"
+}
+
+func (p painter) ghost() string {
+	if p.color {
+		return "\x1b[90m░░\x1b[0m"
+	}
+	return "::"
+}
+
+// Returns the width of a string on screen, skipping escape sequences.
+func visibleLen(s string) int {
+	n, escape := 0, false
+	for _, r := range s {
+		switch {
+		case escape:
+			escape = r != 'm'
+		case r == 0x1b:
+			escape = true
+		default:
+			n++
+		}
+	}
+	return n
+}
+
+func pad(s string, width int) string {
+	if n := visibleLen(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// Draws a kind as it spawns in the given game, in a box four cells wide and
+// two high, for the hold slot and the next queue.
+func (p painter) preview(game *tetris.Game, kind string) []string {
+	lines := []string{strings.Repeat(" ", 8), strings.Repeat(" ", 8)}
+	if kind == "" {
+		return lines
+	}
+	rs := game.Config().Rotation
+	state, _, _ := rs.Spawn(kind, game.Board().Width())
+	t, err := tetris.NewTetrominoFor(rs, kind, state)
+	if err != nil {
+		return lines
+	}
+
+	data, bounds := t.Data(), t.Bounds()
+	for row := bounds.Top; row <= bounds.Bottom && row-bounds.Top < len(lines); row++ {
+		line := ""
+		for col := bounds.Left; col <= bounds.Right; col++ {
+			if data[row][col] {
+				line += p.cell(tetris.KindCell(kind))
+			} else {
+				line += "  "
+			}
+		}
+		lines[row-bounds.Top] = pad(line, 8)
+	}
+	return lines
+}
+
+// Returns the cells of the board with the active piece and its ghost drawn
+// in, and which of them belong to the ghost.
+func (c *Client) screenCells() ([][]tetris.Cell, [][]bool) {
+	game := c.game
+	board := game.Board()
+	cells := make([][]tetris.Cell, board.Height())
+	ghost := make([][]bool, board.Height())
+	for row := range cells {
+		cells[row] = make([]tetris.Cell, board.Width())
+		ghost[row] = make([]bool, board.Width())
+		for col := range cells[row] {
+			cells[row][col], _ = board.Cell(row, col)
+		}
+	}
+
+	t, row, col := game.Active()
+	if t == nil {
+		return cells, ghost
+	}
+	rs := game.Config().Rotation
+	drop := row
+	for tetris.CheckPlacement(rs, board, t, drop+1, col) == nil {
+		drop++
+	}
+
+	pr, pc := rs.Pivot(t.Kind())
+	data := t.Data()
+	for _, at := range [...]struct {
+		row   int
+		ghost bool
+	}{{drop, true}, {row, false}} {
+		for r := 0; r < 4; r++ {
+			for cl := 0; cl < 4; cl++ {
+				br, bc := at.row-pr+r, col-pc+cl
+				if !data[r][cl] || br < 0 || br >= len(cells) || bc < 0 || bc >= len(cells[br]) {
+					continue
+				}
+				cells[br][bc] = tetris.KindCell(t.Kind())
+				ghost[br][bc] = at.ghost
+			}
+		}
+	}
+	return cells, ghost
+}
+
+// Draws the game: the hold slot and score on the left, the board with the
+// active piece and its ghost, and the next queue on the right, followed by a
+// status line.  Lines end in newlines.
+func (c *Client) Render(color bool) string {
+	p := painter{color}
+	game := c.game
+
+	held := ""
+	if t := game.Held(); t != nil {
+		held = t.Kind()
+	}
+	scorer := game.Scorer()
+	left := []string{"HOLD"}
+	left = append(left, p.preview(game, held)...)
+	left = append(left, "",
+		"SCORE", fmt.Sprint(scorer.Score()), "",
+		"LEVEL", fmt.Sprint(scorer.Level()), "",
+		"LINES", fmt.Sprint(scorer.Lines()))
+
+	right := []string{"NEXT"}
+	for _, kind := range game.Queue() {
+		right = append(right, p.preview(game, kind)...)
+		right = append(right, "")
+	}
+
+	cells, ghost := c.screenCells()
+	width := game.Board().Width()
+	border := "+" + strings.Repeat("-", width*2) + "+"
+	lines := []string{pad("", 10) + border}
+	for row := range cells {
+		line := ""
+		for col, cell := range cells[row] {
+			if ghost[row][col] {
+				line += p.ghost()
+			} else {
+				line += p.cell(cell)
+			}
+		}
+
+		side := ""
+		if row < len(left) {
+			side = left[row]
+		}
+		line = pad(side, 10) + "|" + line + "|"
+		if row < len(right) {
+			line += " " + right[row]
+		}
+		lines = append(lines, strings.TrimRight(line, " "))
+	}
+	lines = append(lines, pad("", 10)+border)
+
+	switch {
+	case game.Over():
+		lines = append(lines, pad("", 10)+"GAME OVER  r: restart  q: quit")
+	case c.paused:
+		lines = append(lines, pad("", 10)+"PAUSED  p: resume")
+	default:
+		lines = append(lines, "")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/paulcoyle/tetris"
+)
+
+func TestRenderPlain(t *testing.T) {
+	c := newTestClient(t, DefaultHandling())
+	c.Press("c")
+	frame := c.Render(false)
+	lines := strings.Split(frame, "\n")
+
+	if len(lines) != 24 {
+		t.Fatalf("A frame should have 23 lines, had %d", len(lines)-1)
+	}
+	if !strings.HasPrefix(lines[2], "  TT      |") || !strings.HasPrefix(lines[3], "TTTTTT    |") {
+		t.Errorf("The held T should be drawn under HOLD:\n%s", frame)
+	}
+	if !strings.HasSuffix(lines[1], "| . . . .OOOO . . . .| NEXT") {
+		t.Errorf("The O should spawn at the top with NEXT beside it:\n%s", frame)
+	}
+	if !strings.Contains(lines[20], "| . . . .:::: . . . .|") {
+		t.Errorf("The ghost should be on the floor:\n%s", frame)
+	}
+	if strings.Contains(frame, "\x1b") {
+		t.Error("A plain frame should have no escape codes")
+	}
+}
+
+func TestRenderColor(t *testing.T) {
+	c := newTestClient(t, DefaultHandling())
+	frame := c.Render(true)
+	if !strings.Contains(frame, "\x1b[45m  \x1b[0m") || !strings.Contains(frame, "░░") {
+		t.Error("A colour frame should draw the T in magenta with its ghost")
+	}
+	for _, line := range strings.Split(frame, "\n")[1:21] {
+		if visibleLen(line) < 32 {
+			t.Errorf("Board lines should be 32 columns wide, %q was %d", line, visibleLen(line))
+		}
+	}
+}
+
+func TestRenderStatus(t *testing.T) {
+	c := newTestClient(t, DefaultHandling())
+	c.Press("p")
+	if !strings.Contains(c.Render(false), "PAUSED") {
+		t.Error("A paused game should say so")
+	}
+
+	c.Press("p")
+	for i := 0; i < 100 && !c.Game().Over(); i++ {
+		c.Press("space")
+		c.Step()
+	}
+	if !strings.Contains(c.Render(false), "GAME OVER") {
+		t.Error("A lost game should say so")
+	}
+}
+
+func TestRenderPreviewsUseGameRotation(t *testing.T) {
+	c := newTestClient(t, DefaultHandling())
+	config := tetris.DefaultGameConfig(1)
+	config.Rotation = tetris.ARS
+	game, err := tetris.NewGame(config)
+	if err != nil {
+		t.Fatalf("Game should be created: %s", err)
+	}
+	c.game = game
+
+	c.Press("c")
+	lines := strings.Split(c.Render(false), "\n")
+	if !strings.HasPrefix(lines[2], "TTTTTT    |") || !strings.HasPrefix(lines[3], "  TT      |") {
+		t.Errorf("The held T should be drawn pointing down as ARS spawns it:\n%s", strings.Join(lines, "\n"))
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Plays a client from a script instead of a terminal, one command per line:
+//
+//	press <key>...         presses keys in the current frame
+//	hold <key> <frames>    presses a key every frame for the given frames
+//	wait <frames>          runs frames without pressing anything
+//	render                 writes the current frame to out
+//	expect                 checks that the current frame is the lines up to
+//	...                    the next "end", ignoring trailing spaces
+//	end
+//
+// Keys are named as parseKeys names them and lines starting with # are
+// comments.  Frames are rendered without colour and each is preceded by a
+// "--- frame N" line.
+func runScript(c *Client, r io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		var err error
+		switch fields[0] {
+		case "press":
+			for _, key := range fields[1:] {
+				if err = c.Press(key); err != nil {
+					break
+				}
+			}
+		case "hold":
+			var frames int
+			if len(fields) != 3 {
+				err = fmt.Errorf("hold takes a key and a number of frames")
+			} else if frames, err = strconv.Atoi(fields[2]); err == nil {
+				for i := 0; i < frames && err == nil; i++ {
+					err = c.Press(fields[1])
+					c.Step()
+				}
+			}
+		case "wait":
+			var frames int
+			if len(fields) != 2 {
+				err = fmt.Errorf("wait takes a number of frames")
+			} else if frames, err = strconv.Atoi(fields[1]); err == nil {
+				for i := 0; i < frames; i++ {
+					c.Step()
+				}
+			}
+		case "render":
+			_, err = fmt.Fprintf(out, "--- frame %d\n%s", c.game.Frame(), c.Render(false))
+		case "expect":
+			start := line
+			var expected []string
+			for {
+				if !scanner.Scan() {
+					return fmt.Errorf("Line %d: expect has no end!", start)
+				}
+				line++
+				if strings.TrimSpace(scanner.Text()) == "end" {
+					break
+				}
+				expected = append(expected, scanner.Text())
+			}
+			if err = compareFrame(c.Render(false), expected); err != nil {
+				line = start
+			}
+		default:
+			err = fmt.Errorf("unknown command %q", fields[0])
+		}
+
+		if err != nil {
+			return fmt.Errorf("Line %d: %s!", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// Compares a rendered frame with the expected lines, ignoring trailing
+// spaces, and describes the first difference.
+func compareFrame(frame string, expected []string) error {
+	actual := strings.Split(strings.TrimSuffix(frame, "\n"), "\n")
+	for len(actual) > 0 && strings.TrimSpace(actual[len(actual)-1]) == "" {
+		actual = actual[:len(actual)-1]
+	}
+	for len(expected) > 0 && strings.TrimSpace(expected[len(expected)-1]) == "" {
+		expected = expected[:len(expected)-1]
+	}
+
+	for i := 0; i < len(actual) || i < len(expected); i++ {
+		var a, e string
+		if i < len(actual) {
+			a = strings.TrimRight(actual[i], " ")
+		}
+		if i < len(expected) {
+			e = strings.TrimRight(expected[i], " ")
+		}
+		if a != e {
+			return fmt.Errorf("frame line %d is %q, expected %q", i+1, a, e)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/paulcoyle/tetris"
+)
+
+const testScript = `# Hold the T, shift the O to the wall and drop it.
+press c
+hold left 20
+wait 5
+press space
+wait 1
+expect
+          +--------------------+
+HOLD      | . . .IIIIIIII . . .| NEXT
+  TT      | . . . . . . . . . .|   SSSS
+TTTTTT    | . . . . . . . . . .| SSSS
+          | . . . . . . . . . .|
+SCORE     | . . . . . . . . . .| ZZZZ
+36        | . . . . . . . . . .|   ZZZZ
+          | . . . . . . . . . .|
+LEVEL     | . . . . . . . . . .|     LL
+1         | . . . . . . . . . .| LLLLLL
+          | . . . . . . . . . .|
+LINES     | . . . . . . . . . .| JJ
+0         | . . . . . . . . . .| JJJJJJ
+          | . . . . . . . . . .|
+          | . . . . . . . . . .|   SSSS
+          | . . . . . . . . . .| SSSS
+          | . . . . . . . . . .|
+          | . . . . . . . . . .|
+          | . . . . . . . . . .|
+          |OOOO . . . . . . . .|
+          |OOOO .:::::::: . . .|
+          +--------------------+
+end
+render
+`
+
+func TestScript(t *testing.T) {
+	var out bytes.Buffer
+	if err := runScript(newTestClient(t, DefaultHandling()), strings.NewReader(testScript), &out); err != nil {
+		t.Fatalf("The script should pass: %s", err)
+	}
+	if !strings.HasPrefix(out.String(), "--- frame 26\n") || strings.Count(out.String(), "\n") != 24 {
+		t.Errorf("The script should render one frame, rendered:\n%s", out.String())
+	}
+}
+
+func TestScriptChecksFrames(t *testing.T) {
+	script := strings.Replace(testScript, "36 ", "37 ", 1)
+	err := runScript(newTestClient(t, DefaultHandling()), strings.NewReader(script), &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "Line 7") || !strings.Contains(err.Error(), "line 7") {
+		t.Errorf("A frame that differs should fail at its expect, got %v", err)
+	}
+}
+
+func TestScriptErrors(t *testing.T) {
+	for _, script := range []string{"jump", "wait x", "hold left", "expect\n +--"} {
+		err := runScript(newTestClient(t, DefaultHandling()), strings.NewReader(script), &bytes.Buffer{})
+		if err == nil {
+			t.Errorf("Script %q should be an error", script)
+		}
+	}
+}
+
+// Builds a script holding a key the way terminals repeat it: a press, a
+// pause before the first repeat and then a repeat every few frames.
+func terminalHold(key string, delay, interval, repeats int) string {
+	script := fmt.Sprintf("press %s\nwait %d\n", key, delay)
+	for i := 0; i < repeats; i++ {
+		script += fmt.Sprintf("press %s\nwait %d\n", key, interval)
+	}
+	return script
+}
+
+func TestScriptTerminalRepeats(t *testing.T) {
+	c := newTestClient(t, DefaultHandling())
+	start := activeCol(c)
+	if err := runScript(c, strings.NewReader(terminalHold("right", 30, 2, 0)+"wait 40\n"), &bytes.Buffer{}); err != nil {
+		t.Fatalf("The script should pass: %s", err)
+	}
+	if activeCol(c) != start+1 {
+		t.Errorf("A tap should move once, moved %d", activeCol(c)-start)
+	}
+
+	c = newTestClient(t, DefaultHandling())
+	if err := runScript(c, strings.NewReader(terminalHold("left", 30, 2, 1)), &bytes.Buffer{}); err != nil {
+		t.Fatalf("The script should pass: %s", err)
+	}
+	if activeCol(c) != start-2 {
+		t.Errorf("The first repeat after DAS has charged should shift at once, moved %d", activeCol(c)-start)
+	}
+	if err := runScript(c, strings.NewReader(terminalHold("left", 2, 2, 10)), &bytes.Buffer{}); err != nil {
+		t.Fatalf("The script should pass: %s", err)
+	}
+	if c.Game().Apply(tetris.MoveLeft).OK {
+		t.Error("Repeats should keep the key held and shift the piece to the wall")
+	}
+}
+
+func TestScriptDoubleTaps(t *testing.T) {
+	c := newTestClient(t, DefaultHandling())
+	if err := runScript(c, strings.NewReader("press x\nwait 15\npress x\n"), &bytes.Buffer{}); err != nil {
+		t.Fatalf("The script should pass: %s", err)
+	}
+	if active, _, _ := c.Game().Active(); active.Orient() != 2 {
+		t.Errorf("Tapping rotate twice should turn the piece twice, orientation is %d", active.Orient())
+	}
+
+	c = newTestClient(t, DefaultHandling())
+	if err := runScript(c, strings.NewReader("press space\nwait 20\npress space\nwait 1\n"), &bytes.Buffer{}); err != nil {
+		t.Fatalf("The script should pass: %s", err)
+	}
+	if c.Game().Scorer().Lines() != 0 || countBlocks(c.Game().Board()) != 8 {
+		t.Errorf("Tapping hard drop twice should lock two pieces, the board has %d blocks", countBlocks(c.Game().Board()))
+	}
+}
+
+func countBlocks(b *tetris.Board) int {
+	n := 0
+	for row := 0; row < b.Height(); row++ {
+		for col := 0; col < b.Width(); col++ {
+			if set, _ := b.Block(row, col); set {
+				n++
+			}
+		}
+	}
+	return n
+}
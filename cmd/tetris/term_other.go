@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func makeRaw(fd int) (func() error, error) {
+	return nil, fmt.Errorf("Raw terminal input is not supported on %s, use -script!", runtime.GOOS)
+}
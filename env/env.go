@@ -0,0 +1,393 @@
+// Package env wraps games in the reset and step interface of reinforcement
+// learning environments.
+package env
+
+import (
+	"fmt"
+
+	"github.com/paulcoyle/tetris"
+)
+
+// What the actions given to Step mean.
+type ActionSpace int
+
+const (
+	// Actions are single inputs: the tetris.Action values, and NoOp to let
+	// time pass without one.
+	Inputs ActionSpace = iota
+
+	// Actions index the observation's Moves: every final placement of the
+	// active piece, and of the piece it would swap for when holding.
+	Placements
+)
+
+var actionSpaceNames = [...]string{
+	Inputs:     "Inputs",
+	Placements: "Placements",
+}
+
+func (s ActionSpace) String() string {
+	if s < 0 || int(s) >= len(actionSpaceNames) {
+		return "ActionSpace?"
+	}
+	return actionSpaceNames[s]
+}
+
+// The input action that does nothing, and the number of input actions.
+const (
+	NoOp      = int(tetris.Hold) + 1
+	NumInputs = NoOp + 1
+)
+
+// Settings for an environment.
+type Config struct {
+	// The rules games are played with.  Its randomizer is ignored in favour
+	// of one made from the seed given to Reset.
+	Game       tetris.GameConfig
+	Randomizer func(seed int64) tetris.Randomizer
+
+	Actions ActionSpace
+
+	// Frames the game advances after each input action.  With zero, pieces
+	// only lock when hard dropped.  Placement actions never advance time
+	// other than to skip the entry delay.
+	TicksPerStep int
+
+	// Ends an episode after this many steps, zero for no limit.
+	MaxSteps int
+
+	Rewards Rewards
+}
+
+// Returns an environment for guideline games taking placement actions, with
+// the default rewards.
+func DefaultConfig() Config {
+	return Config{
+		Game:       tetris.DefaultGameConfig(0),
+		Randomizer: tetris.NewBagRandomizer,
+		Actions:    Placements,
+		Rewards:    DefaultRewards(),
+	}
+}
+
+// A final placement of a piece, after holding first if Hold is set.
+type Move struct {
+	Hold bool
+	*tetris.Placement
+}
+
+// What the agent sees of a game.
+type Observation struct {
+	Width  int
+	Height int
+
+	// The board, row-major from the top: 1 for set blocks and 0 for unset.
+	// The active piece is not included.
+	Board []float32
+
+	// The active piece as an index into tetris.Kinds, -1 while there is
+	// none, and where its pivot is.
+	Piece  int
+	Orient int
+	Row    int
+	Col    int
+
+	Queue         []int // the previews as indices into tetris.Kinds, next first
+	Hold          int   // the held kind as an index into tetris.Kinds, -1 when empty
+	HoldAvailable bool
+
+	// With placement actions, the moves Step takes the index of.
+	Moves []Move
+}
+
+// Returns the observation as one flat vector: the board followed by one-hot
+// encodings of the active piece, each preview and the held piece, and 1 if
+// holding is allowed.  A missing piece encodes as all zeros.
+func (o *Observation) Vector() []float32 {
+	kinds := len(tetris.Kinds)
+	v := make([]float32, len(o.Board), len(o.Board)+kinds*(len(o.Queue)+2)+1)
+	copy(v, o.Board)
+
+	oneHot := func(piece int) {
+		start := len(v)
+		v = append(v, make([]float32, kinds)...)
+		if piece >= 0 {
+			v[start+piece] = 1
+		}
+	}
+	oneHot(o.Piece)
+	for _, piece := range o.Queue {
+		oneHot(piece)
+	}
+	oneHot(o.Hold)
+
+	if o.HoldAvailable {
+		v = append(v, 1)
+	} else {
+		v = append(v, 0)
+	}
+	return v
+}
+
+// What happened during a step, for computing its reward.
+type Transition struct {
+	Before *tetris.GameSnapshot
+	After  *tetris.GameSnapshot
+	Locks  []*tetris.LockEvent // the pieces locked during the step
+}
+
+// The rewards for what happens during a step.  Each one is added up.
+type Rewards struct {
+	Clear    [5]float64 // by the number of lines cleared by a piece at once
+	Points   float64    // per point scored
+	Piece    float64    // per piece locked
+	Step     float64    // per step taken
+	GameOver float64    // on topping out
+
+	// An extra reward computed from the transition, if not nil.
+	Custom func(*Transition) float64
+}
+
+// Returns rewards of 1, 3, 5 and 8 for clearing one to four lines at once and
+// -10 for topping out.
+func DefaultRewards() Rewards {
+	return Rewards{Clear: [5]float64{0, 1, 3, 5, 8}, GameOver: -10}
+}
+
+// Returns the reward for the transition.
+func (r *Rewards) Reward(t *Transition) float64 {
+	reward := r.Step + r.Points*float64(t.After.Score-t.Before.Score)
+	for _, lock := range t.Locks {
+		reward += r.Piece
+		if lines := lock.Lines; lines < len(r.Clear) {
+			reward += r.Clear[lines]
+		} else {
+			reward += r.Clear[len(r.Clear)-1]
+		}
+	}
+	if t.After.Over && !t.Before.Over {
+		reward += r.GameOver
+	}
+	if r.Custom != nil {
+		reward += r.Custom(t)
+	}
+	return reward
+}
+
+// Counts kept over an episode.
+type Info struct {
+	Steps  int
+	Pieces int
+	Lines  int
+	Score  int
+
+	// Set when the episode ended at MaxSteps rather than by topping out.
+	Truncated bool
+}
+
+// The outcome of a step.
+type StepResult struct {
+	Observation *Observation
+	Reward      float64
+	Done        bool
+	Info        Info
+}
+
+// An environment playing one game at a time.
+type Env struct {
+	config Config
+	game   *tetris.Game
+	obs    *Observation
+	info   Info
+	done   bool
+}
+
+// Creates an environment.  Reset must be called before the first step.
+func New(config Config) (*Env, error) {
+	if config.Randomizer == nil {
+		return nil, fmt.Errorf("An environment needs a randomizer!")
+	}
+	if config.Game.Rotation == nil {
+		return nil, fmt.Errorf("An environment needs a rotation system!")
+	}
+	if config.Actions != Inputs && config.Actions != Placements {
+		return nil, fmt.Errorf("Unknown action space %d!", config.Actions)
+	}
+	return &Env{config: config, done: true}, nil
+}
+
+func (e *Env) Config() Config {
+	return e.config
+}
+
+// Returns the game being played, nil before the first Reset.  It must not be
+// modified.
+func (e *Env) Game() *tetris.Game {
+	return e.game
+}
+
+// Returns the number of actions Step accepts right now: NumInputs with input
+// actions, or the number of moves observed with placement actions.
+func (e *Env) NumActions() int {
+	if e.config.Actions == Inputs {
+		return NumInputs
+	}
+	if e.obs == nil {
+		return 0
+	}
+	return len(e.obs.Moves)
+}
+
+// Starts a new episode with pieces dealt from the given seed and returns the
+// first observation.  The same seed and actions always play out the same.
+func (e *Env) Reset(seed int64) (*Observation, error) {
+	config := e.config.Game
+	config.Randomizer = e.config.Randomizer(seed)
+	game, err := tetris.NewGame(config)
+	if err != nil {
+		return nil, err
+	}
+
+	e.game, e.info = game, Info{}
+	e.skipEntry(nil)
+	e.done = game.Over()
+	e.obs = e.observe()
+	return e.obs, nil
+}
+
+// Takes an action and returns what followed.  It is an error to step an
+// environment that is done or to give an action out of range.
+func (e *Env) Step(action int) (*StepResult, error) {
+	if e.done {
+		return nil, fmt.Errorf("Step called on an environment that is done, call Reset first!")
+	}
+	if action < 0 || action >= e.NumActions() {
+		return nil, fmt.Errorf("Action %d is out of range, there are %d!", action, e.NumActions())
+	}
+
+	t := &Transition{Before: e.game.Snapshot()}
+	if e.config.Actions == Inputs {
+		e.input(action, t)
+	} else if err := e.place(e.obs.Moves[action], t); err != nil {
+		return nil, err
+	}
+	t.After = e.game.Snapshot()
+
+	e.info.Steps++
+	e.info.Pieces += len(t.Locks)
+	e.info.Lines, e.info.Score = t.After.Lines, t.After.Score
+	e.done = t.After.Over
+	if !e.done && e.config.MaxSteps > 0 && e.info.Steps >= e.config.MaxSteps {
+		e.done, e.info.Truncated = true, true
+	}
+
+	e.obs = e.observe()
+	return &StepResult{e.obs, e.config.Rewards.Reward(t), e.done, e.info}, nil
+}
+
+// Applies an input and advances time.
+func (e *Env) input(action int, t *Transition) {
+	if action != NoOp {
+		if res := e.game.Apply(tetris.Action(action)); res.Lock != nil {
+			t.Locks = append(t.Locks, res.Lock)
+		}
+	}
+	for i := 0; i < e.config.TicksPerStep && !e.game.Over(); i++ {
+		if lock := e.game.Tick(); lock != nil {
+			t.Locks = append(t.Locks, lock)
+		}
+	}
+	e.skipEntry(t)
+}
+
+// Plays the inputs leading to a move.  They are played on a clone of the
+// game, so a move that cannot be played leaves the environment as it was.
+func (e *Env) place(m Move, t *Transition) error {
+	game := e.game.Clone()
+	if m.Hold && !game.Hold().OK {
+		return fmt.Errorf("Hold was refused!")
+	}
+	var locks []*tetris.LockEvent
+	for _, a := range m.Path {
+		res := game.Apply(a)
+		if !res.OK {
+			return fmt.Errorf("%s along the path to %v was blocked!", a, m.Cells)
+		}
+		if res.Lock != nil {
+			locks = append(locks, res.Lock)
+		}
+	}
+
+	e.game = game
+	t.Locks = append(t.Locks, locks...)
+	e.skipEntry(t)
+	return nil
+}
+
+// Advances time until the next piece spawns, so every observation has a piece
+// in play unless the game is over.
+func (e *Env) skipEntry(t *Transition) {
+	for !e.game.Over() {
+		if active, _, _ := e.game.Active(); active != nil {
+			return
+		}
+		if lock := e.game.Tick(); lock != nil && t != nil {
+			t.Locks = append(t.Locks, lock)
+		}
+	}
+}
+
+func (e *Env) observe() *Observation {
+	g := e.game
+	b := g.Board()
+	o := &Observation{
+		Width:         b.Width(),
+		Height:        b.Height(),
+		Board:         make([]float32, b.Width()*b.Height()),
+		Piece:         -1,
+		Hold:          -1,
+		HoldAvailable: g.HoldAvailable(),
+	}
+	for row := 0; row < b.Height(); row++ {
+		for col := 0; col < b.Width(); col++ {
+			if set, _ := b.Block(row, col); set {
+				o.Board[row*b.Width()+col] = 1
+			}
+		}
+	}
+
+	active, row, col := g.Active()
+	if active != nil {
+		o.Piece, o.Orient, o.Row, o.Col = tetris.KindIndex(active.Kind()), active.Orient(), row, col
+	}
+	queue := g.Queue()
+	for _, kind := range queue {
+		o.Queue = append(o.Queue, tetris.KindIndex(kind))
+	}
+	if held := g.Held(); held != nil {
+		o.Hold = tetris.KindIndex(held.Kind())
+	}
+
+	if e.config.Actions == Placements && active != nil && !g.Over() {
+		rs := e.config.Game.Rotation
+		for _, p := range tetris.FindPlacements(rs, b, active.Kind(), active.Orient(), row, col) {
+			o.Moves = append(o.Moves, Move{false, p})
+		}
+
+		// Holding brings out the held piece, or the next one if nothing is
+		// held, at its spawn position.
+		swap := ""
+		if held := g.Held(); held != nil {
+			swap = held.Kind()
+		} else if len(queue) > 0 {
+			swap = queue[0]
+		}
+		if o.HoldAvailable && swap != "" && swap != active.Kind() {
+			orient, row, col := rs.Spawn(swap, b.Width())
+			for _, p := range tetris.FindPlacements(rs, b, swap, orient, row, col) {
+				o.Moves = append(o.Moves, Move{true, p})
+			}
+		}
+	}
+	return o
+}
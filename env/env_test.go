@@ -0,0 +1,278 @@
+package env
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/paulcoyle/tetris"
+)
+
+func newTestEnv(t *testing.T, config Config) *Env {
+	e, err := New(config)
+	if err != nil {
+		t.Fatalf("Environment should be created: %s", err)
+	}
+	return e
+}
+
+func reset(t *testing.T, e *Env, seed int64) *Observation {
+	obs, err := e.Reset(seed)
+	if err != nil {
+		t.Fatalf("Reset should succeed: %s", err)
+	}
+	return obs
+}
+
+func step(t *testing.T, e *Env, action int) *StepResult {
+	res, err := e.Step(action)
+	if err != nil {
+		t.Fatalf("Step %d should succeed: %s", action, err)
+	}
+	return res
+}
+
+// Returns the index of the move that locks highest up the board, to top out
+// quickly.
+func highest(obs *Observation) int {
+	best := 0
+	for i, m := range obs.Moves {
+		if m.Cells[0][0] < obs.Moves[best].Cells[0][0] {
+			best = i
+		}
+	}
+	return best
+}
+
+func TestNewChecksConfig(t *testing.T) {
+	config := DefaultConfig()
+	config.Randomizer = nil
+	if _, err := New(config); err == nil {
+		t.Error("An environment without a randomizer should be an error")
+	}
+
+	config = DefaultConfig()
+	config.Actions = 5
+	if _, err := New(config); err == nil {
+		t.Error("An unknown action space should be an error")
+	}
+}
+
+func TestResetObservation(t *testing.T) {
+	e := newTestEnv(t, DefaultConfig())
+	if _, err := e.Step(0); err == nil {
+		t.Error("Stepping before a reset should be an error")
+	}
+
+	obs := reset(t, e, 1)
+	if obs.Width != 10 || obs.Height != 20 || len(obs.Board) != 200 {
+		t.Errorf("The board should be 10x20, got %dx%d with %d values", obs.Width, obs.Height, len(obs.Board))
+	}
+	for _, v := range obs.Board {
+		if v != 0 {
+			t.Fatal("The board should start empty")
+		}
+	}
+	if obs.Piece < 0 || len(obs.Queue) != 5 || obs.Hold != -1 || !obs.HoldAvailable {
+		t.Errorf("There should be a piece, 5 previews and an empty hold, got %+v", obs)
+	}
+	if len(obs.Moves) == 0 || e.NumActions() != len(obs.Moves) {
+		t.Errorf("Every move should be an action, got %d moves and %d actions", len(obs.Moves), e.NumActions())
+	}
+
+	if len(obs.Vector()) != 200+7*7+1 {
+		t.Errorf("The vector should hold the board, 7 one-hot pieces and the hold flag, has %d values", len(obs.Vector()))
+	}
+}
+
+func TestStepPlacesMove(t *testing.T) {
+	e := newTestEnv(t, DefaultConfig())
+	obs := reset(t, e, 1)
+	queue := obs.Queue
+
+	move := obs.Moves[0]
+	res := step(t, e, 0)
+	for row := 0; row < 20; row++ {
+		for col := 0; col < 10; col++ {
+			set, _ := move.Board.Block(row, col)
+			if set != (res.Observation.Board[row*10+col] == 1) {
+				t.Fatalf("The board should be the move's, differs at (%d,%d)", row, col)
+			}
+		}
+	}
+	if res.Observation.Piece != queue[0] {
+		t.Errorf("The next piece should come into play, got %d", res.Observation.Piece)
+	}
+	if res.Done || res.Info.Steps != 1 || res.Info.Pieces != 1 {
+		t.Errorf("One piece should be placed, got %+v", res.Info)
+	}
+
+	if _, err := e.Step(e.NumActions()); err == nil {
+		t.Error("An action out of range should be an error")
+	}
+}
+
+func TestStepHoldMove(t *testing.T) {
+	e := newTestEnv(t, DefaultConfig())
+	obs := reset(t, e, 1)
+	piece := obs.Piece
+
+	hold := -1
+	for i, m := range obs.Moves {
+		if m.Hold {
+			hold = i
+			break
+		}
+	}
+	if hold < 0 {
+		t.Fatal("Holding should offer moves")
+	}
+	if kind := obs.Moves[hold].Kind; kind != tetris.Kinds[obs.Queue[0]] {
+		t.Errorf("Holding should place the next piece, places %s", kind)
+	}
+
+	res := step(t, e, hold)
+	if res.Observation.Hold != piece {
+		t.Errorf("The active piece should be held, hold has %d", res.Observation.Hold)
+	}
+	if res.Observation.Piece != obs.Queue[1] {
+		t.Errorf("The piece after the next should be in play, got %d", res.Observation.Piece)
+	}
+}
+
+func TestStepBlockedMoveChangesNothing(t *testing.T) {
+	e := newTestEnv(t, DefaultConfig())
+	obs := reset(t, e, 1)
+	before := e.game.Snapshot()
+
+	// A hold move whose path runs into the wall after the hold.
+	hold := -1
+	for i, m := range obs.Moves {
+		if m.Hold {
+			hold = i
+			break
+		}
+	}
+	p := *obs.Moves[hold].Placement
+	p.Path = make([]tetris.Action, e.obs.Width)
+	for i := range p.Path {
+		p.Path[i] = tetris.MoveLeft
+	}
+	obs.Moves[hold].Placement = &p
+
+	if _, err := e.Step(hold); err == nil {
+		t.Fatal("A move that cannot be played should be an error")
+	}
+	if !reflect.DeepEqual(e.game.Snapshot(), before) || e.obs != obs {
+		t.Error("A move that cannot be played should leave the game and observation as they were")
+	}
+
+	res := step(t, e, 0)
+	if res.Info.Pieces != 1 {
+		t.Error("The environment should still play after a blocked move")
+	}
+}
+
+func TestStepEndsOnTopOut(t *testing.T) {
+	config := DefaultConfig()
+	config.Rewards.Piece = 1
+	e := newTestEnv(t, config)
+	obs := reset(t, e, 1)
+
+	var res *StepResult
+	for i := 0; i < 100; i++ {
+		res = step(t, e, highest(obs))
+		if res.Done {
+			break
+		}
+		if res.Reward != 1 {
+			t.Errorf("A piece placed without clearing should reward 1, got %f", res.Reward)
+		}
+		obs = res.Observation
+	}
+	if !res.Done || res.Info.Truncated {
+		t.Fatal("Stacking up should top out")
+	}
+	if res.Reward != 1-10 {
+		t.Errorf("Topping out should reward -10 plus the piece, got %f", res.Reward)
+	}
+	if len(res.Observation.Moves) != 0 || res.Observation.Piece != -1 {
+		t.Error("A finished game should have no piece or moves")
+	}
+	if _, err := e.Step(0); err == nil {
+		t.Error("Stepping when done should be an error")
+	}
+}
+
+func TestStepTruncatesAtMaxSteps(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxSteps = 3
+	e := newTestEnv(t, config)
+	reset(t, e, 1)
+
+	for i := 1; i <= 3; i++ {
+		res := step(t, e, 0)
+		if res.Done != (i == 3) || res.Info.Truncated != (i == 3) {
+			t.Errorf("Step %d should be done only at the limit, got %+v", i, res.Info)
+		}
+	}
+}
+
+func TestInputActions(t *testing.T) {
+	config := DefaultConfig()
+	config.Actions = Inputs
+	config.Rewards.Step = -0.5
+	e := newTestEnv(t, config)
+	obs := reset(t, e, 1)
+	if e.NumActions() != NumInputs || obs.Moves != nil {
+		t.Errorf("Input actions should not list moves, has %d actions", e.NumActions())
+	}
+
+	res := step(t, e, int(tetris.MoveLeft))
+	if res.Observation.Col != obs.Col-1 || res.Reward != -0.5 {
+		t.Errorf("The piece should move left, is at column %d", res.Observation.Col)
+	}
+	res = step(t, e, NoOp)
+	if res.Observation.Col != obs.Col-1 || res.Info.Pieces != 0 {
+		t.Error("Doing nothing without ticks should leave the piece alone")
+	}
+	res = step(t, e, int(tetris.HardDrop))
+	if res.Info.Pieces != 1 || res.Observation.Piece != obs.Queue[0] {
+		t.Errorf("A hard drop should lock the piece, got %+v", res.Info)
+	}
+}
+
+func TestInputActionsTick(t *testing.T) {
+	config := DefaultConfig()
+	config.Actions = Inputs
+	config.TicksPerStep = 60
+	e := newTestEnv(t, config)
+	obs := reset(t, e, 1)
+
+	res := step(t, e, NoOp)
+	if res.Observation.Row != obs.Row+1 {
+		t.Errorf("A second of gravity should drop the piece a row, from %d to %d", obs.Row, res.Observation.Row)
+	}
+}
+
+func TestEnvIsDeterministic(t *testing.T) {
+	play := func() []*StepResult {
+		e := newTestEnv(t, DefaultConfig())
+		obs := reset(t, e, 9)
+		var results []*StepResult
+		for i := 0; i < 20 && len(obs.Moves) > 0; i++ {
+			res := step(t, e, len(obs.Moves)/2)
+			obs = res.Observation
+
+			// Placements hold pointers, so compare the rest.
+			plain := *res.Observation
+			plain.Moves = nil
+			res.Observation = &plain
+			results = append(results, res)
+		}
+		return results
+	}
+
+	if !reflect.DeepEqual(play(), play()) {
+		t.Error("The same seed and actions should play the same")
+	}
+}
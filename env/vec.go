@@ -0,0 +1,93 @@
+package env
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Several environments stepped together, each on its own goroutine.
+type VecEnv struct {
+	envs []*Env
+}
+
+// Creates n environments with the same settings.
+func NewVec(config Config, n int) (*VecEnv, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("A vector of environments needs at least one!")
+	}
+
+	v := &VecEnv{}
+	for i := 0; i < n; i++ {
+		e, err := New(config)
+		if err != nil {
+			return nil, err
+		}
+		v.envs = append(v.envs, e)
+	}
+	return v, nil
+}
+
+// Returns the number of environments.
+func (v *VecEnv) Len() int {
+	return len(v.envs)
+}
+
+// Returns the environment at the given index, e.g. to reset it alone once it
+// is done.
+func (v *VecEnv) Env(i int) *Env {
+	return v.envs[i]
+}
+
+// Resets every environment, each with its own seed.
+func (v *VecEnv) Reset(seeds []int64) ([]*Observation, error) {
+	if len(seeds) != len(v.envs) {
+		return nil, fmt.Errorf("%d seeds given for %d environments!", len(seeds), len(v.envs))
+	}
+
+	obs := make([]*Observation, len(v.envs))
+	err := v.each(func(i int) (err error) {
+		obs[i], err = v.envs[i].Reset(seeds[i])
+		return err
+	})
+	return obs, err
+}
+
+// Steps every environment with its own action.  Environments given a negative
+// action are left alone, so those that are done can be skipped, and their
+// result is nil.  The results are the same as stepping each in turn.
+func (v *VecEnv) Step(actions []int) ([]*StepResult, error) {
+	if len(actions) != len(v.envs) {
+		return nil, fmt.Errorf("%d actions given for %d environments!", len(actions), len(v.envs))
+	}
+
+	results := make([]*StepResult, len(v.envs))
+	err := v.each(func(i int) (err error) {
+		if actions[i] >= 0 {
+			results[i], err = v.envs[i].Step(actions[i])
+		}
+		return err
+	})
+	return results, err
+}
+
+// Calls job for every environment at once, returning the error of the lowest
+// numbered one that failed.
+func (v *VecEnv) each(job func(int) error) error {
+	errs := make([]error, len(v.envs))
+	var wg sync.WaitGroup
+	for i := range v.envs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = job(i)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("Environment %d: %s", i, err)
+		}
+	}
+	return nil
+}
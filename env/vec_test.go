@@ -0,0 +1,57 @@
+package env
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVecMatchesSingleEnvs(t *testing.T) {
+	v, err := NewVec(DefaultConfig(), 4)
+	if err != nil {
+		t.Fatalf("Environments should be created: %s", err)
+	}
+	seeds := []int64{1, 2, 3, 4}
+	if _, err := v.Reset(seeds); err != nil {
+		t.Fatalf("Reset should succeed: %s", err)
+	}
+
+	singles := make([]*Env, 4)
+	for i := range singles {
+		singles[i] = newTestEnv(t, DefaultConfig())
+		reset(t, singles[i], seeds[i])
+	}
+
+	for n := 0; n < 10; n++ {
+		actions := []int{0, 1, -1, 2}
+		results, err := v.Step(actions)
+		if err != nil {
+			t.Fatalf("Step should succeed: %s", err)
+		}
+		if results[2] != nil {
+			t.Error("An environment given a negative action should not step")
+		}
+		for i, a := range actions {
+			if a < 0 {
+				continue
+			}
+			want := step(t, singles[i], a)
+			if !reflect.DeepEqual(results[i].Info, want.Info) || !reflect.DeepEqual(results[i].Observation.Board, want.Observation.Board) {
+				t.Fatalf("Environment %d should step like a single one", i)
+			}
+		}
+	}
+}
+
+func TestVecChecksLengths(t *testing.T) {
+	v, _ := NewVec(DefaultConfig(), 2)
+	if _, err := v.Reset([]int64{1}); err == nil {
+		t.Error("Too few seeds should be an error")
+	}
+	v.Reset([]int64{1, 2})
+	if _, err := v.Step([]int{0, 0, 0}); err == nil {
+		t.Error("Too many actions should be an error")
+	}
+	if _, err := NewVec(DefaultConfig(), 0); err == nil {
+		t.Error("No environments should be an error")
+	}
+}
@@ -0,0 +1,210 @@
+package tetris
+
+// A property of a board, or of a placement on it, used to judge how good the
+// placement is.
+type Feature int
+
+const (
+	AggregateHeight   Feature = iota // sum of the column heights
+	Holes                            // empty blocks with a set block above them
+	Bumpiness                        // sum of height differences of neighbouring columns
+	RowTransitions                   // changes between set and empty along rows, walls set
+	ColumnTransitions                // changes between set and empty down columns, floor set
+	Wells                            // sum of 1+2+...+depth over every well
+	LandingHeight                    // height of the middle of the placed piece
+	ErodedCells                      // lines cleared times the piece's blocks cleared
+	NumFeatures
+)
+
+var featureNames = [...]string{
+	AggregateHeight:   "AggregateHeight",
+	Holes:             "Holes",
+	Bumpiness:         "Bumpiness",
+	RowTransitions:    "RowTransitions",
+	ColumnTransitions: "ColumnTransitions",
+	Wells:             "Wells",
+	LandingHeight:     "LandingHeight",
+	ErodedCells:       "ErodedCells",
+}
+
+func (f Feature) String() string {
+	if f < 0 || f >= NumFeatures {
+		return "Feature?"
+	}
+	return featureNames[f]
+}
+
+// How much each feature counts towards a score, indexed by Feature.
+type Weights [NumFeatures]float64
+
+// Pierre Dellacherie's hand tuned weights.
+var DellacherieWeights = Weights{
+	LandingHeight:     -1,
+	ErodedCells:       1,
+	RowTransitions:    -1,
+	ColumnTransitions: -1,
+	Holes:             -4,
+	Wells:             -1,
+}
+
+// Yiyuan Lee's El-Tetris weights, found by particle swarm optimisation over
+// Dellacherie's features.
+var ElTetrisWeights = Weights{
+	LandingHeight:     -4.500158825082766,
+	ErodedCells:       3.4181268101392694,
+	RowTransitions:    -3.2178882868487753,
+	ColumnTransitions: -9.348695305445199,
+	Holes:             -7.899265427351652,
+	Wells:             -3.3855972247263626,
+}
+
+// Scores boards and placements as a weighted sum of their features.  Higher
+// scores are better.
+type Evaluator struct {
+	weights Weights
+}
+
+// The features of a board or placement and how much each added to its score.
+type Evaluation struct {
+	Features Weights // the value of each feature
+	Terms    Weights // each feature's value times its weight
+	Score    float64 // the sum of the terms
+}
+
+// Creates an evaluator that uses the given weights.
+func NewEvaluator(weights Weights) *Evaluator {
+	return &Evaluator{weights}
+}
+
+func (e *Evaluator) Weights() Weights {
+	return e.weights
+}
+
+// Evaluates a placement made on the given board, which should not yet hold
+// the piece.  Board features are measured on the placement's board, after
+// lines were cleared.
+func (e *Evaluator) Evaluate(before *Board, p *Placement) *Evaluation {
+	var features Weights
+	boardFeatures(p.Board, &features)
+
+	top, bottom := p.Cells[0][0], p.Cells[3][0]
+	features[LandingHeight] = float64(2*before.Height()-top-bottom) / 2
+
+	if p.Lines > 0 {
+		eroded := 0
+		for _, cell := range p.Cells {
+			if pieceFillsRow(before, p, cell[0]) {
+				eroded++
+			}
+		}
+		features[ErodedCells] = float64(p.Lines * eroded)
+	}
+
+	return e.weigh(features)
+}
+
+// Evaluates a board on its own.  The placement features are zero.
+func (e *Evaluator) EvaluateBoard(b *Board) *Evaluation {
+	var features Weights
+	boardFeatures(b, &features)
+	return e.weigh(features)
+}
+
+// Returns the score of a placement made on the given board.
+func (e *Evaluator) Score(before *Board, p *Placement) float64 {
+	return e.Evaluate(before, p).Score
+}
+
+func (e *Evaluator) weigh(features Weights) *Evaluation {
+	eval := &Evaluation{Features: features}
+	for f := range features {
+		eval.Terms[f] = features[f] * e.weights[f]
+		eval.Score += eval.Terms[f]
+	}
+	return eval
+}
+
+// Returns true if the placement's blocks fill the rest of the given row.
+func pieceFillsRow(b *Board, p *Placement, row int) bool {
+	empty := 0
+	for col := 0; col < b.Width(); col++ {
+		if !b.isSet(row, col) {
+			empty++
+		}
+	}
+	for _, cell := range p.Cells {
+		if cell[0] == row {
+			empty--
+		}
+	}
+	return empty == 0
+}
+
+// Measures the features that depend only on the board.
+func boardFeatures(b *Board, features *Weights) {
+	w, h := b.Width(), b.Height()
+
+	heights := make([]int, w)
+	for col := 0; col < w; col++ {
+		filled := false
+		for row := 0; row < h; row++ {
+			set := b.isSet(row, col)
+			if set && !filled {
+				heights[col] = h - row
+				filled = true
+			} else if !set && filled {
+				features[Holes]++
+			}
+
+			// Above the board counts as empty, the floor as set.
+			if row > 0 && set != b.isSet(row-1, col) {
+				features[ColumnTransitions]++
+			}
+		}
+		if b.isSet(0, col) {
+			features[ColumnTransitions]++
+		}
+		if !b.isSet(h-1, col) {
+			features[ColumnTransitions]++
+		}
+		features[AggregateHeight] += float64(heights[col])
+	}
+
+	for col := 0; col+1 < w; col++ {
+		diff := heights[col] - heights[col+1]
+		if diff < 0 {
+			diff = -diff
+		}
+		features[Bumpiness] += float64(diff)
+	}
+
+	for row := 0; row < h; row++ {
+		// The walls count as set.
+		prev := true
+		for col := 0; col < w; col++ {
+			set := b.isSet(row, col)
+			if set != prev {
+				features[RowTransitions]++
+			}
+			prev = set
+		}
+		if !prev {
+			features[RowTransitions]++
+		}
+	}
+
+	for col := 0; col < w; col++ {
+		depth := 0
+		for row := 0; row < h; row++ {
+			walled := !b.isSet(row, col) &&
+				(col == 0 || b.isSet(row, col-1)) &&
+				(col == w-1 || b.isSet(row, col+1))
+			if walled {
+				depth++
+				features[Wells] += float64(depth)
+			} else {
+				depth = 0
+			}
+		}
+	}
+}
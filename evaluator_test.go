@@ -0,0 +1,109 @@
+package tetris
+
+import (
+	"testing"
+)
+
+func TestFeatureString(t *testing.T) {
+	if Wells.String() != "Wells" {
+		t.Error("Feature should be named Wells")
+	}
+	if Feature(99).String() != "Feature?" {
+		t.Error("Unknown features should have a placeholder name")
+	}
+}
+
+func TestEvaluateBoardFeatures(t *testing.T) {
+	board, _ := StringArrayToBoard([]string{
+		"|     |",
+		"|     |",
+		"|#    |",
+		"|# # #|",
+		"|## ##|",
+	})
+
+	eval := NewEvaluator(DellacherieWeights).EvaluateBoard(board)
+	expected := map[Feature]float64{
+		AggregateHeight:   9,
+		Holes:             1,
+		Bumpiness:         5,
+		RowTransitions:    12,
+		ColumnTransitions: 7,
+		Wells:             3,
+		LandingHeight:     0,
+		ErodedCells:       0,
+	}
+	for f, value := range expected {
+		if eval.Features[f] != value {
+			t.Errorf("%s should be %v, was %v", f, value, eval.Features[f])
+		}
+	}
+}
+
+func TestEvaluateEmptyBoard(t *testing.T) {
+	board, _ := NewBoard(10, 20)
+	eval := NewEvaluator(ElTetrisWeights).EvaluateBoard(board)
+	if eval.Features[RowTransitions] != 40 {
+		t.Errorf("Every empty row should have two transitions, had %v", eval.Features[RowTransitions])
+	}
+	if eval.Features[ColumnTransitions] != 10 {
+		t.Errorf("Every empty column should have one transition, had %v", eval.Features[ColumnTransitions])
+	}
+}
+
+func TestEvaluatePlacement(t *testing.T) {
+	board, _ := StringArrayToBoard([]string{
+		"|    |",
+		"|    |",
+		"|    |",
+		"|### |",
+	})
+
+	var well *Placement
+	state, row, col := SRS.Spawn("I", 4)
+	for _, p := range FindPlacements(SRS, board, "I", state, row, col) {
+		if p.Lines == 1 {
+			well = p
+		}
+	}
+	if well == nil {
+		t.Fatal("A vertical I should clear a line")
+	}
+
+	e := NewEvaluator(DellacherieWeights)
+	eval := e.Evaluate(board, well)
+	if eval.Features[LandingHeight] != 2.5 {
+		t.Errorf("Landing height should be 2.5, was %v", eval.Features[LandingHeight])
+	}
+	if eval.Features[ErodedCells] != 1 {
+		t.Errorf("One line with one piece block should erode 1, was %v", eval.Features[ErodedCells])
+	}
+
+	sum := 0.0
+	for f := range eval.Terms {
+		if eval.Terms[f] != eval.Features[f]*e.Weights()[f] {
+			t.Errorf("The %s term should be its value times its weight", Feature(f))
+		}
+		sum += eval.Terms[f]
+	}
+	if sum != eval.Score || e.Score(board, well) != eval.Score {
+		t.Error("The score should be the sum of the terms")
+	}
+}
+
+func TestEvaluatorPrefersFlatBoards(t *testing.T) {
+	board, _ := NewBoard(10, 20)
+	state, row, col := SRS.Spawn("O", 10)
+
+	e := NewEvaluator(ElTetrisWeights)
+	placements := FindPlacements(SRS, board, "O", state, row, col)
+	best := placements[0]
+	for _, p := range placements[1:] {
+		if e.Score(board, p) > e.Score(board, best) {
+			best = p
+		}
+	}
+	if best.Cells[0][1] != 0 && best.Cells[1][1] != 9 {
+		t.Errorf("An O on an empty board should go against a wall, went to %v", best.Cells)
+	}
+}
@@ -0,0 +1,421 @@
+package fumen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/paulcoyle/tetris"
+)
+
+// The field of a page as fumen stores it: a piece number per block, row-major
+// from the top row of the board with the garbage row last.
+type field [numBlocks]int
+
+const (
+	numBlocks = (Height + 1) * Width
+
+	empty = 0
+	gray  = 8
+)
+
+// Fumen's piece numbers, indexed by cell.
+var cellPieces = [...]int{
+	tetris.CellEmpty:   empty,
+	tetris.CellI:       1,
+	tetris.CellL:       2,
+	tetris.CellO:       3,
+	tetris.CellZ:       4,
+	tetris.CellT:       5,
+	tetris.CellJ:       6,
+	tetris.CellS:       7,
+	tetris.CellGarbage: gray,
+}
+
+// The cells of fumen's piece numbers.
+var pieceCells = [...]tetris.Cell{
+	empty: tetris.CellEmpty,
+	1:     tetris.CellI,
+	2:     tetris.CellL,
+	3:     tetris.CellO,
+	4:     tetris.CellZ,
+	5:     tetris.CellT,
+	6:     tetris.CellJ,
+	7:     tetris.CellS,
+	gray:  tetris.CellGarbage,
+}
+
+// Returns fumen's piece number for a cell.  User-defined cells become gray.
+func cellPiece(c tetris.Cell) int {
+	if int(c) < len(cellPieces) {
+		return cellPieces[c]
+	}
+	return gray
+}
+
+// Returns the field of a board and garbage row.  Boards shorter than Height
+// rest on the floor.
+func newField(b *tetris.Board, garbage *[Width]tetris.Cell) (*field, error) {
+	if b.Width() != Width || b.Height() > Height {
+		return nil, fmt.Errorf("Board is %dx%d, fumen fields are %d wide and at most %d high!",
+			b.Width(), b.Height(), Width, Height)
+	}
+
+	f := &field{}
+	top := Height - b.Height()
+	for row := 0; row < b.Height(); row++ {
+		for col := 0; col < Width; col++ {
+			cell, _ := b.Cell(row, col)
+			f[(top+row)*Width+col] = cellPiece(cell)
+		}
+	}
+	for col, cell := range garbage {
+		f[Height*Width+col] = cellPiece(cell)
+	}
+	return f, nil
+}
+
+// Returns the field's board and garbage row.
+func (f *field) board() (*tetris.Board, [Width]tetris.Cell) {
+	b, _ := tetris.NewBoard(Width, Height)
+	var garbage [Width]tetris.Cell
+	for i, piece := range f {
+		if i < Height*Width {
+			b.SetCell(i/Width, i%Width, pieceCells[piece])
+		} else {
+			garbage[i-Height*Width] = pieceCells[piece]
+		}
+	}
+	return b, garbage
+}
+
+// Fills the blocks under a piece.
+func (f *field) fill(p *piece) error {
+	for _, c := range p.cells() {
+		x, y := c[0], c[1]
+		if x < 0 || x >= Width || y < 0 || y >= Height {
+			return fmt.Errorf("%s at (%d,%d) is outside the field!", kinds[p.kind], p.x, p.y)
+		}
+		f[(Height-1-y)*Width+x] = p.kind
+	}
+	return nil
+}
+
+// Removes full rows above the garbage row, moving the rest down.
+func (f *field) clearLines() {
+	to := Height - 1
+	for from := Height - 1; from >= 0; from-- {
+		full := true
+		for col := 0; col < Width; col++ {
+			full = full && f[from*Width+col] != empty
+		}
+		if full {
+			continue
+		}
+		copy(f[to*Width:(to+1)*Width], f[from*Width:(from+1)*Width])
+		to--
+	}
+	for ; to >= 0; to-- {
+		for col := 0; col < Width; col++ {
+			f[to*Width+col] = empty
+		}
+	}
+}
+
+// Moves every row up one, losing the top row, and empties the garbage row.
+func (f *field) rise() {
+	copy(f[:], f[Width:])
+	for col := 0; col < Width; col++ {
+		f[Height*Width+col] = empty
+	}
+}
+
+// Flips the rows above the garbage row left to right.
+func (f *field) mirror() {
+	for row := 0; row < Height; row++ {
+		r := f[row*Width : (row+1)*Width]
+		for l, r2 := 0, Width-1; l < r2; l, r2 = l+1, r2-1 {
+			r[l], r[r2] = r[r2], r[l]
+		}
+	}
+}
+
+// Fumen's piece numbers, indexed by piece number, as kinds.
+var kinds = [...]string{1: "I", 2: "L", 3: "O", 4: "Z", 5: "T", 6: "J", 7: "S"}
+
+// The rotations fumen numbers 0 (reverse), 1 (right), 2 (spawn) and 3 (left)
+// as SRS rotation states, and the other way around.
+var rotationStates = [...]int{2, 1, 0, 3}
+
+// A piece as fumen places it: x grows to the right and y upwards from the
+// floor, with (x, y) the centre of rotation under SRS true rotation.
+type piece struct {
+	kind     int // fumen's piece number
+	rotation int // fumen's rotation number
+	x        int
+	y        int
+}
+
+// The (x, y) of each block of each piece at spawn, relative to its centre.
+// The other rotations are clockwise turns of these.
+var spawnCells = [...][4][2]int{
+	1: {{0, 0}, {-1, 0}, {1, 0}, {2, 0}},
+	2: {{0, 0}, {-1, 0}, {1, 0}, {1, 1}},
+	3: {{0, 0}, {1, 0}, {0, 1}, {1, 1}},
+	4: {{0, 0}, {1, 0}, {0, 1}, {-1, 1}},
+	5: {{0, 0}, {-1, 0}, {1, 0}, {0, 1}},
+	6: {{0, 0}, {-1, 0}, {1, 0}, {-1, 1}},
+	7: {{0, 0}, {-1, 0}, {0, 1}, {1, 1}},
+}
+
+// Returns the (x, y) of the piece's blocks.
+func (p *piece) cells() [4][2]int {
+	cells := spawnCells[p.kind]
+	for i := 0; i < rotationStates[p.rotation]; i++ {
+		for j, c := range cells {
+			cells[j] = [2]int{c[1], -c[0]}
+		}
+	}
+	for j := range cells {
+		cells[j][0] += p.x
+		cells[j][1] += p.y
+	}
+	return cells
+}
+
+// Returns the block fumen stores the piece's position as.  Fumen predates
+// SRS true rotation and keeps the older centres of O, I, S and Z in some
+// rotations.
+func (p *piece) position() int {
+	x, y := p.x, p.y
+	switch {
+	case p.kind == 3 && p.rotation == 3:
+		x, y = x-1, y+1
+	case p.kind == 3 && p.rotation == 0:
+		x--
+	case p.kind == 3 && p.rotation == 2:
+		y++
+	case p.kind == 1 && p.rotation == 0:
+		x--
+	case p.kind == 1 && p.rotation == 3:
+		y++
+	case p.kind == 7 && p.rotation == 2:
+		y++
+	case p.kind == 7 && p.rotation == 1:
+		x++
+	case p.kind == 4 && p.rotation == 2:
+		y++
+	case p.kind == 4 && p.rotation == 3:
+		x--
+	}
+	return (Height-1-y)*Width + x
+}
+
+// Sets the piece's centre from the block fumen stores its position as.
+func (p *piece) setPosition(n int) {
+	p.x, p.y = n%Width, Height-1-n/Width
+	switch {
+	case p.kind == 3 && p.rotation == 3:
+		p.x, p.y = p.x+1, p.y-1
+	case p.kind == 3 && p.rotation == 0:
+		p.x++
+	case p.kind == 3 && p.rotation == 2:
+		p.y--
+	case p.kind == 1 && p.rotation == 0:
+		p.x++
+	case p.kind == 1 && p.rotation == 3:
+		p.y--
+	case p.kind == 7 && p.rotation == 2:
+		p.y--
+	case p.kind == 7 && p.rotation == 1:
+		p.x--
+	case p.kind == 4 && p.rotation == 2:
+		p.y--
+	case p.kind == 4 && p.rotation == 3:
+		p.x++
+	}
+}
+
+// Returns the (x, y) of the blocks of a tetromino with its pivot at (row, col)
+// on a board of the given height resting on the floor.
+func tetrominoCells(t *tetris.Tetromino, row, col, height int) [4][2]int {
+	var cells [4][2]int
+	n := 0
+	pr, pc := t.System().Pivot(t.Kind())
+	data := t.Data()
+	for r := 0; r < 4; r++ {
+		for c := 0; c < 4; c++ {
+			if data[r][c] && n < len(cells) {
+				cells[n] = [2]int{col - pc + c, height - 1 - (row - pr + r)}
+				n++
+			}
+		}
+	}
+	return cells
+}
+
+// Returns the piece covering the same blocks as a tetromino with its pivot at
+// (row, col) on a board of the given height resting on the floor.  The
+// tetromino's orientation is tried first as an SRS rotation state, so the
+// rotation is kept for pieces that look the same in several.  It is an
+// error for the piece to lie outside the field, as fumen could not store it.
+func pieceOf(t *tetris.Tetromino, row, col, height int) (*piece, error) {
+	kind := cellPiece(tetris.KindCell(t.Kind()))
+	if kind == gray {
+		return nil, fmt.Errorf("Unknown tetromino kind %s!", t.Kind())
+	}
+
+	blocks := tetrominoCells(t, row, col, height)
+	for i := range rotationStates {
+		state := (t.Orient() + i) % len(rotationStates)
+		p := &piece{kind: kind, rotation: rotationStates[state]}
+		first := p.cells()[0]
+		for _, b := range blocks {
+			p.x, p.y = b[0]-first[0], b[1]-first[1]
+			if sameCells(p.cells(), blocks) {
+				return p, p.checkInField()
+			}
+		}
+	}
+	return nil, fmt.Errorf("Tetromino %s in state %d has no fumen rotation!", t.Kind(), t.Orient())
+}
+
+// Returns an error unless the piece's blocks and the block its position is
+// stored as are all inside the field, above the garbage row.
+func (p *piece) checkInField() error {
+	for _, c := range p.cells() {
+		if c[0] < 0 || c[0] >= Width || c[1] < 0 || c[1] >= Height {
+			return fmt.Errorf("%s at (%d,%d) is outside the field!", kinds[p.kind], p.x, p.y)
+		}
+	}
+	if n := p.position(); n < 0 || n >= Width*Height {
+		return fmt.Errorf("%s at (%d,%d) has no position in the field!", kinds[p.kind], p.x, p.y)
+	}
+	return nil
+}
+
+// Returns the SRS tetromino covering the piece's blocks and the row and column
+// of its pivot on a board of the given height resting on the floor.
+func (p *piece) tetromino(height int) (*tetris.Tetromino, int, int, error) {
+	t, err := tetris.NewTetrominoFor(tetris.SRS, kinds[p.kind], rotationStates[p.rotation])
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	blocks := p.cells()
+	first := tetrominoCells(t, 0, 0, height)[0]
+	for _, b := range blocks {
+		row, col := first[1]-b[1], b[0]-first[0]
+		if sameCells(tetrominoCells(t, row, col, height), blocks) {
+			return t, row, col, nil
+		}
+	}
+	return nil, 0, 0, fmt.Errorf("%s has no SRS position!", kinds[p.kind])
+}
+
+// Returns true if both hold the same blocks in any order.
+func sameCells(a, b [4][2]int) bool {
+	for _, c := range a {
+		found := false
+		for _, d := range b {
+			found = found || c == d
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// The characters fumen writes numbers with, as little-endian base 64.
+const digits = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// Comment characters are stored in base 96 from the space character.
+const (
+	commentBase  = 96
+	commentFirst = ' '
+	maxComment   = 64*64 - 1
+)
+
+// Appends a number as the given number of digits.
+func appendNumber(out []byte, value, n int) []byte {
+	for i := 0; i < n; i++ {
+		out = append(out, digits[value%64])
+		value /= 64
+	}
+	return out
+}
+
+// Reads numbers from fumen data.
+type reader struct {
+	data string
+	pos  int
+}
+
+func (r *reader) empty() bool {
+	return r.pos >= len(r.data)
+}
+
+// Reads a number of the given number of digits.
+func (r *reader) read(n int) (int, error) {
+	if r.pos+n > len(r.data) {
+		return 0, fmt.Errorf("Fumen data ends early at character %d!", r.pos)
+	}
+
+	value, scale := 0, 1
+	for i := 0; i < n; i++ {
+		d := strings.IndexByte(digits, r.data[r.pos])
+		if d < 0 {
+			return 0, fmt.Errorf("Fumen data has invalid character %q at %d!", r.data[r.pos], r.pos)
+		}
+		value += d * scale
+		scale *= 64
+		r.pos++
+	}
+	return value, nil
+}
+
+// Escapes a comment like JavaScript's escape function, which fumen applies
+// before storing it.
+func escape(s string) string {
+	var out strings.Builder
+	for _, u := range utf16.Encode([]rune(s)) {
+		switch {
+		case u < 0x80 && (u >= 'a' && u <= 'z' || u >= 'A' && u <= 'Z' || u >= '0' && u <= '9' ||
+			strings.ContainsRune("@*_+-./", rune(u))):
+			out.WriteByte(byte(u))
+		case u < 0x100:
+			fmt.Fprintf(&out, "%%%02X", u)
+		default:
+			fmt.Fprintf(&out, "%%u%04X", u)
+		}
+	}
+	return out.String()
+}
+
+// Reverses escape.  Malformed escapes are kept as they are, as JavaScript's
+// unescape does.
+func unescape(s string) string {
+	var units []uint16
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' {
+			if i+6 <= len(s) && s[i+1] == 'u' {
+				if u, err := strconv.ParseUint(s[i+2:i+6], 16, 16); err == nil {
+					units = append(units, uint16(u))
+					i += 5
+					continue
+				}
+			}
+			if i+3 <= len(s) {
+				if u, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+					units = append(units, uint16(u))
+					i += 2
+					continue
+				}
+			}
+		}
+		units = append(units, uint16(s[i]))
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,124 @@
+package fumen
+
+import (
+	"testing"
+
+	"github.com/paulcoyle/tetris"
+)
+
+func TestEscape(t *testing.T) {
+	cases := map[string]string{
+		"abc-XYZ_09@*+./": "abc-XYZ_09@*+./",
+		"Hello, world":    "Hello%2C%20world",
+		"café":            "caf%E9",
+		"テト":              "%u30C6%u30C8",
+		"😀":               "%uD83D%uDE00",
+	}
+	for in, out := range cases {
+		if escaped := escape(in); escaped != out {
+			t.Errorf("%q should escape to %q, got %q", in, out, escaped)
+		}
+		if unescaped := unescape(out); unescaped != in {
+			t.Errorf("%q should unescape to %q, got %q", out, in, unescaped)
+		}
+	}
+
+	if s := unescape("100%"); s != "100%" {
+		t.Errorf("A malformed escape should be kept, got %q", s)
+	}
+}
+
+func TestPiecePositionRoundTrip(t *testing.T) {
+	for kind := 1; kind <= 7; kind++ {
+		for rotation := 0; rotation < 4; rotation++ {
+			p := &piece{kind: kind, rotation: rotation, x: 4, y: 5}
+			q := &piece{kind: kind, rotation: rotation}
+			q.setPosition(p.position())
+			if *p != *q {
+				t.Errorf("%s in rotation %d should survive storing, got %+v", kinds[kind], rotation, q)
+			}
+		}
+	}
+}
+
+func TestPieceOfCorrectedPositions(t *testing.T) {
+	// Fumen stores I, O, S and Z in some rotations by an older centre.
+	p := &piece{kind: 3, rotation: 2}
+	p.setPosition((Height-1)*Width + 4)
+	if p.x != 4 || p.y != -1 {
+		t.Errorf("A spawn O stored on the floor should be centred a row lower, got (%d,%d)", p.x, p.y)
+	}
+
+	p = &piece{kind: 1, rotation: 0}
+	p.setPosition((Height-1)*Width + 4)
+	if p.x != 5 || p.y != 0 {
+		t.Errorf("A reversed I should be centred a column right, got (%d,%d)", p.x, p.y)
+	}
+}
+
+func TestTetrominoConversion(t *testing.T) {
+	for _, kind := range tetris.Kinds {
+		for state := 0; state < 4; state++ {
+			tet, _ := tetris.NewTetrominoFor(tetris.SRS, kind, state)
+			p, err := pieceOf(tet, 10, 4, Height)
+			if err != nil {
+				t.Errorf("%s in state %d should be a piece: %s", kind, state, err)
+				continue
+			}
+			if rotationStates[p.rotation] != state {
+				t.Errorf("%s in state %d should keep its rotation, got %d", kind, state, p.rotation)
+			}
+
+			back, row, col, err := p.tetromino(Height)
+			if err != nil {
+				t.Errorf("%s in state %d should convert back: %s", kind, state, err)
+				continue
+			}
+			if back.Orient() != state || row != 10 || col != 4 {
+				t.Errorf("%s in state %d at (10,4) came back in state %d at (%d,%d)", kind, state, back.Orient(), row, col)
+			}
+		}
+	}
+}
+
+func TestTetrominoOfOtherSystem(t *testing.T) {
+	// An ARS T points down at spawn, like an SRS T reversed.
+	tet, _ := tetris.NewTetrominoFor(tetris.ARS, "T", 0)
+	p, err := pieceOf(tet, 10, 4, Height)
+	if err != nil {
+		t.Fatalf("An ARS T should be a piece: %s", err)
+	}
+	if p.rotation != 0 {
+		t.Errorf("An ARS T at spawn should be reversed, got rotation %d", p.rotation)
+	}
+	if !sameCells(p.cells(), tetrominoCells(tet, 10, 4, Height)) {
+		t.Error("The piece should cover the tetromino's blocks")
+	}
+}
+
+func TestFieldClearRiseMirror(t *testing.T) {
+	f := &field{}
+	for col := 0; col < Width; col++ {
+		f[(Height-1)*Width+col] = gray
+	}
+	f[(Height-2)*Width] = 5
+	f[Height*Width+3] = gray
+
+	f.clearLines()
+	if f[(Height-1)*Width] != 5 || f[(Height-1)*Width+1] != empty {
+		t.Error("The full bottom row should clear, dropping the T block")
+	}
+	if f[Height*Width+3] != gray {
+		t.Error("Clearing should leave the garbage row alone")
+	}
+
+	f.rise()
+	if f[(Height-1)*Width+3] != gray || f[(Height-2)*Width] != 5 || f[Height*Width+3] != empty {
+		t.Error("The garbage row should rise into the field")
+	}
+
+	f.mirror()
+	if f[(Height-2)*Width+Width-1] != 5 || f[(Height-1)*Width+Width-4] != gray {
+		t.Error("Mirroring should flip the field left to right")
+	}
+}
@@ -0,0 +1,370 @@
+// Package fumen reads and writes fumen diagrams, the text format most Tetris
+// players share positions in.  Only version 115 is supported.  Quiz comments
+// are kept as plain comments.
+package fumen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/paulcoyle/tetris"
+)
+
+// The size of a fumen field.  Below it is a garbage row that can rise into it.
+const (
+	Width  = 10
+	Height = 23
+)
+
+// The active piece of a page.
+type Piece struct {
+	// An SRS tetromino, as fumen uses SRS shapes.  Any rotation system's
+	// tetromino may be encoded; it is stored by the blocks it covers.
+	Tetromino *tetris.Tetromino
+
+	// The board position of the tetromino's pivot.  When encoding, rows
+	// count on the page's board, or the last board given if it has none.
+	Row int
+	Col int
+}
+
+// A page of a diagram.
+type Page struct {
+	// The field shown on the page, before its piece locks.  Pages decoded
+	// have Width by Height boards, each their own.  For encoding a board
+	// may be shorter and rests on the floor, and a nil board continues from
+	// the previous page.
+	Board   *tetris.Board
+	Garbage [Width]tetris.Cell // the row below the floor
+
+	Piece   *Piece // nil when there is none
+	Comment string
+
+	// Whether the piece locks into the field carried to the next page,
+	// clearing full lines.
+	Lock bool
+
+	// Whether the garbage row rises into the field after the piece locks.
+	Rise bool
+
+	// Whether the field is flipped left to right after the piece locks.
+	Mirror bool
+
+	// Whether the diagram shows guideline colours.  Only the first page's
+	// matters.
+	Colorize bool
+}
+
+// Returns a page with the given board and the flags fumen sets by default.
+func NewPage(b *tetris.Board) *Page {
+	return &Page{Board: b, Lock: true, Colorize: true}
+}
+
+var prefix = regexp.MustCompile(`[vmd]115@`)
+
+// Reads the pages of a diagram.  The diagram may be a bare v115 string or a
+// URL containing one.
+func Decode(s string) ([]*Page, error) {
+	loc := prefix.FindStringIndex(s)
+	if loc == nil {
+		return nil, fmt.Errorf("No fumen v115 data found!")
+	}
+	data := s[loc[1]:]
+	if end := strings.IndexByte(data, '&'); end >= 0 {
+		data = data[:end]
+	}
+	data = strings.Map(func(r rune) rune {
+		if r == '?' || r == ' ' || r == '\n' || r == '\t' || r == '\r' {
+			return -1
+		}
+		return r
+	}, data)
+
+	r := &reader{data: data}
+	var pages []*Page
+	prev := &field{}
+	repeat := 0
+	comment := ""
+	for !r.empty() {
+		current, err := readField(r, prev, &repeat)
+		if err != nil {
+			return nil, err
+		}
+
+		action, err := r.read(3)
+		if err != nil {
+			return nil, err
+		}
+		p, flags := decodeAction(action)
+
+		if flags&flagComment != 0 {
+			if comment, err = readComment(r); err != nil {
+				return nil, err
+			}
+		}
+
+		page := &Page{Comment: comment}
+		page.Board, page.Garbage = current.board()
+		page.Rise, page.Mirror = flags&flagRise != 0, flags&flagMirror != 0
+		page.Colorize, page.Lock = flags&flagColorize != 0, flags&flagNoLock == 0
+		if p != nil {
+			t, row, col, err := p.tetromino(Height)
+			if err != nil {
+				return nil, err
+			}
+			page.Piece = &Piece{t, row, col}
+		}
+		pages = append(pages, page)
+
+		if err := current.after(p, page); err != nil {
+			return nil, fmt.Errorf("Page %d: %s", len(pages), err)
+		}
+		prev = current
+	}
+	return pages, nil
+}
+
+// Reads the field of a page, or repeats the previous one.  Fumen writes an
+// unchanged field followed by the number of pages after it that also repeat
+// it.
+func readField(r *reader, prev *field, repeat *int) (*field, error) {
+	current := *prev
+	if *repeat > 0 {
+		*repeat--
+		return &current, nil
+	}
+
+	changed := false
+	for i := 0; i < numBlocks; {
+		value, err := r.read(2)
+		if err != nil {
+			return nil, err
+		}
+		diff, count := value/numBlocks, value%numBlocks+1
+		if i+count > numBlocks {
+			return nil, fmt.Errorf("Fumen field runs past its end!")
+		}
+		changed = changed || diff != 8
+		for ; count > 0; count-- {
+			current[i] += diff - 8
+			if current[i] < empty || current[i] > gray {
+				return nil, fmt.Errorf("Fumen field has invalid block %d!", current[i])
+			}
+			i++
+		}
+	}
+
+	if !changed {
+		value, err := r.read(1)
+		if err != nil {
+			return nil, err
+		}
+		*repeat = value
+	}
+	return &current, nil
+}
+
+// Reads a comment's length and characters.
+func readComment(r *reader) (string, error) {
+	length, err := r.read(2)
+	if err != nil {
+		return "", err
+	}
+
+	var escaped strings.Builder
+	for i := 0; i < length; i += 4 {
+		value, err := r.read(5)
+		if err != nil {
+			return "", err
+		}
+		for j := 0; j < 4 && i+j < length; j++ {
+			escaped.WriteByte(byte(value%commentBase + commentFirst))
+			value /= commentBase
+		}
+	}
+	return unescape(escaped.String()), nil
+}
+
+// The flags of an action, from the lowest bit.
+const (
+	flagRise = 1 << iota
+	flagMirror
+	flagColorize
+	flagComment
+	flagNoLock
+)
+
+// Splits an action into its piece, nil for none, and flags.
+func decodeAction(value int) (*piece, int) {
+	p := &piece{kind: value % 8, rotation: value / 8 % 4}
+	p.setPosition(value / 32 % numBlocks)
+	flags := value / 32 / numBlocks
+	if p.kind == empty {
+		return nil, flags
+	}
+	return p, flags
+}
+
+// Joins a piece, nil for none, and flags into an action.
+func encodeAction(p *piece, flags int) int {
+	if p == nil {
+		// Fumen places no piece at the top left in reverse rotation.
+		return flags * numBlocks * 32
+	}
+	return (flags*numBlocks+p.position())*32 + p.rotation*8 + p.kind
+}
+
+// Applies what happens after a page is shown: locking its piece, clearing
+// lines, raising the garbage row and mirroring.
+func (f *field) after(p *piece, page *Page) error {
+	if !page.Lock {
+		return nil
+	}
+	if p != nil {
+		if err := f.fill(p); err != nil {
+			return err
+		}
+	}
+	f.clearLines()
+	if page.Rise {
+		f.rise()
+	}
+	if page.Mirror {
+		f.mirror()
+	}
+	return nil
+}
+
+// Writes the pages as a v115 diagram.
+func Encode(pages []*Page) (string, error) {
+	var out []byte
+	prev := &field{}
+	repeatAt := -1 // where the count of repeated fields is, -1 when not repeating
+	comment := ""
+	height := Height // of the last board given, which rests on the field's floor
+	for i, page := range pages {
+		current := prev
+		if page.Board != nil {
+			height = page.Board.Height()
+			var err error
+			if current, err = newField(page.Board, &page.Garbage); err != nil {
+				return "", fmt.Errorf("Page %d: %s", i+1, err)
+			}
+		}
+
+		if fieldData, changed := appendField(nil, prev, current); changed {
+			out = append(out, fieldData...)
+			repeatAt = -1
+		} else if repeatAt >= 0 && out[repeatAt] != digits[len(digits)-1] {
+			// Count this page in the last repeat instead of writing the field.
+			out[repeatAt] = digits[strings.IndexByte(digits, out[repeatAt])+1]
+		} else {
+			out = append(out, fieldData...)
+			repeatAt = len(out)
+			out = appendNumber(out, 0, 1)
+		}
+
+		var p *piece
+		if page.Piece != nil {
+			var err error
+			if p, err = pieceOf(page.Piece.Tetromino, page.Piece.Row, page.Piece.Col, height); err != nil {
+				return "", fmt.Errorf("Page %d: %s", i+1, err)
+			}
+		}
+		out = appendNumber(out, encodeAction(p, pageFlags(page, comment)), 3)
+
+		if page.Comment != comment {
+			var err error
+			if out, err = appendComment(out, page.Comment); err != nil {
+				return "", fmt.Errorf("Page %d: %s", i+1, err)
+			}
+			comment = page.Comment
+		}
+
+		next := *current
+		if err := next.after(p, page); err != nil {
+			return "", fmt.Errorf("Page %d: %s", i+1, err)
+		}
+		prev = &next
+	}
+
+	return "v115@" + split(string(out)), nil
+}
+
+// Returns the flags of a page's action, given the comment of the page before.
+func pageFlags(page *Page, comment string) int {
+	flags := 0
+	if page.Rise {
+		flags |= flagRise
+	}
+	if page.Mirror {
+		flags |= flagMirror
+	}
+	if page.Colorize {
+		flags |= flagColorize
+	}
+	if page.Comment != comment {
+		flags |= flagComment
+	}
+	if !page.Lock {
+		flags |= flagNoLock
+	}
+	return flags
+}
+
+// Appends a field as runs of the same change from the previous one.  Returns
+// false if nothing changed.
+func appendField(out []byte, prev, current *field) ([]byte, bool) {
+	changed := false
+	run, count := 0, 0
+	for i := range current {
+		diff := current[i] - prev[i] + 8
+		if i > 0 && diff != run {
+			out = appendNumber(out, run*numBlocks+count-1, 2)
+			count = 0
+		}
+		run = diff
+		count++
+		changed = changed || diff != 8
+	}
+	return appendNumber(out, run*numBlocks+count-1, 2), changed
+}
+
+// Appends a comment's length and characters.
+func appendComment(out []byte, comment string) ([]byte, error) {
+	escaped := escape(comment)
+	if len(escaped) > maxComment {
+		return out, fmt.Errorf("Comment is %d characters escaped, fumen allows %d!", len(escaped), maxComment)
+	}
+
+	out = appendNumber(out, len(escaped), 2)
+	for i := 0; i < len(escaped); i += 4 {
+		value, scale := 0, 1
+		for j := 0; j < 4 && i+j < len(escaped); j++ {
+			value += int(escaped[i+j]-commentFirst) * scale
+			scale *= commentBase
+		}
+		out = appendNumber(out, value, 5)
+	}
+	return out, nil
+}
+
+// Breaks the data up with a question mark after the first 42 characters and
+// every 47 after that, as fumen does.
+func split(data string) string {
+	if len(data) <= 42 {
+		return data
+	}
+
+	parts := []string{data[:42]}
+	for rest := data[42:]; rest != ""; {
+		n := 47
+		if len(rest) < n {
+			n = len(rest)
+		}
+		parts = append(parts, rest[:n])
+		rest = rest[n:]
+	}
+	return strings.Join(parts, "?")
+}
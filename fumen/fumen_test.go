@@ -0,0 +1,254 @@
+package fumen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/paulcoyle/tetris"
+)
+
+func decode(t *testing.T, s string) []*Page {
+	pages, err := Decode(s)
+	if err != nil {
+		t.Fatalf("%s should decode: %s", s, err)
+	}
+	return pages
+}
+
+func encode(t *testing.T, pages []*Page) string {
+	s, err := Encode(pages)
+	if err != nil {
+		t.Fatalf("Pages should encode: %s", err)
+	}
+	return s
+}
+
+func emptyBoard() *tetris.Board {
+	b, _ := tetris.NewBoard(Width, Height)
+	return b
+}
+
+func TestDecodeEmpty(t *testing.T) {
+	for _, s := range []string{"v115@vhAAgH", "http://fumen.zui.jp/?v115@vhAAgH", "https://harddrop.com/fumen/?v115@vhAAgH&dummy=1"} {
+		pages := decode(t, s)
+		if len(pages) != 1 {
+			t.Fatalf("%s should have one page, has %d", s, len(pages))
+		}
+		p := pages[0]
+		if !p.Board.Equal(emptyBoard()) || p.Piece != nil || p.Comment != "" {
+			t.Errorf("%s should be an empty page", s)
+		}
+		if !p.Lock || !p.Colorize || p.Rise || p.Mirror {
+			t.Errorf("%s should have the default flags, got %+v", s, p)
+		}
+	}
+
+	if s := encode(t, []*Page{NewPage(emptyBoard())}); s != "v115@vhAAgH" {
+		t.Errorf("An empty page should encode to v115@vhAAgH, got %s", s)
+	}
+}
+
+func TestDecodeField(t *testing.T) {
+	s := "v115@9gF8DeF8DeF8DeF8NeAgH"
+	pages := decode(t, s)
+	b := pages[0].Board
+	expected, _ := tetris.StringArrayToBoard([]string{
+		"|######    |",
+		"|######    |",
+		"|######    |",
+		"|######    |",
+	})
+	for row := 0; row < 4; row++ {
+		for col := 0; col < Width; col++ {
+			want, _ := expected.Block(row, col)
+			if got, _ := b.Block(Height-4+row, col); got != want {
+				t.Errorf("Block (%d,%d) should be %v", Height-4+row, col, want)
+			}
+		}
+	}
+	if cell, _ := b.Cell(Height-1, 0); cell != tetris.CellGarbage {
+		t.Errorf("The blocks should be gray, got %s", cell)
+	}
+
+	// Encoding a board shorter than the field rests it on the floor.
+	if out := encode(t, []*Page{NewPage(expected)}); out != s {
+		t.Errorf("The board should encode to %s, got %s", s, out)
+	}
+}
+
+func TestDecodePieceAndLock(t *testing.T) {
+	pages := decode(t, "v115@vhBVQJAgH")
+	if len(pages) != 2 {
+		t.Fatalf("There should be two pages, got %d", len(pages))
+	}
+
+	p := pages[0].Piece
+	if p == nil || p.Tetromino.Kind() != "T" || p.Tetromino.Orient() != 0 || p.Row != Height-1 || p.Col != 4 {
+		t.Fatalf("The first page should show a spawn T on the floor, got %+v", p)
+	}
+
+	b := pages[1].Board
+	for _, c := range [][2]int{{Height - 1, 3}, {Height - 1, 4}, {Height - 1, 5}, {Height - 2, 4}} {
+		if cell, _ := b.Cell(c[0], c[1]); cell != tetris.CellT {
+			t.Errorf("The T should lock at (%d,%d), got %s", c[0], c[1], cell)
+		}
+	}
+	if pages[1].Piece != nil {
+		t.Error("The second page should have no piece")
+	}
+
+	out := encode(t, []*Page{
+		{Board: emptyBoard(), Piece: p, Lock: true, Colorize: true},
+		{Lock: true, Colorize: true},
+	})
+	if out != "v115@vhBVQJAgH" {
+		t.Errorf("The pages should encode back, got %s", out)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	var pages []*Page
+	random := tetris.NewBagRandomizer(5)
+	b := emptyBoard()
+	b.SetCell(Height-1, 0, tetris.CellJ)
+	b.SetCell(Height-1, 9, tetris.CellUser)
+	for i := 0; i < 16; i++ {
+		kind := random.Next()
+		orient, row, col := tetris.SRS.Spawn(kind, Width)
+		placements := tetris.FindPlacements(tetris.SRS, b, kind, orient, row+3, col)
+		pl := placements[i%len(placements)]
+		tet, _ := tetris.NewTetrominoFor(tetris.SRS, kind, pl.Orient)
+
+		page := &Page{Piece: &Piece{tet, pl.Row, pl.Col}, Lock: i%5 != 4, Colorize: true}
+		if i == 0 {
+			page.Board = b
+			page.Garbage[2] = tetris.CellGarbage
+		}
+		if i%7 == 3 {
+			page.Comment = strings.Repeat("テトリス, page ", i)
+		} else if i > 0 {
+			page.Comment = pages[i-1].Comment
+		}
+		// The last page's flags change the field after it, so the boards
+		// found for earlier pages stay right.
+		page.Rise, page.Mirror = i == 15, i == 15
+		pages = append(pages, page)
+
+		if page.Lock {
+			b = pl.Board
+		}
+	}
+
+	s := encode(t, pages)
+	if strings.Contains(s[:47], "?") || !strings.Contains(s, "?") {
+		t.Errorf("Long diagrams should be broken up by question marks after 42 characters: %s", s)
+	}
+
+	decoded := decode(t, s)
+	if len(decoded) != len(pages) {
+		t.Fatalf("%d pages should decode, got %d", len(pages), len(decoded))
+	}
+	for i, page := range pages {
+		d := decoded[i]
+		if page.Board != nil && !d.Board.Equal(page.Board) {
+			t.Errorf("Page %d should keep its board", i+1)
+		}
+		if d.Comment != page.Comment || d.Lock != page.Lock || d.Rise != page.Rise || d.Mirror != page.Mirror {
+			t.Errorf("Page %d should keep its comment and flags", i+1)
+		}
+		if d.Piece.Tetromino.Kind() != page.Piece.Tetromino.Kind() ||
+			!sameCells(tetrominoCells(d.Piece.Tetromino, d.Piece.Row, d.Piece.Col, Height),
+				tetrominoCells(page.Piece.Tetromino, page.Piece.Row, page.Piece.Col, Height)) {
+			t.Errorf("Page %d should keep its piece", i+1)
+		}
+	}
+	if cell, _ := decoded[0].Board.Cell(Height-1, 9); cell != tetris.CellGarbage {
+		t.Errorf("User cells should be stored as gray, got %s", cell)
+	}
+	if decoded[0].Garbage[2] != tetris.CellGarbage {
+		t.Error("The garbage row should be kept")
+	}
+
+	if again := encode(t, decoded); again != s {
+		t.Error("Decoded pages should encode to the same diagram")
+	}
+}
+
+func TestRoundTripShortBoard(t *testing.T) {
+	short, _ := tetris.NewBoard(Width, 20)
+	tet, _ := tetris.NewTetrominoFor(tetris.SRS, "T", 0)
+	pages := []*Page{
+		{Board: short, Piece: &Piece{tet, 19, 4}, Lock: true, Colorize: true},
+		{Lock: true, Colorize: true},
+		{Piece: &Piece{tet, 17, 4}, Lock: true, Colorize: true},
+	}
+
+	decoded := decode(t, encode(t, pages))
+	for i, row := range []int{19, 17} {
+		p := decoded[i*2].Piece
+		if !sameCells(tetrominoCells(p.Tetromino, p.Row, p.Col, Height), tetrominoCells(tet, row, 4, 20)) {
+			t.Errorf("Page %d should keep its piece where it is on the short board, it is at row %d", i*2+1, p.Row)
+		}
+	}
+	for _, cell := range [][2]int{{Height - 2, 4}, {Height - 1, 3}, {Height - 1, 4}, {Height - 1, 5}} {
+		if set, _ := decoded[1].Board.Block(cell[0], cell[1]); !set {
+			t.Errorf("The locked T should rest on the floor, (%d,%d) is empty", cell[0], cell[1])
+		}
+	}
+}
+
+func TestRepeatedFields(t *testing.T) {
+	pages := make([]*Page, 70)
+	for i := range pages {
+		pages[i] = &Page{Lock: true, Colorize: true}
+	}
+	s := encode(t, pages)
+	if decoded := decode(t, s); len(decoded) != 70 {
+		t.Errorf("70 repeated pages should decode, got %d", len(decoded))
+	}
+	// 64 pages share the first field and its repeat count, the rest the next.
+	if n := strings.Count(s, "vh"); n != 2 {
+		t.Errorf("The field should be written twice, was %d times in %s", n, s)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, s := range []string{"hello", "v110@7eAA4G", "v115@vh", "v115@v!AAgH", "v115@vhAAgH$"} {
+		if _, err := Decode(s); err == nil {
+			t.Errorf("%s should not decode", s)
+		}
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	wide, _ := tetris.NewBoard(12, 20)
+	if _, err := Encode([]*Page{NewPage(wide)}); err == nil {
+		t.Error("A board of the wrong width should be an error")
+	}
+
+	page := NewPage(emptyBoard())
+	page.Comment = strings.Repeat("%", 2000)
+	if _, err := Encode([]*Page{page}); err == nil {
+		t.Error("A comment too long to store should be an error")
+	}
+
+	tet, _ := tetris.NewTetrominoFor(tetris.SRS, "I", 1)
+	page = NewPage(emptyBoard())
+	page.Piece = &Piece{tet, 0, 4}
+	if _, err := Encode([]*Page{page}); err == nil {
+		t.Error("Locking a piece above the field should be an error")
+	}
+
+	// Without colours or locking nothing else would catch the position.
+	for _, lock := range []bool{true, false} {
+		page = &Page{Board: emptyBoard(), Piece: &Piece{tet, 0, 4}, Lock: lock}
+		if _, err := Encode([]*Page{page}); err == nil {
+			t.Errorf("A colourless piece above the field should be an error when locking is %v", lock)
+		}
+	}
+	low, _ := tetris.NewBoard(Width, 4)
+	page = &Page{Board: low, Piece: &Piece{tet, 5, 4}}
+	if _, err := Encode([]*Page{page}); err == nil {
+		t.Error("A piece below the floor should be an error")
+	}
+}
@@ -0,0 +1,450 @@
+package tetris
+
+import (
+	"fmt"
+)
+
+// The rules a Game is played with.
+type GameConfig struct {
+	Width  int
+	Height int
+
+	// An optional board to start from instead of an empty one.  When set, its
+	// dimensions override Width and Height.
+	Board *Board
+
+	Rotation   RotationSystem
+	Randomizer Randomizer
+
+	// How many upcoming kinds are kept in the next queue.
+	Previews int
+
+	// Frames taken to fall one row.  Zero or less drops pieces straight to
+	// the floor (20G).
+	Gravity int
+
+	// Frames a piece may rest on the stack before it locks.
+	LockDelay int
+
+	// How many times moving or rotating a resting piece may restart its lock
+	// delay.  The count starts over whenever the piece falls below the lowest
+	// row it has reached.  Less than zero allows unlimited resets.
+	MoveResets int
+
+	// Frames between a piece locking and the next one spawning (ARE).
+	EntryDelay int
+
+	// Enables the hold slot.
+	Hold bool
+
+	// Allows holding more than once before a piece locks.
+	UnlimitedHold bool
+
+	// Allows a hold sent during the entry delay to swap the next piece as
+	// it spawns (IHS).
+	InitialHold bool
+
+	// Recognises spins by any kind of tetromino, not just T.
+	AllSpin bool
+
+	// The points awarded for clears and drops, and the level to start on.
+	// The zero table awards nothing but still counts lines.
+	Scoring ScoreTable
+	Level   int
+}
+
+// Returns a configuration close to the modern guideline: a 10x20 board, SRS,
+// a 7-bag randomizer, five previews, hold, one row per second at 60 frames per
+// second, half a second of lock delay, 15 move resets and guideline scoring
+// from level 1.
+func DefaultGameConfig(seed int64) GameConfig {
+	return GameConfig{
+		Width:      10,
+		Height:     20,
+		Rotation:   SRS,
+		Randomizer: NewBagRandomizer(seed),
+		Previews:   5,
+		Gravity:    60,
+		LockDelay:  30,
+		MoveResets: 15,
+		Hold:       true,
+		Scoring:    GuidelineScoring,
+		Level:      1,
+	}
+}
+
+// Describes a tetromino locking onto the board.
+type LockEvent struct {
+	Kind   string
+	Orient int
+	Row    int
+	Col    int
+	ClearEvent
+	Points int // points scored for the clear
+	Frame  int
+}
+
+// A running game of tetris advanced one frame at a time by Tick.  Given the
+// same configuration and the same calls, a game always plays out the same.
+type Game struct {
+	config GameConfig
+	board  *Board
+	rs     RotationSystem
+	random Randomizer
+	queue  []string
+	scorer *Scorer
+
+	active *Tetromino // nil between pieces and once the game is over
+	row    int
+	col    int
+
+	lastAction Action // last successful action on the active piece
+	lastKick   int    // kick used by the last rotation
+
+	held     *Tetromino // in its spawn orientation, nil if nothing is held
+	holdUsed bool       // whether the active piece came out of a hold
+	ihs      bool       // whether a hold is waiting for the next spawn
+
+	frame      int
+	fallTimer  int // frames since the active piece last fell
+	lockTimer  int // frames the active piece has been resting
+	resets     int // lock delay resets used since reaching lowest
+	lowest     int // lowest row the active piece has reached
+	entryTimer int // frames left before the next piece spawns
+	over       bool
+}
+
+func NewGame(config GameConfig) (*Game, error) {
+	if config.Rotation == nil {
+		return nil, fmt.Errorf("A rotation system is required")
+	}
+	if config.Randomizer == nil {
+		return nil, fmt.Errorf("A randomizer is required")
+	}
+	if config.Previews < 0 {
+		return nil, fmt.Errorf("Previews %d is negative", config.Previews)
+	}
+	if config.LockDelay < 0 {
+		return nil, fmt.Errorf("Lock delay %d is negative", config.LockDelay)
+	}
+	if config.EntryDelay < 0 {
+		return nil, fmt.Errorf("Entry delay %d is negative", config.EntryDelay)
+	}
+
+	var board *Board
+	if config.Board != nil {
+		board = config.Board.Copy()
+		config.Width, config.Height = board.Width(), board.Height()
+	} else {
+		var err error
+		board, err = NewBoard(config.Width, config.Height)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	g := &Game{
+		config: config,
+		board:  board,
+		rs:     config.Rotation,
+		random: config.Randomizer.Clone(),
+		scorer: NewScorer(config.Scoring, config.Level),
+	}
+	g.fillQueue()
+	g.spawn()
+
+	return g, nil
+}
+
+func (g *Game) Config() GameConfig {
+	return g.config
+}
+
+// Returns the game's board.  It must not be modified.
+func (g *Game) Board() *Board {
+	return g.board
+}
+
+// Returns the active tetromino and the row and column of its pivot.  The
+// tetromino is nil while waiting for the next piece to spawn and once the game
+// is over.
+func (g *Game) Active() (*Tetromino, int, int) {
+	return g.active, g.row, g.col
+}
+
+// Returns the kinds of the upcoming tetrominoes, next first.
+func (g *Game) Queue() []string {
+	return append([]string(nil), g.queue[:g.config.Previews]...)
+}
+
+// Returns a clone of the randomizer the queue is filled from.  It continues
+// the sequence from the piece after the last preview.
+func (g *Game) Randomizer() Randomizer {
+	return g.random.Clone()
+}
+
+// Returns the number of frames played so far.
+func (g *Game) Frame() int {
+	return g.frame
+}
+
+func (g *Game) Over() bool {
+	return g.over
+}
+
+// Returns the scorer keeping the game's score, level and line count.
+func (g *Game) Scorer() *Scorer {
+	return g.scorer
+}
+
+// Returns the held tetromino, in its spawn orientation, or nil if nothing is
+// held.
+func (g *Game) Held() *Tetromino {
+	return g.held
+}
+
+// Returns true if a hold is allowed right now.
+func (g *Game) HoldAvailable() bool {
+	return g.config.Hold && !g.over && (!g.holdUsed || g.config.UnlimitedHold)
+}
+
+// Advances the game by one frame: counting down to the next spawn, applying
+// gravity and locking a piece that has rested for the lock delay.  When a
+// piece locks the event is returned, otherwise nil.
+func (g *Game) Tick() *LockEvent {
+	if g.over {
+		return nil
+	}
+	g.frame++
+
+	if g.active == nil {
+		g.entryTimer--
+		if g.entryTimer <= 0 {
+			g.spawn()
+		}
+		return nil
+	}
+
+	if g.config.Gravity <= 0 {
+		if dist := g.dropDistance(); dist > 0 {
+			g.fall(dist)
+			g.lastAction = SoftDrop
+		}
+	} else {
+		g.fallTimer++
+		if g.fallTimer >= g.config.Gravity {
+			g.fallTimer = 0
+			if g.canMove(1, 0) {
+				g.fall(1)
+				g.lastAction = SoftDrop
+			}
+		}
+	}
+
+	if !g.canMove(1, 0) {
+		g.lockTimer++
+		if g.lockTimer >= g.config.LockDelay {
+			return g.lock()
+		}
+	}
+
+	return nil
+}
+
+// Returns an independent copy of the game that plays on exactly as the game
+// would.
+func (g *Game) Clone() *Game {
+	c := *g
+	c.board = g.board.Copy()
+	c.random = g.random.Clone()
+	c.queue = append([]string(nil), g.queue...)
+	scorer := *g.scorer
+	c.scorer = &scorer
+	if g.active != nil {
+		c.active = g.active.Copy()
+	}
+	if g.held != nil {
+		c.held = g.held.Copy()
+	}
+	return &c
+}
+
+// Returns a copy of the game's state.
+func (g *Game) Snapshot() *GameSnapshot {
+	s := &GameSnapshot{
+		Board:         g.board.Copy(),
+		Row:           g.row,
+		Col:           g.col,
+		Queue:         g.Queue(),
+		HoldAvailable: g.HoldAvailable(),
+		Score:         g.scorer.Score(),
+		Level:         g.scorer.Level(),
+		Lines:         g.scorer.Lines(),
+		Frame:         g.frame,
+		Over:          g.over,
+	}
+	if g.active != nil {
+		s.Active = g.active.Copy()
+	}
+	if g.held != nil {
+		s.Held = g.held.Copy()
+	}
+	return s
+}
+
+// A copy of a game's state at some frame.
+type GameSnapshot struct {
+	Board         *Board
+	Active        *Tetromino // nil when no piece is in play
+	Row           int
+	Col           int
+	Queue         []string
+	Held          *Tetromino // nil when nothing is held
+	HoldAvailable bool
+	Score         int
+	Level         int
+	Lines         int
+	Frame         int
+	Over          bool
+}
+
+// Keeps enough kinds in the queue to show every preview.  No more are drawn
+// than are shown, so the randomizer always continues from the last preview.
+func (g *Game) fillQueue() {
+	for len(g.queue) < g.config.Previews {
+		g.queue = append(g.queue, g.random.Next())
+	}
+}
+
+// Takes the next kind off the queue.
+func (g *Game) next() string {
+	if len(g.queue) == 0 {
+		g.queue = append(g.queue, g.random.Next())
+	}
+	kind := g.queue[0]
+	g.queue = g.queue[1:]
+	g.fillQueue()
+	return kind
+}
+
+// Brings the next kind in the queue into play, or the held kind if an initial
+// hold is waiting.
+func (g *Game) spawn() {
+	kind := g.next()
+	if g.ihs {
+		g.ihs = false
+		g.holdUsed = true
+		kind = g.swapHold(kind)
+	}
+	g.spawnKind(kind)
+}
+
+// Puts the given kind in the hold slot and returns the kind to play instead:
+// the one previously held, or the next in the queue if the slot was empty.
+func (g *Game) swapHold(kind string) string {
+	held := g.held
+	state, _, _ := g.rs.Spawn(kind, g.board.Width())
+	g.held, _ = NewTetrominoFor(g.rs, kind, state)
+
+	if held == nil {
+		return g.next()
+	}
+	return held.Kind()
+}
+
+// Brings the given kind into play at its spawn position, ending the game if it
+// is blocked there.
+func (g *Game) spawnKind(kind string) {
+	state, row, col := g.rs.Spawn(kind, g.board.Width())
+	tet, err := NewTetrominoFor(g.rs, kind, state)
+	if err != nil {
+		g.over = true
+		return
+	}
+
+	g.active, g.row, g.col = tet, row, col
+	g.fallTimer, g.lockTimer, g.resets, g.lowest = 0, 0, 0, row
+	g.lastAction, g.lastKick = HardDrop, -1
+	if !fits(g.rs, g.board, tet, row, col) {
+		g.over = true
+		g.active = nil
+	}
+}
+
+// Returns true if the active piece could be shifted by the given rows and
+// columns.
+func (g *Game) canMove(rows, cols int) bool {
+	return fits(g.rs, g.board, g.active, g.row+rows, g.col+cols)
+}
+
+// Returns how many rows the active piece can fall before landing.
+func (g *Game) dropDistance() int {
+	dist := 0
+	for g.canMove(dist+1, 0) {
+		dist++
+	}
+	return dist
+}
+
+// Moves the active piece down, which restarts its lock delay and, on reaching
+// a new lowest row, its move resets.
+func (g *Game) fall(rows int) {
+	if rows <= 0 {
+		return
+	}
+	g.row += rows
+	g.lockTimer = 0
+	if g.row > g.lowest {
+		g.lowest = g.row
+		g.resets = 0
+	}
+}
+
+// Restarts the lock delay after the active piece was moved or rotated, as long
+// as it has move resets left.
+func (g *Game) resetLock() {
+	if g.config.MoveResets >= 0 && g.resets >= g.config.MoveResets {
+		return
+	}
+	g.resets++
+	g.lockTimer = 0
+}
+
+// Locks the active piece where it is, clears and scores lines and starts the
+// next piece.
+// The game is over if any of the piece locked above the top of the board.
+func (g *Game) lock() *LockEvent {
+	t := g.active
+	spin := DetectSpin(g.rs, g.board, t, g.row, g.col, g.lastAction, g.lastKick, g.config.AllSpin)
+	g.board, _ = Place(g.rs, g.board, t, g.row, g.col)
+
+	pr, _ := g.rs.Pivot(t.Kind())
+	for i := 0; i < 4; i++ {
+		if t.masks[i] != 0 && g.row-pr+i < 0 {
+			g.over = true
+		}
+	}
+
+	event := &LockEvent{
+		Kind:   t.Kind(),
+		Orient: t.Orient(),
+		Row:    g.row,
+		Col:    g.col,
+		Frame:  g.frame,
+	}
+	event.Spin = spin
+	event.Lines = ClearFullLines(g.board)
+	event.PerfectClear = event.Lines > 0 && g.board.isEmpty()
+	event.Points = g.scorer.Clear(event.ClearEvent)
+
+	g.active = nil
+	g.holdUsed = false
+	if !g.over {
+		g.entryTimer = g.config.EntryDelay
+		if g.entryTimer <= 0 {
+			g.spawn()
+		}
+	}
+
+	return event
+}
@@ -0,0 +1,355 @@
+package tetris
+
+import (
+	"testing"
+)
+
+func newTestGame(t *testing.T, config GameConfig) *Game {
+	g, err := NewGame(config)
+	if err != nil {
+		t.Fatalf("Game should be created: %s", err)
+	}
+	return g
+}
+
+func TestNewGameRequiresRules(t *testing.T) {
+	config := DefaultGameConfig(1)
+	config.Rotation = nil
+	if _, err := NewGame(config); err == nil {
+		t.Error("A game without a rotation system should be an error")
+	}
+
+	config = DefaultGameConfig(1)
+	config.Randomizer = nil
+	if _, err := NewGame(config); err == nil {
+		t.Error("A game without a randomizer should be an error")
+	}
+
+	config = DefaultGameConfig(1)
+	config.Width = 0
+	if _, err := NewGame(config); err == nil {
+		t.Error("A game with a bad board size should be an error")
+	}
+
+	for name, change := range map[string]func(*GameConfig){
+		"previews":    func(c *GameConfig) { c.Previews = -1 },
+		"lock delay":  func(c *GameConfig) { c.LockDelay = -1 },
+		"entry delay": func(c *GameConfig) { c.EntryDelay = -1 },
+	} {
+		config = DefaultGameConfig(1)
+		change(&config)
+		if _, err := NewGame(config); err == nil {
+			t.Errorf("A game with negative %s should be an error", name)
+		}
+	}
+}
+
+func TestNewGameSpawnsFirstPiece(t *testing.T) {
+	config := DefaultGameConfig(1)
+	first := config.Randomizer.Clone().Next()
+
+	g := newTestGame(t, config)
+	active, row, col := g.Active()
+	if active == nil {
+		t.Fatal("The first piece should be in play")
+	}
+	if active.Kind() != first {
+		t.Errorf("The first piece should be %s, was %s", first, active.Kind())
+	}
+
+	state, srow, scol := SRS.Spawn(first, 10)
+	if active.Orient() != state || row != srow || col != scol {
+		t.Error("The first piece should be at its spawn position")
+	}
+	if len(g.Queue()) != 5 {
+		t.Errorf("The queue should show 5 pieces, showed %d", len(g.Queue()))
+	}
+}
+
+func TestGameRandomizerFollowsQueue(t *testing.T) {
+	config := DefaultGameConfig(1)
+	expected := deal(config.Randomizer.Clone(), 7)
+
+	// The first piece and five previews come before it.
+
+	g := newTestGame(t, config)
+	if next := g.Randomizer().Next(); next != expected[6] {
+		t.Errorf("The game's randomizer should deal %s after the queue, dealt %s", expected[6], next)
+	}
+}
+
+func TestNewGameDoesNotAdvanceConfigRandomizer(t *testing.T) {
+	config := DefaultGameConfig(1)
+	a := newTestGame(t, config)
+	b := newTestGame(t, config)
+	if !sameKinds(a.Queue(), b.Queue()) {
+		t.Error("Games created from the same config should deal the same pieces")
+	}
+}
+
+func TestGravityFallsOneRowPerPeriod(t *testing.T) {
+	config := DefaultGameConfig(1)
+	config.Gravity = 3
+	g := newTestGame(t, config)
+	_, start, _ := g.Active()
+
+	for i := 0; i < 2; i++ {
+		g.Tick()
+	}
+	if _, row, _ := g.Active(); row != start {
+		t.Error("The piece should not fall before the gravity period")
+	}
+
+	g.Tick()
+	if _, row, _ := g.Active(); row != start+1 {
+		t.Errorf("The piece should have fallen one row, was at %d", row)
+	}
+}
+
+func TestInstantGravityDropsToFloor(t *testing.T) {
+	config := DefaultGameConfig(1)
+	config.Gravity = 0
+	config.LockDelay = 10
+	g := newTestGame(t, config)
+
+	g.Tick()
+	active, row, col := g.Active()
+	if fits(SRS, g.Board(), active, row+1, col) {
+		t.Error("20G should drop the piece onto the floor in one frame")
+	}
+}
+
+func TestPieceLocksAfterLockDelay(t *testing.T) {
+	config := DefaultGameConfig(1)
+	config.Gravity = 0
+	config.LockDelay = 5
+	g := newTestGame(t, config)
+	first, _, _ := g.Active()
+	next := g.Queue()[0]
+
+	for i := 0; i < 4; i++ {
+		if g.Tick() != nil {
+			t.Fatal("The piece should not lock before the lock delay")
+		}
+	}
+
+	event := g.Tick()
+	if event == nil {
+		t.Fatal("The piece should lock once the lock delay has passed")
+	}
+	if event.Kind != first.Kind() || event.Frame != 5 {
+		t.Errorf("Unexpected lock event %+v", event)
+	}
+
+	if active, _, _ := g.Active(); active == nil || active.Kind() != next {
+		t.Error("The next piece should spawn straight after the lock")
+	}
+	if empty, _ := NewBoard(10, 20); g.Board().Equal(empty) {
+		t.Error("The locked piece should be on the board")
+	}
+}
+
+func TestEntryDelay(t *testing.T) {
+	config := DefaultGameConfig(1)
+	config.Gravity = 0
+	config.LockDelay = 1
+	config.EntryDelay = 3
+	g := newTestGame(t, config)
+
+	if g.Tick() == nil {
+		t.Fatal("The piece should lock")
+	}
+	for i := 0; i < 2; i++ {
+		g.Tick()
+		if active, _, _ := g.Active(); active != nil {
+			t.Fatal("No piece should be in play during the entry delay")
+		}
+	}
+
+	g.Tick()
+	if active, _, _ := g.Active(); active == nil {
+		t.Error("The next piece should spawn after the entry delay")
+	}
+}
+
+func TestLockClearsLines(t *testing.T) {
+	board, _ := StringArrayToBoard([]string{
+		"|    |",
+		"|    |",
+		"|    |",
+		"|    |",
+		"|#  #|",
+	})
+	config := DefaultGameConfig(1)
+	config.Board = board
+	config.Randomizer = &fixedRandomizer{kinds: []string{"O"}}
+	config.Gravity = 0
+	config.LockDelay = 1
+	g := newTestGame(t, config)
+
+	event := g.Tick()
+	if event == nil || event.Lines != 1 {
+		t.Fatalf("The O should lock and clear one line, got %+v", event)
+	}
+
+	expected, _ := StringArrayToBoard([]string{
+		"|    |",
+		"|    |",
+		"|    |",
+		"|    |",
+		"| ## |",
+	})
+	if !g.Board().Equal(expected) {
+		t.Errorf("Board should have the line cleared\n%s", g.Board())
+	}
+}
+
+func TestGameOverWhenSpawnBlocked(t *testing.T) {
+	board, _ := NewBoard(10, 4)
+	board.SetRow(1, true)
+	board.SetBlock(1, 0, false)
+
+	config := DefaultGameConfig(1)
+	config.Board = board
+	g := newTestGame(t, config)
+	if !g.Over() {
+		t.Error("The game should be over when the first piece cannot spawn")
+	}
+	if g.Tick() != nil || g.Frame() != 0 {
+		t.Error("Ticking a finished game should do nothing")
+	}
+}
+
+func TestGameOverOnLockOut(t *testing.T) {
+	config := DefaultGameConfig(1)
+	config.Randomizer = &fixedRandomizer{kinds: []string{"I"}}
+	config.LockDelay = 1
+	g := newTestGame(t, config)
+
+	// Kicked up above a filled top row, as a rotation might.
+	g.board.SetRow(0, true)
+	g.board.SetBlock(0, 0, false)
+	g.row = -1
+
+	g.Tick()
+	if !g.Over() {
+		t.Error("Locking entirely above the board should end the game")
+	}
+}
+
+func TestMoveResetsAreLimited(t *testing.T) {
+	config := DefaultGameConfig(1)
+	config.Gravity = 0
+	config.LockDelay = 2
+	config.MoveResets = 2
+	g := newTestGame(t, config)
+
+	g.Tick()
+	for i := 0; i < 2; i++ {
+		g.resetLock()
+		if g.Tick() != nil {
+			t.Fatal("A reset should restart the lock delay")
+		}
+	}
+
+	g.resetLock()
+	if g.Tick() == nil {
+		t.Error("Resets past the limit should not restart the lock delay")
+	}
+}
+
+func TestGamesAreDeterministic(t *testing.T) {
+	config := DefaultGameConfig(77)
+	config.Gravity = 1
+	config.LockDelay = 3
+	a := newTestGame(t, config)
+	b := newTestGame(t, config)
+
+	for i := 0; i < 500; i++ {
+		ea, eb := a.Tick(), b.Tick()
+		if (ea == nil) != (eb == nil) || (ea != nil && *ea != *eb) {
+			t.Fatalf("Games diverged at frame %d", i)
+		}
+	}
+	if !a.Board().EqualCells(b.Board()) || a.Over() != b.Over() {
+		t.Error("Games with the same config should end up the same")
+	}
+}
+
+func TestSnapshotIsACopy(t *testing.T) {
+	g := newTestGame(t, DefaultGameConfig(1))
+	s := g.Snapshot()
+
+	s.Board.SetRow(19, true)
+	s.Active.RotateFwd()
+	s.Queue[0] = "X"
+
+	if g.Board().rowFull(19) {
+		t.Error("Changing the snapshot board should not change the game")
+	}
+	if active, _, _ := g.Active(); active.Orient() != 0 {
+		t.Error("Changing the snapshot piece should not change the game")
+	}
+	if g.Queue()[0] == "X" {
+		t.Error("Changing the snapshot queue should not change the game")
+	}
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	g := newTestGame(t, DefaultGameConfig(1))
+	g.MoveLeft()
+	for i := 0; i < 100; i++ {
+		g.Tick()
+	}
+	c := g.Clone()
+
+	play := func(g *Game) {
+		for i := 0; i < 20; i++ {
+			g.RotateCW()
+			g.HardDrop()
+			g.Hold()
+			g.Tick()
+		}
+	}
+	play(g)
+	if c.Frame() != 100 || c.Board().Equal(g.Board()) || c.Scorer().Score() == g.Scorer().Score() {
+		t.Fatal("Playing the game should not change the clone")
+	}
+
+	play(c)
+	if !c.Board().EqualCells(g.Board()) || c.Scorer().Score() != g.Scorer().Score() ||
+		!sameKinds(c.Queue(), g.Queue()) || c.Held().Kind() != g.Held().Kind() {
+		t.Error("The clone should play on exactly as the game did")
+	}
+}
+
+// Deals the given kinds over and over.
+type fixedRandomizer struct {
+	kinds []string
+	next  int
+}
+
+func (r *fixedRandomizer) Name() string {
+	return "fixed"
+}
+
+func (r *fixedRandomizer) Next() string {
+	kind := r.kinds[r.next%len(r.kinds)]
+	r.next++
+	return kind
+}
+
+func (r *fixedRandomizer) Clone() Randomizer {
+	c := *r
+	return &c
+}
+
+func (r *fixedRandomizer) Distribution() Distribution {
+	var d Distribution
+	d[KindIndex(r.kinds[r.next%len(r.kinds)])] = 1
+	return d
+}
+
+func (r *fixedRandomizer) Advance(kind string) {
+	r.next++
+}
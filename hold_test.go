@@ -0,0 +1,148 @@
+package tetris
+
+import (
+	"testing"
+)
+
+func TestHoldIntoEmptySlot(t *testing.T) {
+	g := newInputGame(t, "T", "L", "J")
+
+	res := g.Hold()
+	if !res.OK {
+		t.Fatal("Holding into an empty slot should succeed")
+	}
+	if g.Held() == nil || g.Held().Kind() != "T" {
+		t.Error("The T should be held")
+	}
+	if active, _, _ := g.Active(); active.Kind() != "L" {
+		t.Errorf("The next piece should come into play, got %s", active.Kind())
+	}
+}
+
+func TestHoldSwapsWithHeldPiece(t *testing.T) {
+	g := newInputGame(t, "T", "L", "J")
+	g.Hold()
+	g.HardDrop()
+
+	if active, _, _ := g.Active(); active.Kind() != "J" {
+		t.Fatalf("The J should be in play, got %s", active.Kind())
+	}
+	g.Hold()
+	if active, _, _ := g.Active(); active.Kind() != "T" {
+		t.Errorf("The held T should come back into play, got %s", active.Kind())
+	}
+	if g.Held().Kind() != "J" {
+		t.Error("The J should now be held")
+	}
+	if g.Queue()[0] != "T" {
+		t.Error("Swapping with the hold should not take from the queue")
+	}
+}
+
+func TestHoldResetsToSpawnPosition(t *testing.T) {
+	g := newInputGame(t, "L", "O", "J")
+	g.RotateCW()
+	g.MoveLeft()
+	g.SoftDrop()
+	g.Hold()
+	g.HardDrop()
+	g.Hold()
+
+	active, row, col := g.Active()
+	state, srow, scol := SRS.Spawn("L", 10)
+	if active.Kind() != "L" || active.Orient() != state || row != srow || col != scol {
+		t.Errorf("The held L should return at its spawn position, got %d at (%d,%d)", active.Orient(), row, col)
+	}
+	if g.Held().Orient() != state {
+		t.Error("The held piece should be kept in its spawn orientation")
+	}
+}
+
+func TestHoldOncePerPiece(t *testing.T) {
+	g := newInputGame(t, "T", "L", "J")
+	g.Hold()
+	if g.HoldAvailable() {
+		t.Error("Hold should not be available again before a lock")
+	}
+	if g.Hold().OK {
+		t.Error("A second hold before a lock should fail")
+	}
+
+	g.HardDrop()
+	if !g.HoldAvailable() {
+		t.Error("Hold should be available again after a lock")
+	}
+}
+
+func TestUnlimitedHold(t *testing.T) {
+	config := DefaultGameConfig(1)
+	config.Randomizer = &fixedRandomizer{kinds: []string{"T", "L"}}
+	config.UnlimitedHold = true
+	g := newTestGame(t, config)
+
+	g.Hold()
+	if !g.Hold().OK {
+		t.Error("Unlimited hold should allow holding again")
+	}
+	if active, _, _ := g.Active(); active.Kind() != "T" {
+		t.Error("Holding twice should bring the first piece back")
+	}
+}
+
+func TestHoldDisabled(t *testing.T) {
+	config := DefaultGameConfig(1)
+	config.Hold = false
+	g := newTestGame(t, config)
+	if g.HoldAvailable() || g.Apply(Hold).OK {
+		t.Error("Hold should fail when disabled")
+	}
+}
+
+func TestInitialHold(t *testing.T) {
+	config := DefaultGameConfig(1)
+	config.Randomizer = &fixedRandomizer{kinds: []string{"T", "L", "J"}}
+	config.EntryDelay = 2
+	config.InitialHold = true
+	g := newTestGame(t, config)
+
+	g.HardDrop()
+	if !g.Hold().OK {
+		t.Fatal("Hold should be accepted during the entry delay")
+	}
+	g.Tick()
+	g.Tick()
+
+	if active, _, _ := g.Active(); active == nil || active.Kind() != "J" {
+		t.Error("The initial hold should skip straight past the L")
+	}
+	if g.Held() == nil || g.Held().Kind() != "L" {
+		t.Error("The L should be held by the initial hold")
+	}
+	if g.HoldAvailable() {
+		t.Error("An initial hold should use up the hold for that piece")
+	}
+}
+
+func TestHoldDuringEntryDelayWithoutIHS(t *testing.T) {
+	config := DefaultGameConfig(1)
+	config.EntryDelay = 2
+	g := newTestGame(t, config)
+	g.HardDrop()
+	if g.Hold().OK {
+		t.Error("Hold during the entry delay should fail without IHS")
+	}
+}
+
+func TestSnapshotShowsHold(t *testing.T) {
+	g := newInputGame(t, "T", "L")
+	s := g.Snapshot()
+	if s.Held != nil || !s.HoldAvailable {
+		t.Error("Snapshot should show an empty, available hold")
+	}
+
+	g.Hold()
+	s = g.Snapshot()
+	if s.Held == nil || s.Held.Kind() != "T" || s.HoldAvailable {
+		t.Error("Snapshot should show the held T and that hold is used")
+	}
+}
@@ -0,0 +1,208 @@
+package tetris
+
+// A discrete input a player sends to a Game.
+type Action int
+
+const (
+	MoveLeft Action = iota
+	MoveRight
+	SoftDrop // fall one row
+	HardDrop // fall to the floor and lock at once
+	RotateCW
+	RotateCCW
+	Rotate180
+	Hold // swap the active piece with the held one
+)
+
+var actionNames = [...]string{
+	MoveLeft:  "MoveLeft",
+	MoveRight: "MoveRight",
+	SoftDrop:  "SoftDrop",
+	HardDrop:  "HardDrop",
+	RotateCW:  "RotateCW",
+	RotateCCW: "RotateCCW",
+	Rotate180: "Rotate180",
+	Hold:      "Hold",
+}
+
+func (a Action) String() string {
+	if a < 0 || int(a) >= len(actionNames) {
+		return "Action?"
+	}
+	return actionNames[a]
+}
+
+// The outcome of applying an action to a game.
+type ActionResult struct {
+	Action Action
+	OK     bool // false if the action was blocked or there was no piece
+
+	// Where the active piece is after the action.
+	Orient int
+	Row    int
+	Col    int
+
+	Kick    int        // index of the kick a rotation used, -1 otherwise
+	Dropped int        // rows fallen by a soft or hard drop
+	Lock    *LockEvent // set when the action locked the piece
+}
+
+// Applies the given action to the active piece.
+func (g *Game) Apply(a Action) *ActionResult {
+	switch a {
+	case MoveLeft:
+		return g.MoveLeft()
+	case MoveRight:
+		return g.MoveRight()
+	case SoftDrop:
+		return g.SoftDrop()
+	case HardDrop:
+		return g.HardDrop()
+	case RotateCW:
+		return g.RotateCW()
+	case RotateCCW:
+		return g.RotateCCW()
+	case Rotate180:
+		return g.Rotate180()
+	case Hold:
+		return g.Hold()
+	}
+	return g.result(a, false)
+}
+
+// Shifts the active piece one column left.
+func (g *Game) MoveLeft() *ActionResult {
+	return g.shift(MoveLeft, -1)
+}
+
+// Shifts the active piece one column right.
+func (g *Game) MoveRight() *ActionResult {
+	return g.shift(MoveRight, 1)
+}
+
+// Moves the active piece down one row.
+func (g *Game) SoftDrop() *ActionResult {
+	if g.active == nil || !g.canMove(1, 0) {
+		return g.result(SoftDrop, false)
+	}
+
+	g.fall(1)
+	g.fallTimer = 0
+	g.lastAction = SoftDrop
+	g.scorer.Drop(1, false)
+
+	res := g.result(SoftDrop, true)
+	res.Dropped = 1
+	return res
+}
+
+// Drops the active piece as far as it will fall and locks it there.
+func (g *Game) HardDrop() *ActionResult {
+	if g.active == nil {
+		return g.result(HardDrop, false)
+	}
+
+	dist := g.dropDistance()
+	if dist > 0 {
+		g.fall(dist)
+		g.lastAction = HardDrop
+	}
+	g.scorer.Drop(dist, true)
+
+	res := g.result(HardDrop, true)
+	res.Dropped = dist
+	res.Lock = g.lock()
+	return res
+}
+
+// Turns the active piece clockwise, kicking it if needed.
+func (g *Game) RotateCW() *ActionResult {
+	return g.rotate(RotateCW, 1)
+}
+
+// Turns the active piece counter-clockwise, kicking it if needed.
+func (g *Game) RotateCCW() *ActionResult {
+	return g.rotate(RotateCCW, -1)
+}
+
+// Turns the active piece around, kicking it if needed.
+func (g *Game) Rotate180() *ActionResult {
+	return g.rotate(Rotate180, 2)
+}
+
+// Swaps the active piece with the held one, or with the next in the queue if
+// nothing is held yet.  The piece coming out of the hold enters at its spawn
+// position.  Unless the game allows unlimited holds, a piece that came out of
+// the hold can't be held again until the next one spawns.  During the entry
+// delay the hold is instead applied as the next piece spawns, if the game
+// allows initial holds.
+func (g *Game) Hold() *ActionResult {
+	if !g.HoldAvailable() {
+		return g.result(Hold, false)
+	}
+
+	if g.active == nil {
+		if !g.config.InitialHold {
+			return g.result(Hold, false)
+		}
+		g.ihs = true
+		return g.result(Hold, true)
+	}
+
+	g.holdUsed = true
+	g.spawnKind(g.swapHold(g.active.Kind()))
+	return g.result(Hold, true)
+}
+
+func (g *Game) shift(a Action, cols int) *ActionResult {
+	if g.active == nil || !g.canMove(0, cols) {
+		return g.result(a, false)
+	}
+
+	resting := !g.canMove(1, 0)
+	g.col += cols
+	g.lastAction = a
+	g.moved(resting)
+
+	return g.result(a, true)
+}
+
+func (g *Game) rotate(a Action, dir int) *ActionResult {
+	if g.active == nil {
+		return g.result(a, false)
+	}
+
+	resting := !g.canMove(1, 0)
+	row, col, kick, err := Rotate(g.rs, g.board, g.active, g.row, g.col, dir)
+	if err != nil {
+		return g.result(a, false)
+	}
+
+	g.col = col
+	if row > g.row {
+		g.fall(row - g.row)
+	} else {
+		g.row = row
+	}
+	g.lastAction, g.lastKick = a, kick
+	g.moved(resting)
+
+	res := g.result(a, true)
+	res.Kick = kick
+	return res
+}
+
+// Spends a move reset on a piece that was, or now is, resting on the stack.
+func (g *Game) moved(resting bool) {
+	if resting || !g.canMove(1, 0) {
+		g.resetLock()
+	}
+}
+
+func (g *Game) result(a Action, ok bool) *ActionResult {
+	res := &ActionResult{Action: a, OK: ok, Row: g.row, Col: g.col, Kick: -1}
+	if g.active != nil {
+		res.Orient = g.active.Orient()
+	}
+	return res
+}
@@ -0,0 +1,141 @@
+package tetris
+
+import (
+	"testing"
+)
+
+// Creates a game dealing only the given kinds that never falls or locks on its
+// own.
+func newInputGame(t *testing.T, kinds ...string) *Game {
+	config := DefaultGameConfig(1)
+	config.Randomizer = &fixedRandomizer{kinds: kinds}
+	config.Gravity = 1000
+	config.LockDelay = 1000
+	return newTestGame(t, config)
+}
+
+func TestActionString(t *testing.T) {
+	if RotateCCW.String() != "RotateCCW" {
+		t.Error("Action should be named RotateCCW")
+	}
+	if Action(99).String() != "Action?" {
+		t.Error("Unknown actions should have a placeholder name")
+	}
+}
+
+func TestMoveLeftAndRight(t *testing.T) {
+	g := newInputGame(t, "T")
+	_, row, col := g.Active()
+
+	res := g.Apply(MoveLeft)
+	if !res.OK || res.Col != col-1 || res.Row != row {
+		t.Errorf("Move left should shift one column, got %+v", res)
+	}
+
+	res = g.Apply(MoveRight)
+	res = g.Apply(MoveRight)
+	if !res.OK || res.Col != col+1 {
+		t.Errorf("Move right should shift one column, got %+v", res)
+	}
+}
+
+func TestMoveBlockedByWall(t *testing.T) {
+	g := newInputGame(t, "O")
+	for g.MoveLeft().OK {
+	}
+
+	res := g.MoveLeft()
+	if res.OK {
+		t.Error("Moving into the wall should fail")
+	}
+	if _, _, col := g.Active(); res.Col != col {
+		t.Error("A failed move should report the unchanged position")
+	}
+}
+
+func TestSoftDrop(t *testing.T) {
+	g := newInputGame(t, "T")
+	_, row, _ := g.Active()
+
+	res := g.SoftDrop()
+	if !res.OK || res.Row != row+1 || res.Dropped != 1 {
+		t.Errorf("Soft drop should fall one row, got %+v", res)
+	}
+	if res.Lock != nil {
+		t.Error("Soft drop should not lock")
+	}
+}
+
+func TestHardDrop(t *testing.T) {
+	g := newInputGame(t, "I", "O")
+	_, row, col := g.Active()
+
+	res := g.HardDrop()
+	if !res.OK || res.Row != 19 || res.Col != col || res.Dropped != 19-row {
+		t.Errorf("Hard drop should fall to the floor, got %+v", res)
+	}
+	if res.Lock == nil || res.Lock.Kind != "I" || res.Lock.Row != 19 {
+		t.Errorf("Hard drop should lock the piece, got %+v", res.Lock)
+	}
+	if active, _, _ := g.Active(); active == nil || active.Kind() != "O" {
+		t.Error("The next piece should be in play after a hard drop")
+	}
+	if set, _ := g.Board().Block(19, 3); !set {
+		t.Error("The I should be locked on the bottom row")
+	}
+}
+
+func TestRotateReportsKick(t *testing.T) {
+	g := newInputGame(t, "T")
+	res := g.RotateCW()
+	if !res.OK || res.Orient != 1 || res.Kick != 0 {
+		t.Errorf("Rotation in the open should not kick, got %+v", res)
+	}
+
+	for g.MoveLeft().OK {
+	}
+	res = g.RotateCCW()
+	if !res.OK || res.Orient != 0 || res.Kick != 1 {
+		t.Errorf("Rotation against the wall should kick, got %+v", res)
+	}
+}
+
+func TestRotate180(t *testing.T) {
+	g := newInputGame(t, "L")
+	res := g.Rotate180()
+	if !res.OK || res.Orient != 2 {
+		t.Errorf("A half turn should reach state 2, got %+v", res)
+	}
+}
+
+func TestActionsWithoutPiece(t *testing.T) {
+	config := DefaultGameConfig(1)
+	config.EntryDelay = 10
+	g := newTestGame(t, config)
+	g.HardDrop()
+
+	for _, a := range []Action{MoveLeft, MoveRight, SoftDrop, HardDrop, RotateCW, RotateCCW, Rotate180} {
+		if g.Apply(a).OK {
+			t.Errorf("%s should fail during the entry delay", a)
+		}
+	}
+}
+
+func TestMovesResetLockDelay(t *testing.T) {
+	config := DefaultGameConfig(1)
+	config.Randomizer = &fixedRandomizer{kinds: []string{"T"}}
+	config.Gravity = 0
+	config.LockDelay = 3
+	g := newTestGame(t, config)
+
+	g.Tick()
+	g.Tick()
+	g.MoveLeft()
+	g.Tick()
+	if g.Tick() != nil {
+		t.Error("Moving a resting piece should restart the lock delay")
+	}
+	if g.Tick() == nil {
+		t.Error("The piece should lock once the restarted delay passes")
+	}
+}
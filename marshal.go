@@ -0,0 +1,304 @@
+package tetris
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Boards and tetrominoes marshal to a compact binary format that starts with
+// a version byte.  Their text form is the binary form in unpadded URL-safe
+// base64, and their JSON form is the text form as a string.
+//
+// A board is its width and height as uvarints, a bitmap of which blocks are
+// set (row-major, least significant bit first, padded with zero bits to a
+// whole byte), the number of bits used for each cell (4, or 8 if any cell is
+// user-defined) and then the cell filling each set block in the same order,
+// packed the same way.
+//
+// A tetromino is a byte holding its kind's index in Kinds above its two bit
+// orientation, then the name of its rotation system, which must be
+// registered, as a uvarint length and bytes.
+const (
+	boardVersion     = 1
+	tetrominoVersion = 1
+
+	// The most blocks a board read from data may have.
+	maxMarshalBlocks = 1 << 24
+)
+
+var rotationSystems = struct {
+	sync.RWMutex
+	byName map[string]RotationSystem
+}{byName: map[string]RotationSystem{
+	Pason.Name(): Pason,
+	SRS.Name():   SRS,
+	ARS.Name():   ARS,
+	NRS.Name():   NRS,
+}}
+
+// Makes a rotation system known by its name, so tetrominoes using it can be
+// unmarshaled.  The built-in systems are already registered.  It is an error
+// to register another system under a name in use.
+func RegisterRotationSystem(rs RotationSystem) error {
+	rotationSystems.Lock()
+	defer rotationSystems.Unlock()
+
+	if existing, ok := rotationSystems.byName[rs.Name()]; ok && existing != rs {
+		return fmt.Errorf("A rotation system named %q is already registered!", rs.Name())
+	}
+	rotationSystems.byName[rs.Name()] = rs
+	return nil
+}
+
+// Returns the registered rotation system with the given name.
+func LookupRotationSystem(name string) (RotationSystem, error) {
+	rotationSystems.RLock()
+	defer rotationSystems.RUnlock()
+
+	rs, ok := rotationSystems.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown rotation system %q!", name)
+	}
+	return rs, nil
+}
+
+// Returns the names of the registered rotation systems in order.
+func RotationSystemNames() []string {
+	rotationSystems.RLock()
+	defer rotationSystems.RUnlock()
+
+	names := make([]string, 0, len(rotationSystems.byName))
+	for name := range rotationSystems.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (b *Board) MarshalBinary() ([]byte, error) {
+	if b.width < 1 || b.height < 1 {
+		return nil, fmt.Errorf("Board is %dx%d and can't be marshaled!", b.width, b.height)
+	}
+
+	out := []byte{boardVersion}
+	out = binary.AppendUvarint(out, uint64(b.width))
+	out = binary.AppendUvarint(out, uint64(b.height))
+
+	bitmap := make([]byte, (len(b.cells)+7)/8)
+	cellBits := 4
+	var set []Cell
+	for i, cell := range b.cells {
+		if cell.Filled() {
+			bitmap[i/8] |= 1 << uint(i%8)
+			set = append(set, cell)
+			if cell >= 1<<4 {
+				cellBits = 8
+			}
+		}
+	}
+	out = append(out, bitmap...)
+	out = append(out, byte(cellBits))
+
+	packed := make([]byte, (len(set)*cellBits+7)/8)
+	for i, cell := range set {
+		bit := i * cellBits
+		packed[bit/8] |= byte(cell) << uint(bit%8)
+	}
+	return append(out, packed...), nil
+}
+
+func (b *Board) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("Board data is empty!")
+	}
+	if data[0] != boardVersion {
+		return fmt.Errorf("Board data has version %d, expected %d!", data[0], boardVersion)
+	}
+	data = data[1:]
+
+	width, n := binary.Uvarint(data)
+	if n <= 0 {
+		return fmt.Errorf("Board data has no valid width!")
+	}
+	data = data[n:]
+	height, n := binary.Uvarint(data)
+	if n <= 0 {
+		return fmt.Errorf("Board data has no valid height!")
+	}
+	data = data[n:]
+	if width < 1 || height < 1 || width > maxMarshalBlocks || height > maxMarshalBlocks ||
+		width*height > maxMarshalBlocks {
+		return fmt.Errorf("Board data is %dx%d, dimensions must be at least 1 and cover at most %d blocks!",
+			width, height, maxMarshalBlocks)
+	}
+
+	blocks := int(width * height)
+	bitmapLen := (blocks + 7) / 8
+	if len(data) < bitmapLen+1 {
+		return fmt.Errorf("Board data for %dx%d is cut short!", width, height)
+	}
+	bitmap, cellBits, packed := data[:bitmapLen], int(data[bitmapLen]), data[bitmapLen+1:]
+	if cellBits != 4 && cellBits != 8 {
+		return fmt.Errorf("Board data has %d bits per cell, expected 4 or 8!", cellBits)
+	}
+	if blocks%8 != 0 && bitmap[bitmapLen-1]>>uint(blocks%8) != 0 {
+		return fmt.Errorf("Board data sets blocks past the end of a %dx%d board!", width, height)
+	}
+
+	numSet := 0
+	for _, by := range bitmap {
+		for ; by != 0; by &= by - 1 {
+			numSet++
+		}
+	}
+	if len(packed) != (numSet*cellBits+7)/8 {
+		return fmt.Errorf("Board data has %d bytes of cells for %d set blocks, expected %d!",
+			len(packed), numSet, (numSet*cellBits+7)/8)
+	}
+
+	board, _ := NewBoard(int(width), int(height))
+	mask := byte(1<<uint(cellBits) - 1)
+	next := 0
+	for i := 0; i < blocks; i++ {
+		if bitmap[i/8]&(1<<uint(i%8)) == 0 {
+			continue
+		}
+		bit := next * cellBits
+		cell := Cell(packed[bit/8] >> uint(bit%8) & mask)
+		if !cell.Filled() {
+			return fmt.Errorf("Board data sets block (%d,%d) to an empty cell!", i/int(width), i%int(width))
+		}
+		board.set(i/int(width), i%int(width), cell)
+		next++
+	}
+	if bits := numSet * cellBits; bits%8 != 0 && packed[len(packed)-1]>>uint(bits%8) != 0 {
+		return fmt.Errorf("Board data has cells past the last set block!")
+	}
+
+	*b = *board
+	return nil
+}
+
+func (b *Board) MarshalText() ([]byte, error) {
+	return marshalText(b)
+}
+
+func (b *Board) UnmarshalText(text []byte) error {
+	return unmarshalText(text, b)
+}
+
+func (b *Board) MarshalJSON() ([]byte, error) {
+	return marshalJSON(b)
+}
+
+func (b *Board) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, b, "Board")
+}
+
+func (t *Tetromino) MarshalBinary() ([]byte, error) {
+	if t.rs == nil {
+		return nil, fmt.Errorf("Tetromino has no rotation system and can't be marshaled!")
+	}
+	kind := KindIndex(t.kind)
+	if kind < 0 || t.orient < 0 || t.orient > 3 {
+		return nil, fmt.Errorf("Tetromino %s in state %d can't be marshaled!", t.kind, t.orient)
+	}
+
+	name := t.rs.Name()
+	out := []byte{tetrominoVersion, byte(kind<<2 | t.orient)}
+	out = binary.AppendUvarint(out, uint64(len(name)))
+	return append(out, name...), nil
+}
+
+func (t *Tetromino) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 {
+		return fmt.Errorf("Tetromino data is %d bytes, too short!", len(data))
+	}
+	if data[0] != tetrominoVersion {
+		return fmt.Errorf("Tetromino data has version %d, expected %d!", data[0], tetrominoVersion)
+	}
+
+	kind, orient := int(data[1]>>2), int(data[1]&3)
+	if kind >= len(Kinds) {
+		return fmt.Errorf("Tetromino data has unknown kind %d!", kind)
+	}
+
+	length, n := binary.Uvarint(data[2:])
+	if n <= 0 || uint64(len(data)-2-n) != length {
+		return fmt.Errorf("Tetromino data has a malformed rotation system name!")
+	}
+	rs, err := LookupRotationSystem(string(data[2+n:]))
+	if err != nil {
+		return err
+	}
+
+	tet, err := NewTetrominoFor(rs, Kinds[kind], orient)
+	if err != nil {
+		return err
+	}
+	*t = *tet
+	return nil
+}
+
+func (t *Tetromino) MarshalText() ([]byte, error) {
+	return marshalText(t)
+}
+
+func (t *Tetromino) UnmarshalText(text []byte) error {
+	return unmarshalText(text, t)
+}
+
+func (t *Tetromino) MarshalJSON() ([]byte, error) {
+	return marshalJSON(t)
+}
+
+func (t *Tetromino) UnmarshalJSON(data []byte) error {
+	return unmarshalJSON(data, t, "Tetromino")
+}
+
+type binaryMarshaler interface {
+	MarshalBinary() ([]byte, error)
+}
+
+type binaryUnmarshaler interface {
+	UnmarshalBinary(data []byte) error
+}
+
+func marshalText(v binaryMarshaler) ([]byte, error) {
+	data, err := v.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	text := make([]byte, base64.RawURLEncoding.EncodedLen(len(data)))
+	base64.RawURLEncoding.Encode(text, data)
+	return text, nil
+}
+
+func unmarshalText(text []byte, v binaryUnmarshaler) error {
+	data := make([]byte, base64.RawURLEncoding.DecodedLen(len(text)))
+	n, err := base64.RawURLEncoding.Decode(data, text)
+	if err != nil {
+		return fmt.Errorf("Text %q is not valid base64: %s", text, err)
+	}
+	return v.UnmarshalBinary(data[:n])
+}
+
+func marshalJSON(v binaryMarshaler) ([]byte, error) {
+	text, err := marshalText(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+func unmarshalJSON(data []byte, v binaryUnmarshaler, what string) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("%s JSON must be a string: %s", what, err)
+	}
+	return unmarshalText([]byte(text), v)
+}
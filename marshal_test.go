@@ -0,0 +1,202 @@
+package tetris
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func marshalTestBoard() *Board {
+	b, _ := NewBoard(10, 20)
+	b.SetRow(19, true)
+	b.SetCell(19, 4, CellEmpty)
+	b.SetCell(18, 0, CellT)
+	b.SetCell(18, 1, CellI)
+	b.SetCell(17, 9, CellL)
+	return b
+}
+
+func TestBoardBinaryRoundTrip(t *testing.T) {
+	wide, _ := NewBoard(70, 3)
+	wide.SetCell(1, 65, CellZ)
+	wide.SetCell(2, 3, CellUser+5)
+
+	for _, b := range []*Board{marshalTestBoard(), wide} {
+		data, err := b.MarshalBinary()
+		if err != nil {
+			t.Fatalf("Board should marshal: %s", err)
+		}
+
+		var c Board
+		if err := c.UnmarshalBinary(data); err != nil {
+			t.Fatalf("Board should unmarshal: %s", err)
+		}
+		if !c.EqualCells(b) || c.Hash() != b.Hash() {
+			t.Errorf("The board should come back the same:\n%s", &c)
+		}
+	}
+}
+
+func TestBoardBinaryIsCompact(t *testing.T) {
+	b, _ := NewBoard(10, 20)
+	data, _ := b.MarshalBinary()
+	// Version, width, height, 25 bytes of bitmap and the cell size.
+	if len(data) != 29 {
+		t.Errorf("An empty 10x20 board should take 29 bytes, took %d", len(data))
+	}
+
+	b.SetRow(19, true)
+	data, _ = b.MarshalBinary()
+	if len(data) != 29+5 {
+		t.Errorf("Ten garbage cells should take 5 more bytes, took %d", len(data)-29)
+	}
+}
+
+func TestBoardTextAndJSON(t *testing.T) {
+	b := marshalTestBoard()
+	text, err := b.MarshalText()
+	if err != nil {
+		t.Fatalf("Board should marshal as text: %s", err)
+	}
+	var c Board
+	if err := c.UnmarshalText(text); err != nil || !c.EqualCells(b) {
+		t.Errorf("Board should come back from text %s: %v", text, err)
+	}
+
+	type position struct {
+		Board *Board
+		Piece *Tetromino
+	}
+	piece, _ := NewTetrominoFor(SRS, "J", 3)
+	data, err := json.Marshal(position{b, piece})
+	if err != nil {
+		t.Fatalf("Position should marshal as JSON: %s", err)
+	}
+	if !strings.Contains(string(data), `"Board":"`+string(text)+`"`) {
+		t.Errorf("The board should be its text as a JSON string, got %s", data)
+	}
+
+	var p position
+	if err := json.Unmarshal(data, &p); err != nil {
+		t.Fatalf("Position should unmarshal from JSON: %s", err)
+	}
+	if !p.Board.EqualCells(b) || p.Piece.Kind() != "J" || p.Piece.Orient() != 3 || p.Piece.System() != SRS {
+		t.Errorf("The position should come back the same, got %+v", p)
+	}
+}
+
+func TestBoardUnmarshalErrors(t *testing.T) {
+	valid, _ := marshalTestBoard().MarshalBinary()
+	corrupt := func(f func([]byte) []byte) []byte {
+		return f(append([]byte(nil), valid...))
+	}
+
+	cases := map[string][]byte{
+		"empty":         {},
+		"version":       corrupt(func(d []byte) []byte { d[0] = 9; return d }),
+		"zero width":    {boardVersion, 0, 5, 0, 4},
+		"huge":          {boardVersion, 0xff, 0xff, 0xff, 0x7f, 0xff, 0xff, 0xff, 0x7f, 4},
+		"truncated":     valid[:20],
+		"extra cells":   append(append([]byte(nil), valid...), 0),
+		"missing cells": valid[:len(valid)-1],
+		"cell size":     corrupt(func(d []byte) []byte { d[28] = 5; return d }),
+		"empty cell":    corrupt(func(d []byte) []byte { d[29] = 0xf0; return d }),
+		"padding":       {boardVersion, 3, 1, 0xf8, 4},
+	}
+	for name, data := range cases {
+		var b Board
+		if err := b.UnmarshalBinary(data); err == nil {
+			t.Errorf("Unmarshaling %s data should be an error", name)
+		}
+	}
+
+	var b Board
+	if err := b.UnmarshalText([]byte("not base64!")); err == nil {
+		t.Error("Unmarshaling text that isn't base64 should be an error")
+	}
+	if err := json.Unmarshal([]byte(`{"width":10}`), &b); err == nil {
+		t.Error("Unmarshaling JSON that isn't a string should be an error")
+	}
+	if _, err := (&Board{}).MarshalBinary(); err == nil {
+		t.Error("Marshaling a zero board should be an error")
+	}
+}
+
+func TestTetrominoRoundTrip(t *testing.T) {
+	for _, rs := range []RotationSystem{Pason, SRS, ARS, NRS} {
+		for _, kind := range Kinds {
+			for state := 0; state < rs.NumStates(kind); state++ {
+				tet, _ := NewTetrominoFor(rs, kind, state)
+				text, err := tet.MarshalText()
+				if err != nil {
+					t.Fatalf("%s %s should marshal: %s", rs.Name(), kind, err)
+				}
+
+				var back Tetromino
+				if err := back.UnmarshalText(text); err != nil {
+					t.Fatalf("%s %s should unmarshal: %s", rs.Name(), kind, err)
+				}
+				if back.Kind() != kind || back.Orient() != state || back.System() != rs || !back.Data().Equal(tet.Data()) {
+					t.Errorf("%s %s in state %d should come back the same", rs.Name(), kind, state)
+				}
+			}
+		}
+	}
+}
+
+func TestTetrominoUnmarshalErrors(t *testing.T) {
+	tet, _ := NewTetrominoFor(SRS, "T", 1)
+	valid, _ := tet.MarshalBinary()
+
+	cases := map[string][]byte{
+		"short":       {tetrominoVersion},
+		"version":     {2, valid[1], valid[2], 's', 'r', 's'},
+		"kind":        {tetrominoVersion, 7 << 2, 3, 's', 'r', 's'},
+		"state":       {tetrominoVersion, byte(KindIndex("O"))<<2 | 1, 5, 'p', 'a', 's', 'o', 'n'},
+		"system":      {tetrominoVersion, valid[1], 3, 'x', 'y', 'z'},
+		"name length": append(append([]byte(nil), valid...), 'x'),
+	}
+	for name, data := range cases {
+		var back Tetromino
+		if err := back.UnmarshalBinary(data); err == nil {
+			t.Errorf("Unmarshaling data with a bad %s should be an error", name)
+		}
+	}
+}
+
+// A system for the registry test, made once so that registering it again on
+// later runs of the test is not a clash.
+var customTestSystem = newTableRotation("custom-test", srs_orients, srsKicks)
+
+func TestRotationSystemRegistry(t *testing.T) {
+	rs, err := LookupRotationSystem("ars")
+	if err != nil || rs != ARS {
+		t.Errorf("ARS should be registered, got %v", err)
+	}
+	if _, err := LookupRotationSystem("missing"); err == nil {
+		t.Error("Looking up an unknown system should be an error")
+	}
+
+	custom := customTestSystem
+	if err := RegisterRotationSystem(custom); err != nil {
+		t.Fatalf("A new system should register: %s", err)
+	}
+	if err := RegisterRotationSystem(custom); err != nil {
+		t.Errorf("Registering the same system twice should be fine: %s", err)
+	}
+	if err := RegisterRotationSystem(newTableRotation("srs", srs_orients, srsKicks)); err == nil {
+		t.Error("Registering another system under a used name should be an error")
+	}
+
+	tet, _ := NewTetrominoFor(custom, "S", 2)
+	data, _ := tet.MarshalBinary()
+	var back Tetromino
+	if err := back.UnmarshalBinary(data); err != nil || back.System() != custom {
+		t.Errorf("A tetromino of a registered system should come back with it: %v", err)
+	}
+
+	names := RotationSystemNames()
+	if len(names) < 5 || names[0] != "ars" {
+		t.Errorf("The names should be sorted and include the built-ins, got %v", names)
+	}
+}
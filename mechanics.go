@@ -2,6 +2,7 @@ package tetris
 
 import (
 	"fmt"
+	"math/bits"
 )
 
 var leastRotations = [4][4]int{
@@ -16,31 +17,25 @@ func FindLeastRotation(from, to int) int {
 }
 
 // For the given board, clears any full lines adjusting the board accordingly.
-// The value returned is the number of lines cleared in the operation.  This
+// Rows above the cleared lines keep their cells as they shift down.  The value
+// returned is the number of lines cleared in the operation.  This
 // method modifies directly the board passed in.  If this is not desired, be
 // sure to Copy() the board before passing it in.
 func ClearFullLines(b *Board) int {
-	lines := FindFullLines(b)
-	if len(lines) == 0 {
-		return 0
-	}
-
-	step := 1
-	workRow := lines[len(lines)-step] // start on lowest cleared line
-	for (workRow - step) >= 0 {
-		copyRow := workRow - step
-		nextLineIdx := len(lines) - step - 1
-
-		if nextLineIdx < 0 || copyRow != lines[nextLineIdx] {
-			// If the copy source row is not the next clear line, copy it to
-			// the working row then move the working row up by one
-			b.CopyRow(copyRow, workRow)
-			workRow--
-		} else {
-			// Otherwise, increase the step and retry on a copyRow step above
-			// the workRow
-			step = step + 1
+	cleared := 0
+
+	// Walk up from the bottom, copying every row that survives down to the
+	// lowest row not yet written.
+	workRow := b.Height() - 1
+	for row := b.Height() - 1; row >= 0; row-- {
+		if b.rowFull(row) {
+			cleared++
+			continue
 		}
+		if workRow != row {
+			b.CopyRow(row, workRow)
+		}
+		workRow--
 	}
 
 	// Finally, clear the remaining rows
@@ -49,7 +44,7 @@ func ClearFullLines(b *Board) int {
 		workRow--
 	}
 
-	return len(lines)
+	return cleared
 }
 
 // Locates the rows which have full lines and returns their row indices in
@@ -58,15 +53,7 @@ func FindFullLines(b *Board) []int {
 	lines := make([]int, 0)
 
 	for row := 0; row < b.Height(); row++ {
-		full := true
-		for col := 0; col < b.Width(); col++ {
-			if set, _ := b.Block(row, col); !set {
-				full = false
-				break
-			}
-		}
-
-		if full {
+		if b.rowFull(row) {
 			lines = append(lines, row)
 		}
 	}
@@ -75,13 +62,13 @@ func FindFullLines(b *Board) []int {
 }
 
 // Returns the board, the final column, and an error
-func PlaceInColumn(b *Board, s *Tetromino, row, col int) (*Board, int, error) {
-	err := CheckPlacement(b, s, row, col)
+func PlaceInColumn(rs RotationSystem, b *Board, s *Tetromino, row, col int) (*Board, int, error) {
+	err := CheckPlacement(rs, b, s, row, col)
 	if err != nil {
 		return nil, -1, err
 	}
 
-	board, frow, err := PlaceInLastRow(b, s, row, col)
+	board, frow, err := PlaceInLastRow(rs, b, s, row, col)
 	if err != nil {
 		return nil, -1, err
 	}
@@ -90,37 +77,43 @@ func PlaceInColumn(b *Board, s *Tetromino, row, col int) (*Board, int, error) {
 }
 
 // Returns the board, the final row, and an error
-func PlaceInLastRow(b *Board, s *Tetromino, row, col int) (*Board, int, error) {
+func PlaceInLastRow(rs RotationSystem, b *Board, s *Tetromino, row, col int) (*Board, int, error) {
+	if err := checkSystem(rs, s); err != nil {
+		return nil, -1, err
+	}
+
+	pr, pc := rs.Pivot(s.Kind())
 	for i := row; i < b.Height(); i++ {
-		e := CheckPlacement(b, s, i+1, col)
-		if e != nil {
-			b, _ := Place(b, s, i, col)
+		if !fitsAt(b, s, i+1-pr, col-pc) {
+			b, _ := Place(rs, b, s, i, col)
 			return b, i, nil
 		}
 	}
 	return nil, -1, fmt.Errorf("Could not place in row %d!", row)
 }
 
-func Place(b *Board, t *Tetromino, row, col int) (*Board, error) {
+// Places the tetromino on a copy of the board, filling its blocks with the
+// cell for the tetromino's kind.  The row and column locate the tetromino's
+// pivot in the given rotation system, which must be the tetromino's own.
+func Place(rs RotationSystem, b *Board, t *Tetromino, row, col int) (*Board, error) {
 	board := b.Copy()
 
-	err := CheckPlacement(board, t, row, col)
+	err := CheckPlacement(rs, board, t, row, col)
 	if err != nil {
 		return board, err
 	}
 
-	origin_row := 1
-	origin_col := 2
-
+	pr, pc := rs.Pivot(t.Kind())
+	cell := KindCell(t.Kind())
 	for i := 0; i < 4; i++ {
+		r := row - pr + i
+		if t.masks[i] == 0 || r < 0 || r >= board.Height() {
+			continue
+		}
+
 		for j := 0; j < 4; j++ {
-			r := row - origin_row + i
-			c := col - origin_col + j
-			if r >= 0 && r < board.Height() && c >= 0 && c < board.Width() && t.Data()[i][j] {
-				err = board.SetBlock(r, c, true)
-				if err != nil {
-					return board, fmt.Errorf("Can't place block (%d,%d)!\n", r, c)
-				}
+			if t.Data()[i][j] {
+				board.set(r, col-pc+j, cell)
 			}
 		}
 	}
@@ -128,33 +121,99 @@ func Place(b *Board, t *Tetromino, row, col int) (*Board, error) {
 	return board, nil
 }
 
-func CheckPlacement(b *Board, t *Tetromino, row, col int) error {
-	left := t.Bounds().Left
-	right := t.Bounds().Right
-	bottom := t.Bounds().Bottom
-
-	origin_row := 1
-	origin_col := 2
+// Checks that the tetromino, with its pivot in the given rotation system at
+// (row, col), lies on the board without covering any set blocks.  It is an
+// error for the tetromino to belong to another rotation system, as its shape
+// would not match the pivot.
+func CheckPlacement(rs RotationSystem, b *Board, t *Tetromino, row, col int) error {
+	if err := checkSystem(rs, t); err != nil {
+		return err
+	}
 
-	if (left-origin_col+col) < 0 || (right-origin_col+col) >= b.Width() ||
-		(bottom-origin_row+row) >= b.Height() {
+	pr, pc := rs.Pivot(t.Kind())
+	if !inBoundsAt(b, t, row-pr, col-pc) {
 		return fmt.Errorf("Block placed at (%d,%d) would be out of bounds!", row, col)
 	}
 
-	// Now that we know it's not out of bounds, loop through 4x4 data
-	// Convert (i,j) to global position (x,y) and check if the board has been blocked there
-	// If it has, return error
+	if r, c, taken := overlapAt(b, t, row-pr, col-pc); taken {
+		return fmt.Errorf("Block (%d,%d) already taken!", r, c)
+	}
+
+	return nil
+}
 
+// Returns an error unless the tetromino was created for the rotation system.
+func checkSystem(rs RotationSystem, t *Tetromino) error {
+	if t.System() != rs {
+		return fmt.Errorf("Tetromino %s of rotation system %s used with %s!", t.Kind(), t.System().Name(), rs.Name())
+	}
+	return nil
+}
+
+// The block of a tetromino's 4x4 data that the built-in rotation systems
+// position at the row and column given to the placement functions.
+const (
+	origin_row = 1
+	origin_col = 2
+)
+
+// Same as CheckPlacement but without the cost of building an error, for use in
+// loops that probe many positions.
+func fits(rs RotationSystem, b *Board, t *Tetromino, row, col int) bool {
+	pr, pc := rs.Pivot(t.Kind())
+	return fitsAt(b, t, row-pr, col-pc)
+}
+
+// The functions below locate a tetromino by the board position of the top
+// left corner of its 4x4 data rather than by its pivot.
+
+func fitsAt(b *Board, t *Tetromino, top, left int) bool {
+	if !inBoundsAt(b, t, top, left) {
+		return false
+	}
+	_, _, taken := overlapAt(b, t, top, left)
+	return !taken
+}
+
+// Checks that the tetromino lies within the left, right and bottom edges of the
+// board.  Blocks above the top of the board are allowed.
+func inBoundsAt(b *Board, t *Tetromino, top, left int) bool {
+	bounds := t.Bounds()
+	return (bounds.Left+left) >= 0 && (bounds.Right+left) < b.Width() &&
+		(bounds.Bottom+top) < b.Height()
+}
+
+// Finds the first block already set on the board that the tetromino would
+// cover, returning its row and column and true if there is one.  Rows wholly
+// above or below the board are skipped.
+func overlapAt(b *Board, t *Tetromino, top, left int) (int, int, bool) {
 	for i := 0; i < 4; i++ {
+		r := top + i
+		if t.masks[i] == 0 || r < 0 || r >= b.Height() {
+			continue
+		}
+
+		if b.words == 1 {
+			if hit := b.data[r] & shiftMask(t.masks[i], left); hit != 0 {
+				return r, bits.TrailingZeros64(hit), true
+			}
+			continue
+		}
 		for j := 0; j < 4; j++ {
-			r := row - origin_row + i
-			c := col - origin_col + j
-			d, err := b.Block(r, c)
-			if t.Data()[i][j] && d && err == nil {
-				return fmt.Errorf("Block (%d,%d) already taken!", r, c)
+			c := left + j
+			if t.Data()[i][j] && c >= 0 && c < b.Width() && b.isSet(r, c) {
+				return r, c, true
 			}
 		}
 	}
 
-	return nil
+	return -1, -1, false
+}
+
+// Moves a tetromino row mask so that bit n lines up with board column n+shift.
+func shiftMask(mask uint64, shift int) uint64 {
+	if shift >= 0 {
+		return mask << uint(shift)
+	}
+	return mask >> uint(-shift)
 }
@@ -281,7 +281,7 @@ func BenchmarkClearFullLines(b *testing.B) {
 func TestPlacesOnClearBoard(t *testing.T) {
 	b, _ := NewBoard(10, 20)
 	p, _ := NewTetromino("O", 0)
-	_, err := Place(b, p, 0, 5)
+	_, err := Place(Pason, b, p, 0, 5)
 	if err != nil {
 		fmt.Print(err)
 		t.Error("Shouldn't receive error on good placement!")
@@ -292,12 +292,23 @@ func TestPlaceFailsOnBadPlacement(t *testing.T) {
 	b, _ := NewBoard(10, 20)
 	b.SetBlock(0, 3, true)
 	p, _ := NewTetromino("I", 0)
-	_, placement := Place(b, p, 0, 5)
+	_, placement := Place(Pason, b, p, 0, 5)
 	if placement == nil {
 		t.Error("Shouldn't be able to do a bad placement!")
 	}
 }
 
+func TestPlaceFailsForAnotherRotationSystem(t *testing.T) {
+	b, _ := NewBoard(10, 20)
+	p, _ := NewTetrominoFor(ARS, "T", 0)
+	if _, err := Place(SRS, b, p, 10, 5); err == nil {
+		t.Error("Shouldn't place a tetromino with another system's pivot!")
+	}
+	if _, _, err := PlaceInColumn(SRS, b, p, 0, 5); err == nil {
+		t.Error("Shouldn't drop a tetromino with another system's pivot!")
+	}
+}
+
 func TestPlaceSucceedsOnValidPlacementI(t *testing.T) {
 	b, _ := NewBoard(10, 20)
 	b.SetBlock(4, 1, true)
@@ -315,7 +326,7 @@ func TestPlaceSucceedsOnValidPlacementI(t *testing.T) {
 	b.SetBlock(5, 5, true)
 	b.SetBlock(5, 6, true)
 	p, _ := NewTetromino("I", 0)
-	_, placement := Place(b, p, 4, 4)
+	_, placement := Place(Pason, b, p, 4, 4)
 	if placement != nil {
 		t.Error("Shouldn't be able to do a bad placement!")
 	}
@@ -338,7 +349,7 @@ func TestPlaceSucceedsOnValidPlacementT(t *testing.T) {
 	b.SetBlock(4, 5, true)
 
 	p, _ := NewTetromino("T", 3)
-	_, placement := Place(b, p, 2, 4)
+	_, placement := Place(Pason, b, p, 2, 4)
 	if placement != nil {
 		t.Error("Shouldn't be able to do a bad placement!")
 	}
@@ -362,7 +373,7 @@ func TestPlaceInColumnOnEmptyBoard(t *testing.T) {
 	})
 
 	tet, _ := NewTetromino("T", 2)
-	board, _, err := PlaceInColumn(board, tet, 1, 1)
+	board, _, err := PlaceInColumn(Pason, board, tet, 1, 1)
 	if err != nil {
 		t.Error("Placement should be valid")
 		t.FailNow() // board will be nil in this case
@@ -393,7 +404,7 @@ func TestPlaceInColumnOnExistingPiece(t *testing.T) {
 	})
 
 	tet, _ := NewTetromino("T", 3)
-	board, _, err := PlaceInColumn(board, tet, 1, 3)
+	board, _, err := PlaceInColumn(Pason, board, tet, 1, 3)
 	if err != nil {
 		t.Error("Placement should be valid")
 		t.FailNow() // board will be nil in this case
@@ -434,7 +445,7 @@ func TestPlaceInColumnDoesntPhaseThroughBlocks(t *testing.T) {
 	})
 
 	tet, _ := NewTetromino("I", 1)
-	board, _, err := PlaceInColumn(board, tet, 1, 4)
+	board, _, err := PlaceInColumn(Pason, board, tet, 1, 4)
 	if err != nil {
 		t.Error("Placement should be valid")
 		t.FailNow() // board will be nil in this case
@@ -457,7 +468,7 @@ func TestPlaceInColumnErrorsOnBadPlacement(t *testing.T) {
 	})
 
 	tet, _ := NewTetromino("T", 3)
-	board, _, err := PlaceInColumn(board, tet, 1, 3)
+	board, _, err := PlaceInColumn(Pason, board, tet, 1, 3)
 	if err == nil {
 		t.Error("Placement should be invalid")
 	}
@@ -476,3 +487,144 @@ func intArrSame(a, b []int) bool {
 
 	return true
 }
+
+// A cluttered, realistic mid-game board used by the placement benchmarks.
+func benchBoard() *Board {
+	board, _ := StringArrayToBoard([]string{
+		"|          |",
+		"|          |",
+		"|          |",
+		"|          |",
+		"|          |",
+		"|          |",
+		"|          |",
+		"|          |",
+		"|          |",
+		"|          |",
+		"|          |",
+		"|          |",
+		"|     #    |",
+		"|#   ##    |",
+		"|## ####  #|",
+		"|## ##### #|",
+		"|######## #|",
+		"|#### #####|",
+		"|######### |",
+		"|#### #####|",
+	})
+	return board
+}
+
+func BenchmarkFindFullLines(b *testing.B) {
+	board := benchBoard()
+	for i := 0; i < b.N; i++ {
+		FindFullLines(board)
+	}
+}
+
+func BenchmarkCheckPlacement(b *testing.B) {
+	board := benchBoard()
+	tet, _ := NewTetromino("T", 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for col := 1; col < 9; col++ {
+			CheckPlacement(Pason, board, tet, 10, col)
+		}
+	}
+}
+
+func BenchmarkPlaceInLastRow(b *testing.B) {
+	board := benchBoard()
+	tet, _ := NewTetromino("L", 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		PlaceInLastRow(Pason, board, tet, 0, 5)
+	}
+}
+
+func TestClearFullLinesOnWideBoard(t *testing.T) {
+	board, _ := NewBoard(80, 4)
+	board.SetRow(3, true)
+	board.SetRow(2, true)
+	board.SetBlock(2, 70, false)
+	board.SetBlock(1, 65, true)
+
+	lines := FindFullLines(board)
+	if !intArrSame([]int{3}, lines) {
+		t.Errorf("Only row 3 should be full, got %v", lines)
+	}
+
+	numLines := ClearFullLines(board)
+	if numLines != 1 {
+		t.Error("Number of cleared lines should be 1")
+	}
+
+	expected, _ := NewBoard(80, 4)
+	expected.SetRow(3, true)
+	expected.SetBlock(3, 70, false)
+	expected.SetBlock(2, 65, true)
+	if !board.Equal(expected) {
+		t.Error("Modified wide board should reflect removed lines")
+	}
+}
+
+func TestPlaceAcrossWordBoundaryOnWideBoard(t *testing.T) {
+	board, _ := NewBoard(70, 5)
+	board.SetBlock(4, 62, true)
+
+	tet, _ := NewTetromino("I", 0)
+	if err := CheckPlacement(Pason, board, tet, 4, 64); err == nil {
+		t.Error("Placement over a set block should fail")
+	}
+
+	placed, err := Place(Pason, board, tet, 3, 64)
+	if err != nil {
+		t.Error("Placement should be valid")
+		t.FailNow()
+	}
+	for col := 62; col < 66; col++ {
+		if set, _ := placed.Block(3, col); !set {
+			t.Errorf("Block (3,%d) should be set by placement", col)
+		}
+	}
+
+	if err := CheckPlacement(Pason, board, tet, 3, 69); err == nil {
+		t.Error("Placement past the right edge should fail")
+	}
+}
+
+func TestPlaceWritesKind(t *testing.T) {
+	board, _ := NewBoard(5, 5)
+	tet, _ := NewTetromino("S", 0)
+	board, _ = Place(Pason, board, tet, 2, 2)
+
+	for _, c := range [][2]int{{2, 2}, {2, 3}, {3, 1}, {3, 2}} {
+		if cell, _ := board.Cell(c[0], c[1]); cell != CellS {
+			t.Errorf("Cell (%d,%d) should be S, was %s", c[0], c[1], cell)
+		}
+	}
+}
+
+func TestClearFullLinesKeepsCells(t *testing.T) {
+	board, _ := StringArrayToBoard([]string{
+		"|     |",
+		"|     |",
+		"|#### |",
+		"|#### |",
+	})
+	tet, _ := NewTetromino("I", 1)
+	board, _ = Place(Pason, board, tet, 1, 4)
+	board.SetCell(1, 0, CellT)
+
+	ClearFullLines(board)
+
+	if cell, _ := board.Cell(3, 0); cell != CellT {
+		t.Errorf("T cell should have shifted down to row 3, found %s", cell)
+	}
+	if cell, _ := board.Cell(3, 4); cell != CellI {
+		t.Errorf("I cell should have shifted down to row 3, found %s", cell)
+	}
+	if cell, _ := board.Cell(1, 0); cell != CellEmpty {
+		t.Errorf("Row 1 should be empty after the clear, found %s", cell)
+	}
+}
@@ -0,0 +1,89 @@
+package tetris
+
+import (
+	"fmt"
+)
+
+// Describes the inputs a path needs, from easiest to hardest to perform.
+type PathKind int
+
+const (
+	PathHardDrop PathKind = iota // shifts and rotations then a hard drop
+	PathTuck                     // soft drops, then only shifts
+	PathSpin                     // a rotation after a soft drop
+	numPathKinds
+)
+
+var pathKindNames = [...]string{
+	PathHardDrop: "HardDrop",
+	PathTuck:     "Tuck",
+	PathSpin:     "Spin",
+}
+
+func (k PathKind) String() string {
+	if k < 0 || k >= numPathKinds {
+		return "PathKind?"
+	}
+	return pathKindNames[k]
+}
+
+// Returns the kind of a path of this kind once the given action is added.
+func (k PathKind) after(a Action) PathKind {
+	switch {
+	case a == SoftDrop && k < PathTuck:
+		return PathTuck
+	case isRotation(a) && k == PathTuck:
+		return PathSpin
+	}
+	return k
+}
+
+// A sequence of inputs that moves a tetromino from where it starts to where it
+// locks.
+type InputPath struct {
+	Actions []Action // ending with a HardDrop
+	Kind    PathKind
+}
+
+// Finds the shortest sequence of inputs that takes a tetromino of the given
+// kind starting in the given orientation at (row, col) to lock at (toRow,
+// toCol) in orientation toOrient.  The target must be a position where the
+// tetromino rests on the stack or floor.  Shifts, quarter turns with the
+// rotation system's kicks and single row soft drops are considered, and the
+// path returned is the shortest of the easiest kind that reaches the target:
+// a hard drop alone if possible, then tucks, then spins.  Orientations of
+// symmetric pieces that cover the same blocks reach the same target.
+func FindPath(rs RotationSystem, b *Board, kind string, orient, row, col, toOrient, toRow, toCol int) (*InputPath, error) {
+	s := newPlacementSearch(rs, b, kind, true)
+	if s == nil {
+		return nil, fmt.Errorf("Kind %s is not in rotation system %s!", kind, rs.Name())
+	}
+	if toOrient < 0 || toOrient >= len(s.tets) {
+		return nil, fmt.Errorf("Orientation %d of %s does not exist!", toOrient, kind)
+	}
+	if !s.fits(toOrient, toRow, toCol) || s.fits(toOrient, toRow+1, toCol) {
+		return nil, fmt.Errorf("%s cannot lock at (%d,%d)!", kind, toRow, toCol)
+	}
+	target, ok := s.cells(toOrient, toRow, toCol)
+	if !ok {
+		return nil, fmt.Errorf("%s at (%d,%d) would lock above the board!", kind, toRow, toCol)
+	}
+
+	best := -1
+	s.run(orient, row, col, func(n int) bool {
+		node := s.nodes[n]
+		if best >= 0 && s.nodes[best].class <= node.class {
+			return true
+		}
+		cells, _ := s.cells(node.orient, s.landing(node.orient, node.row, node.col), node.col)
+		if cells == target {
+			best = n
+		}
+		return best < 0 || s.nodes[best].class != PathHardDrop
+	})
+	if best < 0 {
+		return nil, fmt.Errorf("%s cannot reach (%d,%d) in orientation %d!", kind, toRow, toCol, toOrient)
+	}
+
+	return &InputPath{append(s.path(best), HardDrop), s.nodes[best].class}, nil
+}
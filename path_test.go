@@ -0,0 +1,110 @@
+package tetris
+
+import (
+	"testing"
+)
+
+func findSpawnedPath(t *testing.T, b *Board, kind string, orient, row, col int) *InputPath {
+	state, srow, scol := SRS.Spawn(kind, b.Width())
+	path, err := FindPath(SRS, b, kind, state, srow, scol, orient, row, col)
+	if err != nil {
+		t.Fatalf("A path should be found: %s", err)
+	}
+	if path.Actions[len(path.Actions)-1] != HardDrop {
+		t.Error("The path should end with a hard drop")
+	}
+	return path
+}
+
+func TestPathKindString(t *testing.T) {
+	if PathTuck.String() != "Tuck" {
+		t.Error("Path kind should be named Tuck")
+	}
+	if PathKind(9).String() != "PathKind?" {
+		t.Error("Unknown path kinds should have a placeholder name")
+	}
+}
+
+func TestFindPathStraightDrop(t *testing.T) {
+	board, _ := NewBoard(10, 20)
+	_, _, col := SRS.Spawn("T", 10)
+
+	path := findSpawnedPath(t, board, "T", 0, 19, 1)
+	if path.Kind != PathHardDrop {
+		t.Errorf("An open column should need a hard drop only, needed %s", path.Kind)
+	}
+	if len(path.Actions) != col {
+		t.Errorf("The path should be %d shifts and a drop, was %v", col-1, path.Actions)
+	}
+}
+
+func TestFindPathIgnoresSymmetricRotation(t *testing.T) {
+	board, _ := NewBoard(10, 20)
+	path := findSpawnedPath(t, board, "O", 2, 19, 4)
+	for _, a := range path.Actions {
+		if isRotation(a) {
+			t.Errorf("An O should not rotate to reach a symmetric state, path was %v", path.Actions)
+		}
+	}
+}
+
+func TestFindPathTuck(t *testing.T) {
+	board, _ := StringArrayToBoard([]string{
+		"|          |",
+		"|          |",
+		"|          |",
+		"|     #####|",
+		"|          |",
+		"|          |",
+	})
+
+	path := findSpawnedPath(t, board, "O", 0, 5, 8)
+	if path.Kind != PathTuck {
+		t.Errorf("Reaching under the overhang should be a tuck, was %s", path.Kind)
+	}
+}
+
+func TestFindPathSpin(t *testing.T) {
+	path := findSpawnedPath(t, tsdBoard(), "T", 2, 3, 3)
+	if path.Kind != PathSpin {
+		t.Errorf("Reaching the T slot should need a spin, was %s", path.Kind)
+	}
+
+	config := DefaultGameConfig(1)
+	config.Board = tsdBoard()
+	config.Randomizer = &fixedRandomizer{kinds: []string{"T"}}
+	config.Gravity = 1000
+	config.LockDelay = 1000
+	g := newTestGame(t, config)
+
+	var result *ActionResult
+	for _, a := range path.Actions {
+		result = g.Apply(a)
+	}
+	if result.Lock == nil || result.Lock.Row != 3 || result.Lock.Col != 3 || result.Lock.Lines != 2 {
+		t.Errorf("Following the path should lock in the slot, got %+v", result.Lock)
+	}
+}
+
+func TestFindPathUnreachable(t *testing.T) {
+	board, _ := StringArrayToBoard([]string{
+		"|          |",
+		"|##########|",
+		"|          |",
+		"|          |",
+	})
+	state, row, col := SRS.Spawn("O", 10)
+
+	if _, err := FindPath(SRS, board, "O", state, row, col, 0, 3, 4); err == nil {
+		t.Error("A target sealed under a full row should be unreachable")
+	}
+	if _, err := FindPath(SRS, board, "O", state, row, col, 0, 2, 4); err == nil {
+		t.Error("A target that does not rest on anything should be rejected")
+	}
+	if _, err := FindPath(SRS, board, "O", state, row, col, 7, 3, 4); err == nil {
+		t.Error("A missing orientation should be rejected")
+	}
+	if _, err := FindPath(SRS, board, "X", state, row, col, 0, 3, 4); err == nil {
+		t.Error("An unknown kind should be rejected")
+	}
+}
@@ -0,0 +1,306 @@
+package tetris
+
+// A place a tetromino can lock, as found by FindPlacements.
+type Placement struct {
+	Kind   string
+	Orient int
+	Row    int
+	Col    int
+
+	// The board blocks, as (row, col), that the tetromino covers, top to
+	// bottom and left to right.
+	Cells [4][2]int
+
+	// The board after the lock, with full lines cleared.
+	Board *Board
+	Lines int
+	Spin  Spin
+
+	// The shortest input sequence from the starting position, ending with a
+	// HardDrop.
+	Path []Action
+}
+
+// Finds every place a tetromino of the given kind can lock when it starts in
+// the given orientation at (row, col) on the board.  All positions reachable
+// by shifting, quarter turns (with kicks) and soft drops are searched breadth
+// first and each lock is returned once, together with the shortest path to
+// it.  Locks covering the same blocks are the same placement whatever the
+// orientation, so symmetric pieces (O, I, S and Z) are not repeated.  A lock
+// that can be reached both with and without a spin is returned once, with
+// the best spin and the shortest path that gets it.  Locks that would leave
+// blocks above the board are not returned.
+func FindPlacements(rs RotationSystem, b *Board, kind string, orient, row, col int) []*Placement {
+	s := newPlacementSearch(rs, b, kind, false)
+	if s == nil {
+		return nil
+	}
+
+	placements := make([]*Placement, 0)
+	found := make(map[[4][2]int]int) // index of the placement covering the cells
+
+	// Most positions drop to a lock already seen, which is cheaper to spot
+	// by where it lands than by the blocks it covers.
+	dropped := make([]bool, len(s.landed)*3)
+	s.run(orient, row, col, func(n int) bool {
+		node := s.nodes[n]
+		row, spin := s.drop(n)
+		i := s.position(node.orient, row, node.col)*3 + int(spin)
+		if dropped[i] {
+			return true
+		}
+		dropped[i] = true
+
+		cells, ok := s.cells(node.orient, row, node.col)
+		if !ok {
+			return true
+		}
+		// Nodes come in order of path length, so the first path to a lock
+		// is the shortest, and a later one only replaces it for a better
+		// spin.
+		if i, ok := found[cells]; !ok {
+			found[cells] = len(placements)
+			placements = append(placements, s.placement(n, row, cells, spin))
+		} else if spin > placements[i].Spin {
+			placements[i] = s.placement(n, row, cells, spin)
+		}
+		return true
+	})
+
+	return placements
+}
+
+// The actions tried from every position, in the order they are tried.
+var searchActions = [...]Action{MoveLeft, MoveRight, RotateCW, RotateCCW, SoftDrop}
+
+// How far above the board a search follows a tetromino kicked upwards.
+const searchMargin = 4
+
+// A breadth first search over the positions of a tetromino on a board.
+type placementSearch struct {
+	rs     RotationSystem
+	b      *Board
+	kind   string
+	tets   []*Tetromino // the tetromino in each of its states
+	pr, pc int
+	nodes  []searchNode
+
+	// The node index of each position, or -1 if it has not been reached.
+	// Positions are kept apart by whether they were arrived at by a rotation,
+	// so that a spin into a place is still found when a slide reaches it
+	// first, and, when classes is set, by the kind of path taken to them.
+	seen    []int32
+	classes bool
+
+	// The row each position lands on when dropped, or -1 if not yet found.
+	landed []int32
+}
+
+// A position reached by the search and how it was reached.
+type searchNode struct {
+	orient, row, col int
+	parent           int32 // index of the previous node, -1 at the start
+	action           Action
+	kick             int
+	class            PathKind
+}
+
+// Creates a search for the given kind of tetromino.  With classes set the
+// search finds the shortest path of each PathKind to every position rather
+// than only the shortest path.
+func newPlacementSearch(rs RotationSystem, b *Board, kind string, classes bool) *placementSearch {
+	states := rs.NumStates(kind)
+	if states < 1 {
+		return nil
+	}
+
+	s := &placementSearch{rs: rs, b: b, kind: kind, classes: classes}
+	for state := 0; state < states; state++ {
+		tet, _ := NewTetrominoFor(rs, kind, state)
+		s.tets = append(s.tets, tet)
+	}
+	s.pr, s.pc = rs.Pivot(kind)
+	s.landed = make([]int32, states*(b.Height()+2*searchMargin)*(b.Width()+2*searchMargin))
+	for i := range s.landed {
+		s.landed[i] = -1
+	}
+
+	layers := 2
+	if classes {
+		layers *= int(numPathKinds)
+	}
+	s.seen = make([]int32, layers*len(s.landed))
+	for i := range s.seen {
+		s.seen[i] = -1
+	}
+	s.nodes = make([]searchNode, 0, len(s.landed))
+	return s
+}
+
+// Returns a number identifying a position, from 0 up to the length of landed,
+// or -1 if it is outside the area searched.
+func (s *placementSearch) position(orient, row, col int) int {
+	h, w := s.b.Height()+2*searchMargin, s.b.Width()+2*searchMargin
+	row, col = row+searchMargin, col+searchMargin
+	if row < 0 || row >= h || col < 0 || col >= w {
+		return -1
+	}
+	return (orient*h+row)*w + col
+}
+
+// Returns the index into seen for a node, or -1 if it is outside the area
+// searched.
+func (s *placementSearch) index(n searchNode) int {
+	i := s.position(n.orient, n.row, n.col)
+	if i < 0 {
+		return -1
+	}
+	layer := int(n.class) * 2
+	if isRotation(n.action) {
+		layer++
+	}
+	return layer*len(s.landed) + i
+}
+
+func (s *placementSearch) fits(orient, row, col int) bool {
+	return fitsAt(s.b, s.tets[orient], row-s.pr, col-s.pc)
+}
+
+// Visits positions breadth first from the start, calling visit with the index
+// of each node as it is reached.  The search stops early if visit returns
+// false.
+func (s *placementSearch) run(orient, row, col int, visit func(int) bool) {
+	if !s.fits(orient, row, col) {
+		return
+	}
+	s.add(searchNode{orient, row, col, -1, HardDrop, -1, PathHardDrop})
+
+	states := len(s.tets)
+	for n := 0; n < len(s.nodes); n++ {
+		if !visit(n) {
+			return
+		}
+
+		cur := s.nodes[n]
+		for _, a := range searchActions {
+			next := searchNode{cur.orient, cur.row, cur.col, int32(n), a, -1, cur.class}
+			switch a {
+			case MoveLeft:
+				next.col--
+			case MoveRight:
+				next.col++
+			case SoftDrop:
+				next.row++
+			case RotateCW, RotateCCW:
+				dir := 1
+				if a == RotateCCW {
+					dir = -1
+				}
+				next.orient = ((cur.orient+dir)%states + states) % states
+				if next.orient == cur.orient {
+					continue
+				}
+				r, c, kick, ok := kickInto(s.rs, s.b, cur.orient, s.tets[next.orient], cur.row, cur.col)
+				if !ok {
+					continue
+				}
+				next.row, next.col, next.kick = r, c, kick
+			}
+
+			if a != RotateCW && a != RotateCCW && !s.fits(next.orient, next.row, next.col) {
+				continue
+			}
+			if s.classes {
+				next.class = cur.class.after(a)
+			}
+			s.add(next)
+		}
+	}
+}
+
+// Records a node unless its position has already been reached.
+func (s *placementSearch) add(n searchNode) {
+	i := s.index(n)
+	if i < 0 || s.seen[i] >= 0 {
+		return
+	}
+	s.seen[i] = int32(len(s.nodes))
+	s.nodes = append(s.nodes, n)
+}
+
+// Returns the row a tetromino at the given position lands on when dropped.
+// Every position passed on the way down is remembered to land there too.
+func (s *placementSearch) landing(orient, row, col int) int {
+	land := row
+	for s.landed[s.position(orient, land, col)] < 0 && s.fits(orient, land+1, col) {
+		land++
+	}
+	if l := s.landed[s.position(orient, land, col)]; l >= 0 {
+		land = int(l)
+	}
+	for r := row; r <= land; r++ {
+		s.landed[s.position(orient, r, col)] = int32(land)
+	}
+	return land
+}
+
+// Returns the inputs leading from the start to a node.
+func (s *placementSearch) path(n int) []Action {
+	var path []Action
+	for ; s.nodes[n].parent >= 0; n = int(s.nodes[n].parent) {
+		path = append(path, s.nodes[n].action)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// Finds where a node locks when hard dropped, returning the row and the
+// spin.
+func (s *placementSearch) drop(n int) (int, Spin) {
+	node := s.nodes[n]
+	row := s.landing(node.orient, node.row, node.col)
+	if row != node.row {
+		return row, SpinNone
+	}
+	return row, DetectSpin(s.rs, s.b, s.tets[node.orient], row, node.col, node.action, node.kick, false)
+}
+
+// Builds the placement made by hard dropping from a node to the given row.
+func (s *placementSearch) placement(n, row int, cells [4][2]int, spin Spin) *Placement {
+	node := s.nodes[n]
+	p := &Placement{
+		Kind:   s.kind,
+		Orient: node.orient,
+		Row:    row,
+		Col:    node.col,
+		Cells:  cells,
+		Spin:   spin,
+	}
+	p.Board, _ = Place(s.rs, s.b, s.tets[node.orient], row, node.col)
+	p.Lines = ClearFullLines(p.Board)
+	p.Path = append(s.path(n), HardDrop)
+	return p
+}
+
+// Returns the board blocks covered by the tetromino at the given position, top
+// to bottom and left to right, and false if any lie above the board.
+func (s *placementSearch) cells(orient, row, col int) ([4][2]int, bool) {
+	var cells [4][2]int
+	cell := 0
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			if !s.tets[orient].Data()[i][j] {
+				continue
+			}
+			r := row - s.pr + i
+			if r < 0 {
+				return cells, false
+			}
+			cells[cell] = [2]int{r, col - s.pc + j}
+			cell++
+		}
+	}
+	return cells, true
+}
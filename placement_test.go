@@ -0,0 +1,152 @@
+package tetris
+
+import (
+	"testing"
+)
+
+func findSpawned(t *testing.T, b *Board, kind string) []*Placement {
+	state, row, col := SRS.Spawn(kind, b.Width())
+	placements := FindPlacements(SRS, b, kind, state, row, col)
+	if len(placements) == 0 {
+		t.Fatalf("Some placements should be found for %s", kind)
+	}
+	return placements
+}
+
+func TestFindPlacementsOnEmptyBoard(t *testing.T) {
+	board, _ := NewBoard(10, 20)
+	expected := map[string]int{"O": 9, "I": 17, "S": 17, "Z": 17, "T": 34, "J": 34, "L": 34}
+
+	for kind, n := range expected {
+		placements := findSpawned(t, board, kind)
+		if len(placements) != n {
+			t.Errorf("%s should have %d placements on an empty board, had %d", kind, n, len(placements))
+		}
+	}
+}
+
+func TestFindPlacementsAreDistinct(t *testing.T) {
+	board, _ := NewBoard(10, 20)
+	seen := make(map[[4][2]int]bool)
+	for _, p := range findSpawned(t, board, "S") {
+		if seen[p.Cells] {
+			t.Errorf("Placement covering %v should only be found once", p.Cells)
+		}
+		seen[p.Cells] = true
+	}
+}
+
+func TestFindPlacementsTucksUnderOverhang(t *testing.T) {
+	board, _ := StringArrayToBoard([]string{
+		"|          |",
+		"|          |",
+		"|          |",
+		"|     #####|",
+		"|          |",
+		"|          |",
+	})
+
+	var tucked *Placement
+	for _, p := range findSpawned(t, board, "O") {
+		if p.Cells[0] == [2]int{4, 8} {
+			tucked = p
+		}
+	}
+	if tucked == nil {
+		t.Fatal("An O should slide under the overhang to the right wall")
+	}
+	if tucked.Path[len(tucked.Path)-1] != HardDrop {
+		t.Error("The path should end with a hard drop")
+	}
+
+	drops := 0
+	for _, a := range tucked.Path {
+		if a == SoftDrop {
+			drops++
+		}
+	}
+	if drops == 0 {
+		t.Error("Reaching under the overhang should take soft drops")
+	}
+}
+
+func TestFindPlacementsClearsLines(t *testing.T) {
+	board, _ := StringArrayToBoard([]string{
+		"|          |",
+		"|          |",
+		"|          |",
+		"|#### #####|",
+		"|#### #####|",
+	})
+
+	for _, p := range findSpawned(t, board, "I") {
+		if p.Cells[0] == [2]int{1, 4} {
+			if p.Lines != 2 {
+				t.Errorf("A vertical I in the well should clear 2 lines, cleared %d", p.Lines)
+			}
+			return
+		}
+	}
+	t.Error("A vertical I should fit the well")
+}
+
+func TestFindPlacementsFindsTSpin(t *testing.T) {
+	for _, p := range findSpawned(t, tsdBoard(), "T") {
+		if p.Spin == SpinFull && p.Lines == 2 {
+			return
+		}
+	}
+	t.Error("A T-spin double should be found")
+}
+
+func TestFindPlacementsReturnsEachLockOnce(t *testing.T) {
+	seen := make(map[[4][2]int]bool)
+	spun := false
+	for _, p := range findSpawned(t, tsdBoard(), "T") {
+		if seen[p.Cells] {
+			t.Errorf("The lock covering %v should be returned once", p.Cells)
+		}
+		seen[p.Cells] = true
+		spun = spun || (p.Spin == SpinFull && p.Lines == 2)
+	}
+	if !spun {
+		t.Error("A lock reached with and without a spin should keep the spin")
+	}
+}
+
+func TestFindPlacementsBlockedStart(t *testing.T) {
+	board, _ := NewBoard(10, 4)
+	board.SetRow(0, true)
+	if placements := FindPlacements(SRS, board, "T", 0, 0, 4); len(placements) != 0 {
+		t.Errorf("A blocked start should have no placements, had %d", len(placements))
+	}
+	if placements := FindPlacements(SRS, board, "X", 0, 0, 4); len(placements) != 0 {
+		t.Error("An unknown kind should have no placements")
+	}
+}
+
+func TestFindPlacementsPathsReplay(t *testing.T) {
+	board := tsdBoard()
+	for _, p := range findSpawned(t, board, "T") {
+		config := DefaultGameConfig(1)
+		config.Board = board
+		config.Randomizer = &fixedRandomizer{kinds: []string{"T"}}
+		config.Gravity = 1000
+		config.LockDelay = 1000
+		g := newTestGame(t, config)
+
+		var result *ActionResult
+		for _, a := range p.Path {
+			result = g.Apply(a)
+		}
+		if result.Lock == nil {
+			t.Fatalf("Path %v should end in a lock", p.Path)
+		}
+		if result.Lock.Spin != p.Spin {
+			t.Errorf("Path %v should lock with spin %s, was %s", p.Path, p.Spin, result.Lock.Spin)
+		}
+		if !g.Board().EqualCells(p.Board) {
+			t.Errorf("Path %v should reach the placement's board\n%s", p.Path, g.Board())
+		}
+	}
+}
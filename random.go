@@ -0,0 +1,362 @@
+package tetris
+
+import (
+	"fmt"
+	"math"
+)
+
+// Produces the sequence of tetromino kinds a game is played with.  Every
+// randomizer is created from an explicit seed and two randomizers created
+// with the same seed produce the same sequence.
+type Randomizer interface {
+	// Returns a short name identifying the algorithm, e.g. "7bag".
+	Name() string
+
+	// Returns the kind of the next tetromino in the sequence.
+	Next() string
+
+	// Returns an independent randomizer that continues the sequence exactly
+	// as this one would.
+	Clone() Randomizer
+
+	// Returns the chance of each kind being the next one dealt.
+	Distribution() Distribution
+
+	// Moves the sequence on as if the given kind had just been dealt,
+	// without drawing on the seed.  Searches use it on clones to follow each
+	// kind that could come next.
+	Advance(kind string)
+}
+
+// The chance of each kind being dealt, indexed like Kinds.
+type Distribution [len(Kinds)]float64
+
+// Returns the chance of the given kind, zero for unknown kinds.
+func (d Distribution) Of(kind string) float64 {
+	if i := KindIndex(kind); i >= 0 {
+		return d[i]
+	}
+	return 0
+}
+
+// Returns the position of the kind in Kinds, or -1 if it is not one.
+func KindIndex(kind string) int {
+	for i, k := range Kinds {
+		if k == kind {
+			return i
+		}
+	}
+	return -1
+}
+
+func uniform() Distribution {
+	var d Distribution
+	for i := range d {
+		d[i] = 1 / float64(len(Kinds))
+	}
+	return d
+}
+
+// A small, fast pseudo-random generator (SplitMix64) whose whole state is a
+// single word so that randomizers can be cloned and their sequences are the
+// same on every platform and Go release.
+type rng struct {
+	state uint64
+}
+
+func newRNG(seed int64) rng {
+	return rng{uint64(seed)}
+}
+
+func (r *rng) next() uint64 {
+	r.state += 0x9e3779b97f4a7c15
+	z := r.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Returns a uniformly distributed value in [0, n).
+func (r *rng) intn(n int) int {
+	// Rejection sampling avoids the bias of a plain modulo.
+	max := ^uint64(0) - ^uint64(0)%uint64(n)
+	for {
+		v := r.next()
+		if v < max {
+			return int(v % uint64(n))
+		}
+	}
+}
+
+// Picks every kind independently with equal probability.
+type pureRandomizer struct {
+	rng rng
+}
+
+func NewPureRandomizer(seed int64) Randomizer {
+	return &pureRandomizer{newRNG(seed)}
+}
+
+func (r *pureRandomizer) Name() string {
+	return "random"
+}
+
+func (r *pureRandomizer) Next() string {
+	return Kinds[r.rng.intn(len(Kinds))]
+}
+
+func (r *pureRandomizer) Clone() Randomizer {
+	c := *r
+	return &c
+}
+
+func (r *pureRandomizer) Distribution() Distribution {
+	return uniform()
+}
+
+func (r *pureRandomizer) Advance(kind string) {
+}
+
+// Deals kinds out of a shuffled bag holding some number of copies of every
+// kind, refilling the bag once it is empty.
+type bagRandomizer struct {
+	rng    rng
+	copies int
+	bag    []string
+}
+
+// Creates a 7-bag randomizer: every run of seven pieces holds each kind once.
+func NewBagRandomizer(seed int64) Randomizer {
+	return &bagRandomizer{rng: newRNG(seed), copies: 1}
+}
+
+// Creates a 14-bag randomizer: every run of fourteen pieces holds each kind
+// twice.
+func NewDoubleBagRandomizer(seed int64) Randomizer {
+	return &bagRandomizer{rng: newRNG(seed), copies: 2}
+}
+
+func (r *bagRandomizer) Name() string {
+	if r.copies == 2 {
+		return "14bag"
+	}
+	return "7bag"
+}
+
+func (r *bagRandomizer) Next() string {
+	r.fill()
+	return r.take(r.rng.intn(len(r.bag)))
+}
+
+func (r *bagRandomizer) Clone() Randomizer {
+	c := *r
+	c.bag = append([]string(nil), r.bag...)
+	return &c
+}
+
+// Kinds are as likely as the copies of them left in the bag.
+func (r *bagRandomizer) Distribution() Distribution {
+	if len(r.bag) == 0 {
+		return uniform()
+	}
+
+	var d Distribution
+	for _, kind := range r.bag {
+		d[KindIndex(kind)] += 1 / float64(len(r.bag))
+	}
+	return d
+}
+
+func (r *bagRandomizer) Advance(kind string) {
+	r.fill()
+	for i, k := range r.bag {
+		if k == kind {
+			r.take(i)
+			return
+		}
+	}
+}
+
+// Refills the bag if it is empty.
+func (r *bagRandomizer) fill() {
+	if len(r.bag) == 0 {
+		for i := 0; i < r.copies; i++ {
+			r.bag = append(r.bag, Kinds[:]...)
+		}
+	}
+}
+
+// Removes and returns the kind at the given position in the bag.
+func (r *bagRandomizer) take(i int) string {
+	kind := r.bag[i]
+	r.bag[i] = r.bag[len(r.bag)-1]
+	r.bag = r.bag[:len(r.bag)-1]
+	return kind
+}
+
+// The randomizer of Tetris The Grand Master: a kind found among the last four
+// dealt is rerolled up to three times.  The history starts full of Z and the
+// first piece is never S, Z or O.
+type tgmRandomizer struct {
+	rng     rng
+	history [4]string
+	first   bool
+}
+
+const tgmRolls = 4
+
+func NewTGMRandomizer(seed int64) Randomizer {
+	return &tgmRandomizer{newRNG(seed), [4]string{"Z", "Z", "Z", "Z"}, true}
+}
+
+func (r *tgmRandomizer) Name() string {
+	return "tgm"
+}
+
+func (r *tgmRandomizer) Next() string {
+	var kind string
+	if r.first {
+		r.first = false
+		starts := [...]string{"I", "T", "J", "L"}
+		kind = starts[r.rng.intn(len(starts))]
+	} else {
+		for roll := 0; roll < tgmRolls; roll++ {
+			kind = Kinds[r.rng.intn(len(Kinds))]
+			if !r.inHistory(kind) {
+				break
+			}
+		}
+	}
+
+	r.Advance(kind)
+	return kind
+}
+
+func (r *tgmRandomizer) inHistory(kind string) bool {
+	for _, h := range r.history {
+		if h == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *tgmRandomizer) Clone() Randomizer {
+	c := *r
+	return &c
+}
+
+// A kind missing from the history is dealt by the first roll that comes up
+// with it, and one in the history only by the last roll, once every roll
+// before it has come up with kinds in the history.
+func (r *tgmRandomizer) Distribution() Distribution {
+	var d Distribution
+	if r.first {
+		for _, kind := range [...]string{"I", "T", "J", "L"} {
+			d[KindIndex(kind)] = 0.25
+		}
+		return d
+	}
+
+	seen := 0
+	for _, kind := range Kinds {
+		if r.inHistory(kind) {
+			seen++
+		}
+	}
+	n := float64(len(Kinds))
+	rerolled := float64(seen) / n // chance of a roll having to be rolled again
+
+	missing := 0.0
+	for roll := 0; roll < tgmRolls; roll++ {
+		missing += math.Pow(rerolled, float64(roll)) / n
+	}
+	for i, kind := range Kinds {
+		if r.inHistory(kind) {
+			d[i] = math.Pow(rerolled, tgmRolls-1) / n
+		} else {
+			d[i] = missing
+		}
+	}
+	return d
+}
+
+func (r *tgmRandomizer) Advance(kind string) {
+	r.first = false
+	copy(r.history[1:], r.history[:3])
+	r.history[0] = kind
+}
+
+// The randomizer of NES Tetris: one of eight outcomes is drawn, seven kinds
+// and a reroll.  Drawing the reroll or the previous kind draws once more from
+// the seven kinds, keeping whatever comes up.
+type nesRandomizer struct {
+	rng  rng
+	prev string
+}
+
+func NewNESRandomizer(seed int64) Randomizer {
+	return &nesRandomizer{rng: newRNG(seed)}
+}
+
+func (r *nesRandomizer) Name() string {
+	return "nes"
+}
+
+func (r *nesRandomizer) Next() string {
+	roll := r.rng.intn(len(Kinds) + 1)
+	if roll == len(Kinds) || Kinds[roll] == r.prev {
+		roll = r.rng.intn(len(Kinds))
+	}
+
+	r.prev = Kinds[roll]
+	return r.prev
+}
+
+func (r *nesRandomizer) Clone() Randomizer {
+	c := *r
+	return &c
+}
+
+// With a previous kind, each other kind comes up 9 times in 56 and the
+// previous one 1 in 28.
+func (r *nesRandomizer) Distribution() Distribution {
+	n := float64(len(Kinds))
+	prev := KindIndex(r.prev)
+	if prev < 0 {
+		return uniform()
+	}
+
+	var d Distribution
+	for i := range d {
+		if i == prev {
+			d[i] = 2 / (n + 1) / n
+		} else {
+			d[i] = 1/(n+1) + 2/(n+1)/n
+		}
+	}
+	return d
+}
+
+func (r *nesRandomizer) Advance(kind string) {
+	r.prev = kind
+}
+
+// The built-in randomizers by name.
+var randomizers = map[string]func(int64) Randomizer{
+	"random": NewPureRandomizer,
+	"7bag":   NewBagRandomizer,
+	"14bag":  NewDoubleBagRandomizer,
+	"tgm":    NewTGMRandomizer,
+	"nes":    NewNESRandomizer,
+}
+
+// Creates the built-in randomizer with the given name, as returned by its
+// Name method, from a seed.
+func NewRandomizer(name string, seed int64) (Randomizer, error) {
+	create, ok := randomizers[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown randomizer %q!", name)
+	}
+	return create(seed), nil
+}
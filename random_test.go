@@ -0,0 +1,257 @@
+package tetris
+
+import (
+	"math"
+	"testing"
+)
+
+var randomizerConstructors = map[string]func(int64) Randomizer{
+	"random": NewPureRandomizer,
+	"7bag":   NewBagRandomizer,
+	"14bag":  NewDoubleBagRandomizer,
+	"tgm":    NewTGMRandomizer,
+	"nes":    NewNESRandomizer,
+}
+
+func deal(r Randomizer, n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = r.Next()
+	}
+	return out
+}
+
+func sameKinds(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRandomizerNames(t *testing.T) {
+	for name, create := range randomizerConstructors {
+		if create(1).Name() != name {
+			t.Errorf("Randomizer should be named %s, was %s", name, create(1).Name())
+		}
+	}
+}
+
+func TestNewRandomizerByName(t *testing.T) {
+	for name, create := range randomizerConstructors {
+		r, err := NewRandomizer(name, 7)
+		if err != nil {
+			t.Errorf("%s should be created by name: %s", name, err)
+			continue
+		}
+		if !sameKinds(deal(r, 30), deal(create(7), 30)) {
+			t.Errorf("%s created by name should deal like its constructor", name)
+		}
+	}
+
+	if _, err := NewRandomizer("fixed", 7); err == nil {
+		t.Error("An unknown randomizer should be an error")
+	}
+}
+
+func TestRandomizersAreDeterministic(t *testing.T) {
+	for name, create := range randomizerConstructors {
+		a := deal(create(42), 200)
+		b := deal(create(42), 200)
+		if !sameKinds(a, b) {
+			t.Errorf("%s should deal the same sequence for the same seed", name)
+		}
+
+		c := deal(create(43), 200)
+		if sameKinds(a, c) {
+			t.Errorf("%s should deal different sequences for different seeds", name)
+		}
+	}
+}
+
+func TestRandomizersDealValidKinds(t *testing.T) {
+	for name, create := range randomizerConstructors {
+		for _, kind := range deal(create(7), 200) {
+			if NumTetOrients(kind) < 1 {
+				t.Errorf("%s dealt invalid kind %q", name, kind)
+			}
+		}
+	}
+}
+
+func TestRandomizerCloneContinuesSequence(t *testing.T) {
+	for name, create := range randomizerConstructors {
+		r := create(99)
+		deal(r, 10)
+
+		c := r.Clone()
+		if !sameKinds(deal(r, 50), deal(c, 50)) {
+			t.Errorf("%s clone should continue the same sequence", name)
+		}
+	}
+}
+
+func TestBagDealsEveryKindPerBag(t *testing.T) {
+	for copies, r := range map[int]Randomizer{1: NewBagRandomizer(5), 2: NewDoubleBagRandomizer(5)} {
+		size := len(Kinds) * copies
+		for bag := 0; bag < 20; bag++ {
+			counts := make(map[string]int)
+			for _, kind := range deal(r, size) {
+				counts[kind]++
+			}
+			for _, kind := range Kinds {
+				if counts[kind] != copies {
+					t.Errorf("Bag of %d should hold %s %d times, held it %d times", size, kind, copies, counts[kind])
+				}
+			}
+		}
+	}
+}
+
+func TestTGMFirstPieceIsNeverSZO(t *testing.T) {
+	for seed := int64(0); seed < 100; seed++ {
+		switch NewTGMRandomizer(seed).Next() {
+		case "S", "Z", "O":
+			t.Errorf("Seed %d dealt an S, Z or O first", seed)
+		}
+	}
+}
+
+func TestTGMAvoidsRecentKinds(t *testing.T) {
+	kinds := deal(NewTGMRandomizer(3), 7000)
+	repeats := 0
+	for i := 1; i < len(kinds); i++ {
+		if kinds[i] == kinds[i-1] {
+			repeats++
+		}
+	}
+
+	// Uniform picks would repeat about 1000 times, the history should cut
+	// that to about 200.
+	if repeats > 400 {
+		t.Errorf("TGM randomizer repeated kinds %d times", repeats)
+	}
+}
+
+func TestNESRarelyRepeats(t *testing.T) {
+	kinds := deal(NewNESRandomizer(3), 5600)
+	repeats := 0
+	for i := 1; i < len(kinds); i++ {
+		if kinds[i] == kinds[i-1] {
+			repeats++
+		}
+	}
+
+	// A repeat happens with probability 1/28, i.e. about 200 times.
+	if repeats < 100 || repeats > 300 {
+		t.Errorf("NES randomizer repeated kinds %d times, expected about 200", repeats)
+	}
+}
+
+func TestDistributionsCoverDealtKinds(t *testing.T) {
+	for name, create := range randomizerConstructors {
+		r := create(5)
+		for i := 0; i < 300; i++ {
+			d := r.Distribution()
+			sum := 0.0
+			for _, p := range d {
+				sum += p
+			}
+			if math.Abs(sum-1) > 1e-9 {
+				t.Fatalf("%s distribution should sum to 1, was %v", name, sum)
+			}
+
+			kind := r.Next()
+			if d.Of(kind) <= 0 {
+				t.Fatalf("%s dealt %s which had no chance", name, kind)
+			}
+		}
+	}
+}
+
+func TestBagDistributionHoldsRemainder(t *testing.T) {
+	r := NewBagRandomizer(3)
+	dealt := deal(r, 5)
+
+	d := r.Distribution()
+	for _, kind := range Kinds {
+		expected := 0.5
+		for _, k := range dealt {
+			if k == kind {
+				expected = 0
+			}
+		}
+		if d.Of(kind) != expected {
+			t.Errorf("%s should have chance %v with two kinds left, had %v", kind, expected, d.Of(kind))
+		}
+	}
+
+	remaining := r.Clone()
+	for _, kind := range Kinds {
+		if d.Of(kind) > 0 {
+			remaining.Advance(kind)
+			break
+		}
+	}
+	for _, p := range remaining.Distribution() {
+		if p != 0 && p != 1 {
+			t.Error("With one kind left it should be certain")
+		}
+	}
+	remaining.Next()
+	if remaining.Distribution().Of("T") != 1.0/7 {
+		t.Error("A refilled bag should be uniform")
+	}
+}
+
+func TestNESDistribution(t *testing.T) {
+	r := NewNESRandomizer(1)
+	if r.Distribution().Of("T") != 1.0/7 {
+		t.Error("The first NES piece should be uniform")
+	}
+
+	r.Advance("T")
+	d := r.Distribution()
+	if math.Abs(d.Of("T")-1.0/28) > 1e-12 {
+		t.Errorf("Repeating the previous kind should have chance 1/28, had %v", d.Of("T"))
+	}
+	if math.Abs(d.Of("I")-9.0/56) > 1e-12 {
+		t.Errorf("Other kinds should have chance 9/56, had %v", d.Of("I"))
+	}
+}
+
+// Compares a randomizer's distribution after the given kinds with how often
+// each kind comes next across many seeds.
+func checkDistribution(t *testing.T, create func(int64) Randomizer, history []string) {
+	counts := make(map[string]int)
+	const trials = 20000
+	var d Distribution
+	for seed := int64(0); seed < trials; seed++ {
+		r := create(seed)
+		for _, kind := range history {
+			r.Advance(kind)
+		}
+		d = r.Distribution()
+		counts[r.Next()]++
+	}
+
+	for _, kind := range Kinds {
+		seen := float64(counts[kind]) / trials
+		if math.Abs(seen-d.Of(kind)) > 0.015 {
+			t.Errorf("%s after %v should come up %.3f of the time, came up %.3f",
+				kind, history, d.Of(kind), seen)
+		}
+	}
+}
+
+func TestDistributionsMatchDealing(t *testing.T) {
+	checkDistribution(t, NewTGMRandomizer, nil)
+	checkDistribution(t, NewTGMRandomizer, []string{"I"})
+	checkDistribution(t, NewTGMRandomizer, []string{"I", "T", "S", "I"})
+	checkDistribution(t, NewNESRandomizer, []string{"L"})
+	checkDistribution(t, NewDoubleBagRandomizer, []string{"O", "O", "S"})
+}
@@ -0,0 +1,135 @@
+package replay
+
+import (
+	"fmt"
+
+	"github.com/paulcoyle/tetris"
+)
+
+// The default number of frames between the snapshots a Player seeks from: ten
+// seconds at 60 frames per second.
+const DefaultInterval = 600
+
+// Plays a replay back through the engine.  The game is always at a frame with
+// that frame's inputs applied.
+type Player struct {
+	replay    *Replay
+	game      *tetris.Game
+	next      int // the index of the next input to apply
+	interval  int
+	snapshots []snapshot // one every interval frames, from frame 0, as reached
+}
+
+// A copy of the game at a frame that is a multiple of the interval.
+type snapshot struct {
+	game *tetris.Game
+	next int
+}
+
+// Starts playing a replay at frame 0, taking a snapshot to seek from every
+// interval frames, or DefaultInterval if it is less than 1.
+func NewPlayer(replay *Replay, interval int) (*Player, error) {
+	if err := replay.check(); err != nil {
+		return nil, err
+	}
+	config, err := replay.Rules.GameConfig()
+	if err != nil {
+		return nil, err
+	}
+	game, err := tetris.NewGame(config)
+	if err != nil {
+		return nil, err
+	}
+	if interval < 1 {
+		interval = DefaultInterval
+	}
+
+	p := &Player{replay: replay, game: game, interval: interval}
+	p.arrive()
+	return p, nil
+}
+
+// Returns the game as of the current frame.  It must not be changed.
+func (p *Player) Game() *tetris.Game {
+	return p.game
+}
+
+func (p *Player) Frame() int {
+	return p.game.Frame()
+}
+
+// Advances one frame.  Returns false, leaving the game alone, once the last
+// frame of the replay is reached or if the game has ended early.
+func (p *Player) Step() bool {
+	frame := p.game.Frame()
+	if frame >= p.replay.Frames {
+		return false
+	}
+	p.game.Tick()
+	if p.game.Frame() == frame {
+		return false
+	}
+	p.arrive()
+	return true
+}
+
+// Moves to the given frame, going back to the nearest snapshot if it is
+// behind the current one.
+func (p *Player) Seek(frame int) error {
+	if frame < 0 || frame > p.replay.Frames {
+		return fmt.Errorf("Frame %d is outside the replay's 0 to %d!", frame, p.replay.Frames)
+	}
+
+	i := frame / p.interval
+	if i >= len(p.snapshots) {
+		i = len(p.snapshots) - 1
+	}
+	if s := p.snapshots[i]; frame < p.game.Frame() || s.game.Frame() > p.game.Frame() {
+		p.game, p.next = s.game.Clone(), s.next
+	}
+
+	for p.game.Frame() < frame {
+		if !p.Step() {
+			return fmt.Errorf("Replay ended at frame %d before reaching %d!", p.game.Frame(), frame)
+		}
+	}
+	return nil
+}
+
+// Plays to the end of the replay and checks the game finished as recorded.
+func (p *Player) Verify() error {
+	if err := p.Seek(p.replay.Frames); err != nil {
+		return err
+	}
+
+	r, g := p.replay, p.game
+	if !g.Board().EqualCells(r.FinalBoard) {
+		return fmt.Errorf("Final board does not match the recording:\n%sexpected:\n%s", g.Board(), r.FinalBoard)
+	}
+	if g.Scorer().Score() != r.Score || g.Scorer().Lines() != r.Lines {
+		return fmt.Errorf("Final score %d and lines %d do not match the recorded %d and %d!",
+			g.Scorer().Score(), g.Scorer().Lines(), r.Score, r.Lines)
+	}
+	return nil
+}
+
+// Applies the inputs of the frame just reached and snapshots it if due.
+func (p *Player) arrive() {
+	frame := p.game.Frame()
+	for p.next < len(p.replay.Inputs) && p.replay.Inputs[p.next].Frame == frame {
+		p.game.Apply(p.replay.Inputs[p.next].Action)
+		p.next++
+	}
+	if frame%p.interval == 0 && frame/p.interval == len(p.snapshots) {
+		p.snapshots = append(p.snapshots, snapshot{p.game.Clone(), p.next})
+	}
+}
+
+// Plays a replay through and checks it finishes as recorded.
+func Verify(replay *Replay) error {
+	p, err := NewPlayer(replay, DefaultInterval)
+	if err != nil {
+		return err
+	}
+	return p.Verify()
+}
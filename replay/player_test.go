@@ -0,0 +1,95 @@
+package replay
+
+import (
+	"testing"
+
+	"github.com/paulcoyle/tetris"
+)
+
+func newTestPlayer(t *testing.T, r *Replay, interval int) *Player {
+	p, err := NewPlayer(r, interval)
+	if err != nil {
+		t.Fatalf("Player should be created: %s", err)
+	}
+	return p
+}
+
+func sameGame(a, b *tetris.Game) bool {
+	ta, ra, ca := a.Active()
+	tb, rb, cb := b.Active()
+	if (ta == nil) != (tb == nil) || ra != rb || ca != cb || (ta != nil && ta.Orient() != tb.Orient()) {
+		return false
+	}
+	return a.Frame() == b.Frame() && a.Board().EqualCells(b.Board()) && a.Scorer().Score() == b.Scorer().Score()
+}
+
+func TestPlayerVerifies(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		r := record(t, seed, 2000)
+		if err := Verify(r); err != nil {
+			t.Errorf("Seed %d should play back as recorded: %s", seed, err)
+		}
+	}
+}
+
+func TestPlayerDetectsMismatch(t *testing.T) {
+	r := record(t, 3, 1000)
+	r.FinalBoard = r.FinalBoard.Copy()
+	r.FinalBoard.SetBlock(0, 0, true)
+	if err := Verify(r); err == nil {
+		t.Error("A different final board should fail to verify")
+	}
+
+	r = record(t, 3, 1000)
+	r.Rules.Seed++
+	if err := Verify(r); err == nil {
+		t.Error("A replay dealt other pieces should fail to verify")
+	}
+}
+
+func TestPlayerSeeks(t *testing.T) {
+	r := record(t, 4, 1500)
+	if r.Frames < 400 {
+		t.Fatalf("The game should last long enough to seek around, lasted %d frames", r.Frames)
+	}
+	p := newTestPlayer(t, r, 100)
+	sequential := newTestPlayer(t, r, 100)
+
+	n := r.Frames
+	for _, frame := range []int{n / 2, 20, n - 1, 0, n / 3, n/3 + 1, n * 4 / 5, n} {
+		if err := p.Seek(frame); err != nil {
+			t.Fatalf("Seeking to %d should succeed: %s", frame, err)
+		}
+		fresh := newTestPlayer(t, r, 1000000)
+		fresh.Seek(frame)
+		if !sameGame(p.Game(), fresh.Game()) {
+			t.Errorf("Seeking to %d should reach the same game as playing straight there", frame)
+		}
+	}
+
+	for sequential.Step() {
+	}
+	if !sameGame(sequential.Game(), p.Game()) {
+		t.Error("Stepping to the end should reach the last frame")
+	}
+
+	if err := p.Seek(r.Frames + 1); err == nil {
+		t.Error("Seeking past the end should be an error")
+	}
+}
+
+func TestPlayerSnapshotsAreUnchanged(t *testing.T) {
+	r := record(t, 5, 1000)
+	p := newTestPlayer(t, r, 50)
+	p.Seek(r.Frames)
+	end := p.Game().Clone()
+
+	// Going back and forth through the snapshots must not disturb them.
+	for i := 0; i < 3; i++ {
+		p.Seek(120)
+		p.Seek(r.Frames)
+	}
+	if !sameGame(p.Game(), end) {
+		t.Error("Seeking back and forth should reach the same end")
+	}
+}
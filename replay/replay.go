@@ -0,0 +1,247 @@
+// Package replay records games as the inputs that played them and plays them
+// back frame by frame.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/paulcoyle/tetris"
+)
+
+// The version of the replay format written by Write.
+const Version = 1
+
+// The rules a game was played with, in a form that can be saved.  Rotation
+// systems are named as registered with tetris.RegisterRotationSystem and
+// randomizers as accepted by tetris.NewRandomizer.
+type Rules struct {
+	Width  int           `json:"width"`
+	Height int           `json:"height"`
+	Board  *tetris.Board `json:"board,omitempty"`
+
+	Rotation   string `json:"rotation"`
+	Randomizer string `json:"randomizer"`
+	Seed       int64  `json:"seed"`
+
+	Previews      int  `json:"previews"`
+	Gravity       int  `json:"gravity"`
+	LockDelay     int  `json:"lock_delay"`
+	MoveResets    int  `json:"move_resets"`
+	EntryDelay    int  `json:"entry_delay"`
+	Hold          bool `json:"hold"`
+	UnlimitedHold bool `json:"unlimited_hold"`
+	InitialHold   bool `json:"initial_hold"`
+	AllSpin       bool `json:"all_spin"`
+
+	Scoring tetris.ScoreTable `json:"scoring"`
+	Level   int               `json:"level"`
+}
+
+// Returns the rules of a game configuration whose randomizer was created
+// from the given seed.
+func NewRules(config tetris.GameConfig, seed int64) Rules {
+	r := Rules{
+		Width:         config.Width,
+		Height:        config.Height,
+		Board:         config.Board,
+		Seed:          seed,
+		Previews:      config.Previews,
+		Gravity:       config.Gravity,
+		LockDelay:     config.LockDelay,
+		MoveResets:    config.MoveResets,
+		EntryDelay:    config.EntryDelay,
+		Hold:          config.Hold,
+		UnlimitedHold: config.UnlimitedHold,
+		InitialHold:   config.InitialHold,
+		AllSpin:       config.AllSpin,
+		Scoring:       config.Scoring,
+		Level:         config.Level,
+	}
+	if config.Rotation != nil {
+		r.Rotation = config.Rotation.Name()
+	}
+	if config.Randomizer != nil {
+		r.Randomizer = config.Randomizer.Name()
+	}
+	return r
+}
+
+// Returns the game configuration of the rules, with a fresh randomizer.
+func (r Rules) GameConfig() (tetris.GameConfig, error) {
+	rs, err := tetris.LookupRotationSystem(r.Rotation)
+	if err != nil {
+		return tetris.GameConfig{}, err
+	}
+	random, err := tetris.NewRandomizer(r.Randomizer, r.Seed)
+	if err != nil {
+		return tetris.GameConfig{}, err
+	}
+
+	return tetris.GameConfig{
+		Width:         r.Width,
+		Height:        r.Height,
+		Board:         r.Board,
+		Rotation:      rs,
+		Randomizer:    random,
+		Previews:      r.Previews,
+		Gravity:       r.Gravity,
+		LockDelay:     r.LockDelay,
+		MoveResets:    r.MoveResets,
+		EntryDelay:    r.EntryDelay,
+		Hold:          r.Hold,
+		UnlimitedHold: r.UnlimitedHold,
+		InitialHold:   r.InitialHold,
+		AllSpin:       r.AllSpin,
+		Scoring:       r.Scoring,
+		Level:         r.Level,
+	}, nil
+}
+
+// An action applied at a frame, before the game ticks on to the next one.
+type Input struct {
+	Frame  int           `json:"frame"`
+	Action tetris.Action `json:"action"`
+}
+
+// A recorded game.
+type Replay struct {
+	Rules  Rules   `json:"rules"`
+	Inputs []Input `json:"inputs"` // in the order applied
+
+	// How the game stood when recording finished.
+	Frames     int           `json:"frames"`
+	FinalBoard *tetris.Board `json:"final_board"`
+	Score      int           `json:"score"`
+	Lines      int           `json:"lines"`
+}
+
+// Checks that the replay is complete and its inputs are in order.
+func (r *Replay) check() error {
+	if r.FinalBoard == nil {
+		return fmt.Errorf("Replay has no final board!")
+	}
+	last := 0
+	for i, in := range r.Inputs {
+		if in.Frame < last {
+			return fmt.Errorf("Input %d at frame %d comes after frame %d!", i, in.Frame, last)
+		}
+		last = in.Frame
+	}
+	if last > r.Frames {
+		return fmt.Errorf("Input at frame %d is after the last frame %d!", last, r.Frames)
+	}
+	return nil
+}
+
+// The first line of a replay file, holding the version and a checksum of the
+// JSON that follows.
+const header = "tetris-replay %d sha256:%s\n"
+
+// Writes the replay: a header line with the format version and the SHA-256 of
+// the rest, then the replay as JSON.
+func Write(w io.Writer, r *Replay) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(body)
+	if _, err := fmt.Fprintf(w, header, Version, hex.EncodeToString(sum[:])); err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
+
+// Reads a replay written by Write, checking its version and checksum.
+func Read(rd io.Reader) (*Replay, error) {
+	br := bufio.NewReader(rd)
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("Replay has no header: %s", err)
+	}
+
+	var version int
+	var checksum string
+	if _, err := fmt.Sscanf(line, header, &version, &checksum); err != nil {
+		return nil, fmt.Errorf("Replay header %q is not valid!", strings.TrimSpace(line))
+	}
+	if version != Version {
+		return nil, fmt.Errorf("Replay has version %d, expected %d!", version, Version)
+	}
+
+	body, err := io.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	if hex.EncodeToString(sum[:]) != checksum {
+		return nil, fmt.Errorf("Replay checksum does not match, it is corrupt!")
+	}
+
+	r := &Replay{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(r); err != nil {
+		return nil, fmt.Errorf("Replay is not valid: %s", err)
+	}
+	if err := r.check(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Plays a game and records its inputs.
+type Recorder struct {
+	rules  Rules
+	game   *tetris.Game
+	inputs []Input
+}
+
+// Starts a game with the given rules.
+func NewRecorder(rules Rules) (*Recorder, error) {
+	config, err := rules.GameConfig()
+	if err != nil {
+		return nil, err
+	}
+	game, err := tetris.NewGame(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{rules: rules, game: game}, nil
+}
+
+// Returns the game being recorded.  It must only be changed through the
+// recorder.
+func (r *Recorder) Game() *tetris.Game {
+	return r.game
+}
+
+// Applies an action to the game and records it.
+func (r *Recorder) Apply(a tetris.Action) *tetris.ActionResult {
+	r.inputs = append(r.inputs, Input{r.game.Frame(), a})
+	return r.game.Apply(a)
+}
+
+// Advances the game one frame.
+func (r *Recorder) Tick() *tetris.LockEvent {
+	return r.game.Tick()
+}
+
+// Returns the replay of the game so far.
+func (r *Recorder) Replay() *Replay {
+	return &Replay{
+		Rules:      r.rules,
+		Inputs:     append([]Input(nil), r.inputs...),
+		Frames:     r.game.Frame(),
+		FinalBoard: r.game.Board().Copy(),
+		Score:      r.game.Scorer().Score(),
+		Lines:      r.game.Scorer().Lines(),
+	}
+}
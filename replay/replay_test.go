@@ -0,0 +1,113 @@
+package replay
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/paulcoyle/tetris"
+)
+
+// Records a game of the given number of frames, with inputs chosen at random
+// from the seed.
+func record(t *testing.T, seed int64, frames int) *Replay {
+	rules := NewRules(tetris.DefaultGameConfig(seed), seed)
+	rules.EntryDelay = 6
+	rec, err := NewRecorder(rules)
+	if err != nil {
+		t.Fatalf("Recorder should be created: %s", err)
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	actions := []tetris.Action{tetris.MoveLeft, tetris.MoveRight, tetris.RotateCW, tetris.RotateCCW,
+		tetris.SoftDrop, tetris.HardDrop, tetris.Hold}
+	for i := 0; i < frames && !rec.Game().Over(); i++ {
+		for r.Intn(4) == 0 {
+			rec.Apply(actions[r.Intn(len(actions))])
+		}
+		rec.Tick()
+	}
+	// Inputs on the last frame count too.
+	rec.Apply(tetris.HardDrop)
+	return rec.Replay()
+}
+
+func TestNewRulesRoundTrip(t *testing.T) {
+	config := tetris.DefaultGameConfig(5)
+	config.Randomizer = tetris.NewTGMRandomizer(5)
+	config.Rotation = tetris.ARS
+	rules := NewRules(config, 5)
+	if rules.Rotation != "ars" || rules.Randomizer != "tgm" || rules.Seed != 5 {
+		t.Errorf("The rules should name the rotation system and randomizer, got %+v", rules)
+	}
+
+	back, err := rules.GameConfig()
+	if err != nil {
+		t.Fatalf("The rules should make a config: %s", err)
+	}
+	if back.Rotation != tetris.ARS || back.Randomizer.Next() != tetris.NewTGMRandomizer(5).Next() ||
+		back.Scoring.Name != config.Scoring.Name || back.LockDelay != config.LockDelay {
+		t.Error("The config should be the one the rules were made from")
+	}
+
+	rules.Randomizer = "fixed"
+	if _, err := rules.GameConfig(); err == nil {
+		t.Error("An unknown randomizer should be an error")
+	}
+}
+
+func TestWriteReadRoundTrip(t *testing.T) {
+	r := record(t, 1, 1000)
+	var buf bytes.Buffer
+	if err := Write(&buf, r); err != nil {
+		t.Fatalf("Replay should be written: %s", err)
+	}
+	if !strings.HasPrefix(buf.String(), "tetris-replay 1 sha256:") {
+		t.Errorf("Replay should start with its header, got %q", buf.String()[:40])
+	}
+
+	back, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Replay should be read: %s", err)
+	}
+	if len(back.Inputs) != len(r.Inputs) || back.Frames != r.Frames || !back.FinalBoard.EqualCells(r.FinalBoard) ||
+		back.Rules.Scoring != r.Rules.Scoring {
+		t.Error("The replay should come back the same")
+	}
+	if err := Verify(back); err != nil {
+		t.Errorf("The replay read should verify: %s", err)
+	}
+}
+
+func TestReadRejectsCorruption(t *testing.T) {
+	var buf bytes.Buffer
+	Write(&buf, record(t, 2, 300))
+	valid := buf.String()
+
+	cases := map[string]string{
+		"header":   "not a replay\n{}",
+		"version":  strings.Replace(valid, "tetris-replay 1", "tetris-replay 2", 1),
+		"checksum": strings.Replace(valid, `"frames":`, `"frames": `, 1),
+		"empty":    "",
+	}
+	for name, data := range cases {
+		if _, err := Read(strings.NewReader(data)); err == nil {
+			t.Errorf("A replay with a bad %s should not be read", name)
+		}
+	}
+}
+
+func TestRecorderRecordsFrames(t *testing.T) {
+	rec, _ := NewRecorder(NewRules(tetris.DefaultGameConfig(1), 1))
+	rec.Apply(tetris.MoveLeft)
+	rec.Tick()
+	rec.Tick()
+	rec.Apply(tetris.HardDrop)
+
+	r := rec.Replay()
+	expected := []Input{{0, tetris.MoveLeft}, {2, tetris.HardDrop}}
+	if len(r.Inputs) != 2 || r.Inputs[0] != expected[0] || r.Inputs[1] != expected[1] || r.Frames != 2 {
+		t.Errorf("The inputs should be stamped with their frames, got %v over %d frames", r.Inputs, r.Frames)
+	}
+}
@@ -0,0 +1,191 @@
+package tetris
+
+import (
+	"fmt"
+)
+
+// Describes how tetrominoes look in each of their rotation states, where they
+// enter the board and how they are nudged when a rotation collides.  Rotation
+// states are numbered from 0 with each increment being a clockwise turn.
+type RotationSystem interface {
+	// Returns a short name identifying the system, e.g. "srs".
+	Name() string
+
+	// Returns the number of rotation states for the given kind of tetromino,
+	// or -1 if the kind does not exist.
+	NumStates(kind string) int
+
+	// Returns the shape of the given kind of tetromino in the given state.
+	Shape(kind string, state int) *TetrominoData
+
+	// Returns the block of the tetromino's 4x4 data that is positioned at
+	// the row and column given to the placement functions.
+	Pivot(kind string) (int, int)
+
+	// Returns the rotation state, row and column a tetromino of the given
+	// kind enters a board of the given width at.
+	Spawn(kind string, width int) (int, int, int)
+
+	// Returns the offsets to try, in order, when rotating the given kind of
+	// tetromino from one state to another.  The first offset is normally
+	// {0, 0}, the unkicked rotation.
+	Kicks(kind string, from, to int) []Kick
+}
+
+// An offset applied to a tetromino's position when testing a rotation.  Rows
+// grow downwards like the board's.
+type Kick struct {
+	Row int
+	Col int
+}
+
+var noKicks = []Kick{{0, 0}}
+
+// Rotations as given in the Pason guide: the orientations of tet_orients with
+// no kicks.  I, S and Z have two orientations and O only one.
+var Pason RotationSystem = newTableRotation("pason", tet_orients, func(string, int, int) []Kick {
+	return noKicks
+})
+
+// The Super Rotation System of the Tetris guideline.
+var SRS RotationSystem = newTableRotation("srs", srs_orients, srsKicks)
+
+// The Arika Rotation System of Tetris The Grand Master.  T, J and L spawn
+// flat side up, pointing down, and pieces rotate so they stay resting on the
+// bottom of their box.  A blocked rotation tries one column right then one
+// column left, except for I which never kicks.  TGM's exception for J, L and
+// T rotations blocked in the centre column is not modelled.
+var ARS RotationSystem = newTableRotation("ars", ars_orients, func(kind string, from, to int) []Kick {
+	if kind == "I" {
+		return noKicks
+	}
+	return ars_kicks
+})
+
+// The Nintendo Rotation System of NES Tetris.  I, S and Z have two
+// orientations, O only one, and there are no kicks.
+var NRS RotationSystem = newTableRotation("nrs", nrs_orients, func(string, int, int) []Kick {
+	return noKicks
+})
+
+var ars_orients = map[string][]int{
+	"O": {0x0660, 0x0660, 0x0660, 0x0660},
+	"I": {0x0f00, 0x2222, 0x0f00, 0x2222},
+	"S": {0x0360, 0x4620, 0x0360, 0x4620},
+	"Z": {0x0630, 0x1320, 0x0630, 0x1320},
+	"L": {0x0740, 0x6220, 0x0170, 0x2230},
+	"J": {0x0710, 0x2260, 0x0470, 0x3220},
+	"T": {0x0720, 0x2620, 0x0270, 0x2320},
+}
+
+var ars_kicks = []Kick{{0, 0}, {0, 1}, {0, -1}}
+
+var nrs_orients = map[string][]int{
+	"O": {0x0660},
+	"I": {0x0f00, 0x2222},
+	"S": {0x0360, 0x2310},
+	"Z": {0x0630, 0x1320},
+	"L": {0x0740, 0x6220, 0x1700, 0x2230},
+	"J": {0x0710, 0x2260, 0x4700, 0x3220},
+	"T": {0x0720, 0x2620, 0x2700, 0x2320},
+}
+
+// A rotation system built from a table of orientations in the integerial
+// format of tet_orients.  Every kind pivots on row 1, column 2 of its data and
+// spawns centred at the top of the board.
+type tableRotation struct {
+	name   string
+	shapes map[string][]*TetrominoData
+	kicks  func(kind string, from, to int) []Kick
+}
+
+func newTableRotation(name string, orients map[string][]int, kicks func(string, int, int) []Kick) *tableRotation {
+	r := &tableRotation{name, make(map[string][]*TetrominoData), kicks}
+	for kind, states := range orients {
+		for _, orient := range states {
+			r.shapes[kind] = append(r.shapes[kind], intToTetData(orient))
+		}
+	}
+	return r
+}
+
+func (r *tableRotation) Name() string {
+	return r.name
+}
+
+func (r *tableRotation) NumStates(kind string) int {
+	states, ok := r.shapes[kind]
+	if !ok {
+		return -1
+	}
+	return len(states)
+}
+
+func (r *tableRotation) Shape(kind string, state int) *TetrominoData {
+	return r.shapes[kind][state]
+}
+
+func (r *tableRotation) Pivot(kind string) (int, int) {
+	return origin_row, origin_col
+}
+
+func (r *tableRotation) Spawn(kind string, width int) (int, int, int) {
+	row, col := spawnCentred(r, kind, 0, width)
+	return 0, row, col
+}
+
+func (r *tableRotation) Kicks(kind string, from, to int) []Kick {
+	return r.kicks(kind, from, to)
+}
+
+// Finds the position at which a tetromino in the given state has its top row
+// on the top row of the board and is centred horizontally, rounding left.
+func spawnCentred(rs RotationSystem, kind string, state, width int) (int, int) {
+	tet, err := NewTetrominoFor(rs, kind, state)
+	if err != nil {
+		return 0, 0
+	}
+
+	pr, pc := rs.Pivot(kind)
+	bounds := tet.Bounds()
+	left := (width - (bounds.Right - bounds.Left + 1)) / 2
+	return pr - bounds.Top, left - bounds.Left + pc
+}
+
+// Rotates a tetromino located at (row, col) on the board by the given number
+// of quarter turns (1 clockwise, -1 counter-clockwise, 2 for a half turn),
+// trying each of the rotation system's kicks in turn.  On success the
+// tetromino is rotated and the final row, column and the index of the kick
+// used are returned.  If every kick collides, or the tetromino belongs to
+// another rotation system, the tetromino is left unchanged and an error is
+// returned.
+func Rotate(rs RotationSystem, b *Board, t *Tetromino, row, col, dir int) (int, int, int, error) {
+	if err := checkSystem(rs, t); err != nil {
+		return row, col, -1, err
+	}
+
+	rotated := t.Copy()
+	from := t.Orient()
+	to := rotated.rotate(dir)
+
+	if r, c, kick, ok := kickInto(rs, b, from, rotated, row, col); ok {
+		*t = *rotated
+		return r, c, kick, nil
+	}
+
+	return row, col, -1, fmt.Errorf("Rotation of %s from %d to %d at (%d,%d) is blocked!", t.Kind(), from, to, row, col)
+}
+
+// Finds the first of the rotation system's kicks that lets a tetromino, already
+// turned from the given state, fit near (row, col).  Returns the kicked row and
+// column, the index of the kick and whether any fitted.
+func kickInto(rs RotationSystem, b *Board, from int, rotated *Tetromino, row, col int) (int, int, int, bool) {
+	pr, pc := rs.Pivot(rotated.Kind())
+	for i, kick := range rs.Kicks(rotated.Kind(), from, rotated.Orient()) {
+		r, c := row+kick.Row, col+kick.Col
+		if fitsAt(b, rotated, r-pr, c-pc) {
+			return r, c, i, true
+		}
+	}
+	return row, col, -1, false
+}
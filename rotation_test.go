@@ -0,0 +1,170 @@
+package tetris
+
+import (
+	"testing"
+)
+
+func TestRotationSystemNames(t *testing.T) {
+	systems := map[string]RotationSystem{"pason": Pason, "srs": SRS, "ars": ARS, "nrs": NRS}
+	for name, rs := range systems {
+		if rs.Name() != name {
+			t.Errorf("Rotation system should be named %s, was %s", name, rs.Name())
+		}
+	}
+}
+
+func TestRotationSystemNumStates(t *testing.T) {
+	cases := []struct {
+		rs     RotationSystem
+		kind   string
+		states int
+	}{
+		{Pason, "I", 2}, {Pason, "O", 1}, {Pason, "T", 4},
+		{SRS, "I", 4}, {SRS, "S", 4}, {SRS, "O", 4},
+		{ARS, "Z", 4}, {ARS, "L", 4},
+		{NRS, "O", 1}, {NRS, "S", 2}, {NRS, "J", 4},
+		{SRS, "X", -1},
+	}
+	for _, c := range cases {
+		if n := c.rs.NumStates(c.kind); n != c.states {
+			t.Errorf("%s %s should have %d states, had %d", c.rs.Name(), c.kind, c.states, n)
+		}
+	}
+}
+
+func TestPasonMatchesOrientationTable(t *testing.T) {
+	for kind, orients := range tet_orients {
+		for state, orient := range orients {
+			if !Pason.Shape(kind, state).Equal(intToTetData(orient)) {
+				t.Errorf("Pason %s state %d should match tet_orients", kind, state)
+			}
+		}
+	}
+}
+
+func TestSRSSpawnPositions(t *testing.T) {
+	board, _ := NewBoard(10, 20)
+	expected := map[string][]string{
+		"T": {"|    #     |", "|   ###    |"},
+		"I": {"|   ####   |", "|          |"},
+		"O": {"|    ##    |", "|    ##    |"},
+		"L": {"|     #    |", "|   ###    |"},
+	}
+
+	for kind, rows := range expected {
+		state, row, col := SRS.Spawn(kind, board.Width())
+		tet, _ := NewTetrominoFor(SRS, kind, state)
+		placed, err := Place(SRS, board, tet, row, col)
+		if err != nil {
+			t.Errorf("Spawned %s should fit on an empty board", kind)
+			continue
+		}
+
+		want, _ := StringArrayToBoard(rows)
+		top, _ := NewBoard(10, 2)
+		for r := 0; r < 2; r++ {
+			for c := 0; c < 10; c++ {
+				set, _ := placed.Block(r, c)
+				top.SetBlock(r, c, set)
+			}
+		}
+		if !top.Equal(want) {
+			t.Errorf("Spawned %s does not match expected\n%s", kind, placed)
+		}
+	}
+}
+
+func TestARSPiecesRestOnTheirBox(t *testing.T) {
+	for _, kind := range []string{"T", "J", "L"} {
+		up, _ := NewTetrominoFor(ARS, kind, 0)
+		down, _ := NewTetrominoFor(ARS, kind, 2)
+		if up.Bounds().Bottom != down.Bounds().Bottom {
+			t.Errorf("ARS %s should keep its bottom when turned over", kind)
+		}
+	}
+}
+
+func TestARSKicksRightThenLeft(t *testing.T) {
+	board, _ := StringArrayToBoard([]string{
+		"|     |",
+		"|     |",
+		"|     |",
+	})
+	tet, _ := NewTetrominoFor(ARS, "T", 3)
+
+	// Against the left wall the flat rotation needs to move right.
+	row, col, kick, err := Rotate(ARS, board, tet, 1, 0, 1)
+	if err != nil {
+		t.Fatal("Rotation against the wall should kick")
+	}
+	if row != 1 || col != 1 || kick != 1 {
+		t.Errorf("Expected (1,1) with kick 1, got (%d,%d) with kick %d", row, col, kick)
+	}
+}
+
+func TestARSIDoesNotKick(t *testing.T) {
+	board, _ := NewBoard(10, 20)
+	tet, _ := NewTetrominoFor(ARS, "I", 1)
+	if _, _, _, err := Rotate(ARS, board, tet, 10, 9, 1); err == nil {
+		t.Error("I should not kick away from the wall")
+	}
+}
+
+func TestRotateRejectsAnotherSystem(t *testing.T) {
+	board, _ := NewBoard(10, 20)
+	tet, _ := NewTetrominoFor(ARS, "T", 0)
+	if _, _, _, err := Rotate(SRS, board, tet, 10, 5, 1); err == nil || tet.Orient() != 0 {
+		t.Error("Rotating with another system should be an error and leave the tetromino alone")
+	}
+}
+
+func TestNRSRotatesClockwise(t *testing.T) {
+	board, _ := NewBoard(10, 20)
+	tet, _ := NewTetrominoFor(NRS, "T", 0)
+	Rotate(NRS, board, tet, 10, 4, 1)
+
+	// Pointing down then turned clockwise the T should point left.
+	expected := intToTetData(0x2620)
+	if !tet.Data().Equal(expected) {
+		t.Errorf("T should point left after a clockwise turn\n%s", tet)
+	}
+}
+
+func TestNRSDoesNotKick(t *testing.T) {
+	board, _ := NewBoard(10, 20)
+	tet, _ := NewTetrominoFor(NRS, "I", 1)
+	if _, _, _, err := Rotate(NRS, board, tet, 10, 9, 1); err == nil {
+		t.Error("NRS rotations should never kick")
+	}
+}
+
+// A rotation system pivoting on the top left corner of each tetromino's data
+// to check the placement functions follow the system's pivot.
+type cornerRotation struct {
+	RotationSystem
+}
+
+func (r cornerRotation) Pivot(kind string) (int, int) {
+	return 0, 0
+}
+
+func TestPlaceFollowsRotationSystemPivot(t *testing.T) {
+	rs := cornerRotation{SRS}
+	board, _ := NewBoard(4, 4)
+	tet, _ := NewTetrominoFor(rs, "I", 0)
+
+	placed, err := Place(rs, board, tet, 0, 0)
+	if err != nil {
+		t.Fatal("Placement should be valid")
+	}
+
+	expected, _ := StringArrayToBoard([]string{
+		"|    |",
+		"|####|",
+		"|    |",
+		"|    |",
+	})
+	if !placed.Equal(expected) {
+		t.Errorf("Placement should use the custom pivot\n%s", placed)
+	}
+}
@@ -0,0 +1,188 @@
+package tetris
+
+// How a piece was spun into the place it locked.
+type Spin int
+
+const (
+	SpinNone Spin = iota
+	SpinMini
+	SpinFull
+)
+
+func (s Spin) String() string {
+	switch s {
+	case SpinMini:
+		return "Mini"
+	case SpinFull:
+		return "Full"
+	}
+	return "None"
+}
+
+// Describes a piece locking, for scoring.
+type ClearEvent struct {
+	Lines        int  // lines cleared by the lock
+	Spin         Spin // how the piece was spun into place
+	PerfectClear bool // whether the lock left the board empty
+}
+
+// Returns true for clears that build back-to-back chains: four lines at once
+// or any spin that clears lines.
+func (e ClearEvent) Difficult() bool {
+	return e.Lines >= 4 || (e.Lines > 0 && e.Spin != SpinNone)
+}
+
+// The points awarded for each kind of clear.  Line clear, spin, perfect clear
+// and combo points are multiplied by the level plus LevelOffset, drop points
+// are not.
+type ScoreTable struct {
+	Name string
+
+	// Points by number of lines cleared (0 to 4) without a spin, with a full
+	// spin and with a mini spin.
+	Lines    [5]int
+	Spin     [5]int
+	SpinMini [5]int
+
+	// Points added by number of lines cleared when the board is left empty.
+	PerfectClear [5]int
+
+	// Multiplier for the line clear points of a difficult clear following
+	// another difficult clear.  Values of 1 or less disable the bonus.
+	BackToBack float64
+
+	// Points for each step of a combo, i.e. consecutive locks that clear
+	// lines: the nth consecutive clear scores Combo * (n-1).
+	Combo int
+
+	// Points per row fallen by soft and hard drops.
+	SoftDrop int
+	HardDrop int
+
+	// Lines needed to go up a level, zero to stay on the starting level.
+	LinesPerLevel int
+
+	// Added to the level to give the points multiplier.
+	LevelOffset int
+}
+
+// The scoring of the modern Tetris guideline, starting from level 1.
+var GuidelineScoring = ScoreTable{
+	Name:          "guideline",
+	Lines:         [5]int{0, 100, 300, 500, 800},
+	Spin:          [5]int{400, 800, 1200, 1600, 0},
+	SpinMini:      [5]int{100, 200, 400, 0, 0},
+	PerfectClear:  [5]int{0, 800, 1200, 1800, 2000},
+	BackToBack:    1.5,
+	Combo:         50,
+	SoftDrop:      1,
+	HardDrop:      2,
+	LinesPerLevel: 10,
+}
+
+// The scoring of NES Tetris, starting from level 0.  There are no spins, hard
+// drops or combos and soft drops score a point per row.
+var NESScoring = ScoreTable{
+	Name:          "nes",
+	Lines:         [5]int{0, 40, 100, 300, 1200},
+	SoftDrop:      1,
+	LinesPerLevel: 10,
+	LevelOffset:   1,
+}
+
+// Keeps the score, level and line count of a game using a ScoreTable.
+type Scorer struct {
+	table *ScoreTable
+	score int
+	start int // the level the game started on
+	level int
+	lines int
+	combo int  // consecutive line clearing locks, -1 after one that clears none
+	b2b   bool // whether the last line clear was difficult
+}
+
+func NewScorer(table ScoreTable, level int) *Scorer {
+	return &Scorer{table: &table, start: level, level: level, combo: -1}
+}
+
+func (s *Scorer) Table() ScoreTable {
+	return *s.table
+}
+
+func (s *Scorer) Score() int {
+	return s.score
+}
+
+func (s *Scorer) Level() int {
+	return s.level
+}
+
+func (s *Scorer) Lines() int {
+	return s.lines
+}
+
+// Returns the current combo count, 0 for the first clear of a chain and -1
+// when there is no chain.
+func (s *Scorer) Combo() int {
+	return s.combo
+}
+
+// Returns true if the next difficult clear will get the back-to-back bonus.
+func (s *Scorer) BackToBack() bool {
+	return s.b2b
+}
+
+// Scores a piece locking and returns the points awarded.
+func (s *Scorer) Clear(e ClearEvent) int {
+	t := s.table
+	lines := e.Lines
+	if lines > 4 {
+		lines = 4
+	}
+	multiplier := s.level + t.LevelOffset
+
+	base := t.Lines[lines]
+	switch e.Spin {
+	case SpinFull:
+		base = t.Spin[lines]
+	case SpinMini:
+		base = t.SpinMini[lines]
+	}
+
+	difficult := e.Difficult()
+	if difficult && s.b2b && t.BackToBack > 1 {
+		base = int(float64(base) * t.BackToBack)
+	}
+	points := base * multiplier
+
+	if e.Lines > 0 {
+		s.combo++
+		points += t.Combo * s.combo * multiplier
+		s.b2b = difficult
+	} else {
+		s.combo = -1
+	}
+
+	if e.PerfectClear {
+		points += t.PerfectClear[lines] * multiplier
+	}
+
+	s.lines += e.Lines
+	if t.LinesPerLevel > 0 {
+		s.level = s.start + s.lines/t.LinesPerLevel
+	}
+
+	s.score += points
+	return points
+}
+
+// Scores a soft or hard drop of the given number of rows and returns the
+// points awarded.
+func (s *Scorer) Drop(rows int, hard bool) int {
+	points := rows * s.table.SoftDrop
+	if hard {
+		points = rows * s.table.HardDrop
+	}
+	s.score += points
+	return points
+}
@@ -0,0 +1,176 @@
+package tetris
+
+import (
+	"testing"
+)
+
+func TestGuidelineLineClears(t *testing.T) {
+	expected := []int{0, 100, 300, 500, 800}
+	for lines, points := range expected {
+		s := NewScorer(GuidelineScoring, 1)
+		if got := s.Clear(ClearEvent{Lines: lines}); got != points {
+			t.Errorf("Clearing %d lines should score %d, scored %d", lines, points, got)
+		}
+	}
+}
+
+func TestGuidelineSpins(t *testing.T) {
+	s := NewScorer(GuidelineScoring, 1)
+	if got := s.Clear(ClearEvent{Lines: 2, Spin: SpinFull}); got != 1200 {
+		t.Errorf("T-spin double should score 1200, scored %d", got)
+	}
+
+	s = NewScorer(GuidelineScoring, 1)
+	if got := s.Clear(ClearEvent{Lines: 1, Spin: SpinMini}); got != 200 {
+		t.Errorf("T-spin mini single should score 200, scored %d", got)
+	}
+
+	s = NewScorer(GuidelineScoring, 1)
+	if got := s.Clear(ClearEvent{Spin: SpinFull}); got != 400 {
+		t.Errorf("T-spin without lines should score 400, scored %d", got)
+	}
+}
+
+func TestLevelMultiplies(t *testing.T) {
+	s := NewScorer(GuidelineScoring, 3)
+	if got := s.Clear(ClearEvent{Lines: 4}); got != 2400 {
+		t.Errorf("Tetris on level 3 should score 2400, scored %d", got)
+	}
+}
+
+func TestBackToBack(t *testing.T) {
+	s := NewScorer(GuidelineScoring, 1)
+	s.Clear(ClearEvent{Lines: 4})
+	if !s.BackToBack() {
+		t.Error("A tetris should start a back-to-back chain")
+	}
+
+	s.Clear(ClearEvent{})
+	got := s.Clear(ClearEvent{Lines: 2, Spin: SpinFull})
+	if got != 1800 {
+		t.Errorf("Back-to-back T-spin double should score 1800, scored %d", got)
+	}
+
+	s.Clear(ClearEvent{})
+	s.Clear(ClearEvent{Lines: 1})
+	if s.BackToBack() {
+		t.Error("A single should break the back-to-back chain")
+	}
+	s.Clear(ClearEvent{})
+	if got := s.Clear(ClearEvent{Lines: 4}); got != 800 {
+		t.Errorf("A tetris after a broken chain should score 800, scored %d", got)
+	}
+}
+
+func TestSpinWithoutLinesKeepsBackToBack(t *testing.T) {
+	s := NewScorer(GuidelineScoring, 1)
+	s.Clear(ClearEvent{Lines: 4})
+	s.Clear(ClearEvent{Spin: SpinFull})
+	if !s.BackToBack() {
+		t.Error("A spin clearing no lines should not break the chain")
+	}
+}
+
+func TestCombo(t *testing.T) {
+	s := NewScorer(GuidelineScoring, 1)
+	s.Clear(ClearEvent{Lines: 1})
+	if s.Combo() != 0 {
+		t.Error("The first clear should start the combo at 0")
+	}
+	if got := s.Clear(ClearEvent{Lines: 1}); got != 150 {
+		t.Errorf("A single with combo 1 should score 150, scored %d", got)
+	}
+	if got := s.Clear(ClearEvent{Lines: 1}); got != 200 {
+		t.Errorf("A single with combo 2 should score 200, scored %d", got)
+	}
+
+	s.Clear(ClearEvent{})
+	if s.Combo() != -1 {
+		t.Error("A lock without lines should end the combo")
+	}
+}
+
+func TestPerfectClear(t *testing.T) {
+	s := NewScorer(GuidelineScoring, 1)
+	if got := s.Clear(ClearEvent{Lines: 4, PerfectClear: true}); got != 2800 {
+		t.Errorf("A perfect clear tetris should score 2800, scored %d", got)
+	}
+}
+
+func TestDrops(t *testing.T) {
+	s := NewScorer(GuidelineScoring, 5)
+	if got := s.Drop(10, true); got != 20 {
+		t.Errorf("Hard dropping 10 rows should score 20, scored %d", got)
+	}
+	if got := s.Drop(3, false); got != 3 {
+		t.Errorf("Soft dropping 3 rows should score 3, scored %d", got)
+	}
+	if s.Score() != 23 {
+		t.Errorf("Score should total 23, was %d", s.Score())
+	}
+}
+
+func TestLevelUp(t *testing.T) {
+	s := NewScorer(GuidelineScoring, 1)
+	for i := 0; i < 3; i++ {
+		s.Clear(ClearEvent{Lines: 4})
+	}
+	if s.Lines() != 12 || s.Level() != 2 {
+		t.Errorf("12 lines from level 1 should reach level 2, got level %d", s.Level())
+	}
+}
+
+func TestNESScoring(t *testing.T) {
+	s := NewScorer(NESScoring, 0)
+	if got := s.Clear(ClearEvent{Lines: 4}); got != 1200 {
+		t.Errorf("NES tetris on level 0 should score 1200, scored %d", got)
+	}
+	if got := s.Clear(ClearEvent{Lines: 4}); got != 1200 {
+		t.Errorf("NES has no back-to-back bonus, scored %d", got)
+	}
+	if s.Level() != 0 {
+		t.Error("8 lines should stay on level 0")
+	}
+	s.Clear(ClearEvent{Lines: 2})
+	if got := s.Clear(ClearEvent{Lines: 1}); got != 80 {
+		t.Errorf("NES single on level 1 should score 80, scored %d", got)
+	}
+	if s.Drop(5, true) != 0 {
+		t.Error("NES should not score hard drops")
+	}
+}
+
+func TestCustomScoring(t *testing.T) {
+	contest := ScoreTable{Name: "contest", Lines: [5]int{0, 1, 3, 6, 10}, LevelOffset: 1}
+	s := NewScorer(contest, 0)
+	s.Clear(ClearEvent{Lines: 3})
+	s.Clear(ClearEvent{Lines: 4, Spin: SpinFull})
+	if s.Score() != 6 {
+		t.Errorf("Custom table with no multiplier should score 6, scored %d", s.Score())
+	}
+}
+
+func TestGameScoresDropsAndClears(t *testing.T) {
+	board, _ := StringArrayToBoard([]string{
+		"|          |",
+		"|          |",
+		"|#         |",
+		"|###    ###|",
+	})
+	config := DefaultGameConfig(1)
+	config.Board = board
+	config.Randomizer = &fixedRandomizer{kinds: []string{"I"}}
+	g := newTestGame(t, config)
+
+	g.SoftDrop()
+	res := g.HardDrop()
+	if res.Lock == nil || res.Lock.Lines != 1 || res.Lock.Points != 100 {
+		t.Fatalf("The I should clear and score a single, got %+v", res.Lock)
+	}
+	if g.Scorer().Score() != 1+2*res.Dropped+100 {
+		t.Errorf("Score should count the drops and the single, was %d", g.Scorer().Score())
+	}
+	if s := g.Snapshot(); s.Lines != 1 || s.Score != g.Scorer().Score() || s.Level != 1 {
+		t.Errorf("Snapshot should show the score, got %+v", s)
+	}
+}
@@ -0,0 +1,232 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/paulcoyle/tetris"
+)
+
+// The score given to a position where the piece to place cannot be placed.
+const lossScore = -1e6
+
+// A move considered by Expectimax and how it is expected to turn out.
+type Candidate struct {
+	Move     Move
+	Expected float64 // the expected sum of the evaluations over the search
+	Variance float64 // the variance of that sum over the pieces dealt
+}
+
+// The outcome of an expectimax search.
+type ExpectimaxResult struct {
+	// Every move for the active piece, best expected first.  A search cut
+	// short only has the moves it finished scoring.
+	Candidates []Candidate
+
+	Nodes    int  // the number of positions evaluated
+	Complete bool // whether every move was scored
+}
+
+// Returns the best of the candidates.
+func (r *ExpectimaxResult) Best() Candidate {
+	return r.Candidates[0]
+}
+
+// Scores every move for the active piece with an expectimax search.  Pieces
+// in the queue are placed as they come, and pieces beyond it are chance
+// nodes: every kind the state's randomizer could deal is tried and weighed by
+// its chance.  Placing a piece takes the best of its placements, of which only
+// the BeamWidth with the best evaluations are searched further.  Hold is not
+// considered when the hold slot is empty and the next piece is unknown.  The
+// search places Depth pieces in all.
+func (s *Searcher) Expectimax(state State) (*ExpectimaxResult, error) {
+	return s.ExpectimaxContext(context.Background(), state)
+}
+
+// Scores every move like Expectimax, stopping early when the context is done.
+// A search cut short returns the moves it finished scoring, without
+// Complete set.  An error is returned only if no move was finished.
+func (s *Searcher) ExpectimaxContext(ctx context.Context, state State) (*ExpectimaxResult, error) {
+	if state.Board == nil {
+		return nil, fmt.Errorf("A search needs a board!")
+	}
+
+	random := state.Randomizer
+	if random == nil {
+		random = tetris.NewPureRandomizer(0)
+	}
+	root := &chanceNode{board: state.Board, current: state.Active, held: state.Held, queue: state.Queue, random: random}
+	children := s.options(nil, root)
+	if len(children) == 0 {
+		return nil, fmt.Errorf("No placement found for %s!", state.Active)
+	}
+
+	c := &cache{entries: make(map[uint64][]scored)}
+	candidates := make([]Candidate, len(children))
+	finished := make([]bool, len(children))
+	nodes := make([]int, len(children))
+	s.each(ctx, len(children), func(i int) {
+		e := &expectimax{s: s, c: c, ctx: ctx}
+		mean, variance := e.chance(children[i].node, s.config.Depth-1)
+		candidates[i] = Candidate{children[i].move, mean + children[i].score, variance}
+		finished[i] = !e.stopped
+		nodes[i] = e.nodes
+	})
+
+	result := &ExpectimaxResult{Nodes: len(children), Complete: true}
+	for i, n := range nodes {
+		result.Nodes += n
+		if finished[i] {
+			result.Candidates = append(result.Candidates, candidates[i])
+		} else {
+			result.Complete = false
+		}
+	}
+	if len(result.Candidates) == 0 {
+		return nil, ctx.Err()
+	}
+	sort.SliceStable(result.Candidates, func(i, j int) bool {
+		return result.Candidates[i].Expected > result.Candidates[j].Expected
+	})
+	return result, nil
+}
+
+// A position in the expectimax search, where the piece to place may not yet
+// be known.
+type chanceNode struct {
+	board   *tetris.Board
+	current string // the piece to place, "" when it is to be dealt
+	held    string
+	queue   []string // the known pieces after current
+	random  tetris.Randomizer
+}
+
+// A position reached by placing a piece.
+type option struct {
+	node  *chanceNode
+	move  Move
+	score float64
+}
+
+// One worker's part of an expectimax search.
+type expectimax struct {
+	s       *Searcher
+	c       *cache
+	ctx     context.Context
+	nodes   int
+	stopped bool // set once the context is done, abandoning the search
+}
+
+// Returns true if the search should be abandoned.
+func (e *expectimax) stop() bool {
+	if !e.stopped && e.ctx.Err() != nil {
+		e.stopped = true
+	}
+	return e.stopped
+}
+
+// Returns the expected value and the variance of the sum of the scores of
+// placing the given number of pieces from a position.
+func (e *expectimax) chance(n *chanceNode, depth int) (float64, float64) {
+	if depth <= 0 || e.stop() {
+		return 0, 0
+	}
+	if n.current != "" {
+		return e.decide(n, depth)
+	}
+	if len(n.queue) > 0 {
+		return e.decide(&chanceNode{n.board, n.queue[0], n.held, n.queue[1:], n.random}, depth)
+	}
+
+	// The variance of the mix of outcomes is taken around its mean rather
+	// than as E[x²]-E[x]², which loses its precision next to lossScore.
+	dist := n.random.Distribution()
+	var means, variances [len(tetris.Kinds)]float64
+	mean := 0.0
+	for i, p := range dist {
+		if p == 0 {
+			continue
+		}
+		if e.stop() {
+			return 0, 0
+		}
+		random := n.random.Clone()
+		random.Advance(tetris.Kinds[i])
+		means[i], variances[i] = e.decide(&chanceNode{n.board, tetris.Kinds[i], n.held, nil, random}, depth)
+		mean += p * means[i]
+	}
+
+	variance := 0.0
+	for i, p := range dist {
+		if p == 0 {
+			continue
+		}
+		d := means[i] - mean
+		variance += p * (variances[i] + d*d)
+	}
+	return mean, variance
+}
+
+// Returns the expected value and variance of the best placement of the piece
+// to place, by expected value.
+func (e *expectimax) decide(n *chanceNode, depth int) (float64, float64) {
+	children := e.s.options(e.c, n)
+	e.nodes += len(children)
+	if len(children) == 0 {
+		return lossScore, 0
+	}
+
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].score > children[j].score
+	})
+	if len(children) > e.s.config.BeamWidth {
+		children = children[:e.s.config.BeamWidth]
+	}
+
+	best, bestVariance := math.Inf(-1), 0.0
+	for _, child := range children {
+		if e.stop() {
+			return 0, 0
+		}
+		mean, variance := e.chance(child.node, depth-1)
+		if mean += child.score; mean > best {
+			best, bestVariance = mean, variance
+		}
+	}
+	return best, bestVariance
+}
+
+// Returns the positions reached by placing the node's piece, and with hold the
+// piece it would swap for.  Scores are looked up in the cache when given one.
+func (s *Searcher) options(c *cache, n *chanceNode) []option {
+	children := s.placeChance(c, n, n.current, n.held, n.queue, false)
+	if !s.config.Hold {
+		return children
+	}
+	if n.held != "" {
+		if n.held != n.current {
+			children = append(children, s.placeChance(c, n, n.held, n.current, n.queue, true)...)
+		}
+	} else if len(n.queue) > 0 {
+		children = append(children, s.placeChance(c, n, n.queue[0], n.current, n.queue[1:], true)...)
+	}
+	return children
+}
+
+func (s *Searcher) placeChance(c *cache, n *chanceNode, kind, held string, queue []string, hold bool) []option {
+	var placements []scored
+	if c == nil {
+		placements = s.score(n.board, kind)
+	} else {
+		placements = c.placements(s, n.board, kind)
+	}
+
+	children := make([]option, len(placements))
+	for i, sp := range placements {
+		child := &chanceNode{board: sp.placement.Board, held: held, queue: queue, random: n.random}
+		children[i] = option{child, Move{hold, sp.placement}, sp.score}
+	}
+	return children
+}
@@ -0,0 +1,223 @@
+package search
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/paulcoyle/tetris"
+)
+
+// Returns a 7-bag randomizer with only the given kind left in its bag.
+func lastInBag(kind string) tetris.Randomizer {
+	r := tetris.NewBagRandomizer(1)
+	for _, k := range tetris.Kinds {
+		if k != kind {
+			r.Advance(k)
+		}
+	}
+	return r
+}
+
+func TestExpectimaxOnePiece(t *testing.T) {
+	config := DefaultConfig()
+	config.Depth = 1
+	config.Hold = false
+	s := newTestSearcher(t, config)
+
+	board := wellBoard()
+	result, err := s.Expectimax(State{Board: board, Active: "T"})
+	if err != nil {
+		t.Fatalf("Expectimax should succeed: %s", err)
+	}
+
+	state, row, col := tetris.SRS.Spawn("T", 10)
+	if len(result.Candidates) != len(tetris.FindPlacements(tetris.SRS, board, "T", state, row, col)) {
+		t.Error("Every placement should be a candidate")
+	}
+	for i, c := range result.Candidates {
+		if c.Expected != config.Evaluator.Score(board, c.Move.Placement) || c.Variance != 0 {
+			t.Error("With one piece the expected value should be its score, with no variance")
+		}
+		if i > 0 && c.Expected > result.Candidates[i-1].Expected {
+			t.Error("Candidates should be sorted best first")
+		}
+	}
+}
+
+func TestExpectimaxFollowsBag(t *testing.T) {
+	config := DefaultConfig()
+	config.Depth = 2
+	config.BeamWidth = 100
+	config.Hold = false
+	s := newTestSearcher(t, config)
+
+	board := wellBoard()
+	result, _ := s.Expectimax(State{Board: board, Active: "O", Randomizer: lastInBag("I")})
+	for _, c := range result.Candidates {
+		if c.Variance > 1e-6 {
+			t.Fatalf("With only an I left in the bag there should be no variance, was %v", c.Variance)
+		}
+	}
+
+	beam, _ := s.Search(State{Board: board, Active: "O", Queue: []string{"I"}})
+	if math.Abs(result.Best().Expected-beam.Score) > 1e-9 {
+		t.Errorf("Knowing the I is next, expectimax should agree with the beam: %v and %v",
+			result.Best().Expected, beam.Score)
+	}
+}
+
+func TestExpectimaxUsesQueueBeforeChance(t *testing.T) {
+	config := DefaultConfig()
+	config.Depth = 2
+	config.Hold = false
+	s := newTestSearcher(t, config)
+
+	result, _ := s.Expectimax(State{Board: wellBoard(), Active: "O", Queue: []string{"I"}})
+	if result.Best().Variance != 0 {
+		t.Error("Pieces in the queue should not be left to chance")
+	}
+
+	result, _ = s.Expectimax(State{Board: wellBoard(), Active: "O"})
+	if result.Best().Variance == 0 {
+		t.Error("An unknown piece should give the outcome some variance")
+	}
+}
+
+func TestExpectimaxParallelMatchesSequential(t *testing.T) {
+	config := DefaultConfig()
+	config.Depth = 2
+	config.BeamWidth = 4
+	config.Workers = 1
+	sequential := newTestSearcher(t, config)
+	config.Workers = 8
+	parallel := newTestSearcher(t, config)
+
+	state := State{Board: wellBoard(), Active: "S", Held: "L", Randomizer: tetris.NewTGMRandomizer(4)}
+	a, _ := sequential.Expectimax(state)
+	b, _ := parallel.Expectimax(state)
+	if len(a.Candidates) != len(b.Candidates) || a.Nodes != b.Nodes {
+		t.Fatal("Searches should consider the same candidates")
+	}
+	for i := range a.Candidates {
+		if a.Candidates[i].Expected != b.Candidates[i].Expected ||
+			a.Candidates[i].Move.Placement.Cells != b.Candidates[i].Move.Placement.Cells {
+			t.Fatal("Candidates should be the same with workers")
+		}
+	}
+}
+
+func TestExpectimaxErrors(t *testing.T) {
+	s := newTestSearcher(t, DefaultConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.ExpectimaxContext(ctx, State{Board: wellBoard(), Active: "T"}); err != context.Canceled {
+		t.Errorf("A cancelled search should return the context's error, got %v", err)
+	}
+	if _, err := s.Expectimax(State{Active: "T"}); err == nil {
+		t.Error("A search without a board should be an error")
+	}
+
+	board, _ := tetris.NewBoard(10, 4)
+	board.SetRow(0, true)
+	board.SetRow(1, true)
+	if _, err := s.Expectimax(State{Board: board, Active: "T"}); err == nil {
+		t.Error("A piece that cannot spawn should be an error")
+	}
+}
+
+func TestExpectimaxVarianceWithLosses(t *testing.T) {
+	config := DefaultConfig()
+	config.Depth = 2
+	config.Hold = false
+	s := newTestSearcher(t, config)
+	one := DefaultConfig()
+	one.Depth = 1
+	one.Hold = false
+	single := newTestSearcher(t, one)
+
+	// On a 4x2 board nothing fits after the O, on a 4x3 board only some
+	// kinds do.
+	for _, height := range []int{2, 3} {
+		board, _ := tetris.NewBoard(4, height)
+		result, err := s.Expectimax(State{Board: board, Active: "O"})
+		if err != nil {
+			t.Fatalf("Expectimax should succeed: %s", err)
+		}
+
+		for _, c := range result.Candidates {
+			var values []float64
+			mean := 0.0
+			for _, kind := range tetris.Kinds {
+				value := lossScore
+				if next, err := single.Expectimax(State{Board: c.Move.Placement.Board, Active: kind}); err == nil {
+					value = next.Best().Expected
+				}
+				values = append(values, value)
+				mean += value / float64(len(tetris.Kinds))
+			}
+			variance := 0.0
+			for _, v := range values {
+				variance += (v - mean) * (v - mean) / float64(len(tetris.Kinds))
+			}
+
+			if c.Variance < 0 || math.Abs(c.Variance-variance) > 1e-6*(1+variance) {
+				t.Errorf("On a board %d high the variance should be %v, was %v", height, variance, c.Variance)
+			}
+		}
+	}
+}
+
+// A context that is done after its Err method has been called a number of
+// times, to cut a search short at a repeatable point.
+type countdownContext struct {
+	context.Context
+	calls int
+}
+
+func (c *countdownContext) Err() error {
+	if c.calls--; c.calls < 0 {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+func TestExpectimaxCutShort(t *testing.T) {
+	config := DefaultConfig()
+	config.Depth = 3
+	config.BeamWidth = 3
+	config.Hold = false
+	config.Workers = 1
+	s := newTestSearcher(t, config)
+	state := State{Board: wellBoard(), Active: "T"}
+
+	full, _ := s.Expectimax(state)
+	if !full.Complete {
+		t.Error("A search left to finish should be complete")
+	}
+
+	// Enough for a few moves, running out deep inside one of them.
+	ctx := &countdownContext{context.Background(), 2000}
+	result, err := s.ExpectimaxContext(ctx, state)
+	if err != nil {
+		t.Fatalf("A search cut short should return the moves it finished: %s", err)
+	}
+	if result.Complete || len(result.Candidates) == 0 || len(result.Candidates) >= len(full.Candidates) {
+		t.Fatalf("A search cut short should have some of the %d moves, had %d", len(full.Candidates), len(result.Candidates))
+	}
+	if ctx.calls > -1 || ctx.calls < -5 {
+		t.Errorf("The search should stop soon after the context runs out, checked it %d more times", -ctx.calls)
+	}
+	for _, c := range result.Candidates {
+		found := false
+		for _, f := range full.Candidates {
+			if f.Move.Placement.Cells == c.Move.Placement.Cells {
+				found = f.Expected == c.Expected && f.Variance == c.Variance
+			}
+		}
+		if !found {
+			t.Error("Finished moves should be scored as in a full search")
+		}
+	}
+}
@@ -0,0 +1,491 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/paulcoyle/tetris"
+)
+
+// Settings for a Monte Carlo tree search.
+type MCTSConfig struct {
+	Rotation  tetris.RotationSystem
+	Evaluator *tetris.Evaluator
+
+	// Weighs trying little visited moves against the best found so far in
+	// the UCT formula.  It is in the units of the evaluator's scores.
+	Exploration float64
+
+	// The search stops after this many simulations or this long, whichever
+	// comes first.  Zero leaves either unlimited, but not both.
+	Iterations int
+	Time       time.Duration
+
+	// The number of pieces each simulation places past the tree using the
+	// default policy.
+	RolloutDepth int
+
+	// Whether to consider swapping pieces with the hold slot.  As with
+	// Expectimax, hold is not considered when the slot is empty and the next
+	// piece is unknown.
+	Hold bool
+
+	// Seeds the choice of pieces dealt in simulations.
+	Seed int64
+}
+
+// Returns a search of 1000 simulations rolling out 4 pieces using hold, the
+// SRS and the El-Tetris weights.
+func DefaultMCTSConfig() MCTSConfig {
+	return MCTSConfig{
+		Rotation:     tetris.SRS,
+		Evaluator:    tetris.NewEvaluator(tetris.ElTetrisWeights),
+		Exploration:  10,
+		Iterations:   1000,
+		RolloutDepth: 4,
+		Hold:         true,
+	}
+}
+
+// The outcome of a Monte Carlo tree search.
+type MCTSResult struct {
+	Move       Move
+	Visits     int     // the number of simulations that began with the move
+	Expected   float64 // their mean sum of evaluations
+	Iterations int     // the number of simulations run by this search
+	Reused     bool    // whether the tree from a previous search was kept
+}
+
+// Chooses moves by Monte Carlo tree search.  Each simulation walks down the
+// tree of placements choosing moves with UCT and dealing unknown pieces from
+// the randomizer's distribution, then plays on with a default policy that
+// takes the best scoring hard drop.  A simulation scores the sum of the
+// evaluator's scores of its placements.  The move tried most often is chosen.
+//
+// The tree is kept between searches.  When the next state searched is one the
+// tree already reached, such as after playing the chosen move and being dealt
+// a piece it had sampled, the search carries on from there.  An MCTS is not
+// safe for concurrent use.
+type MCTS struct {
+	config MCTSConfig
+	rand   *rand.Rand
+	root   *mctsNode
+}
+
+// Creates a Monte Carlo tree search with the given settings.
+func NewMCTS(config MCTSConfig) (*MCTS, error) {
+	if config.Rotation == nil {
+		return nil, fmt.Errorf("A search needs a rotation system!")
+	}
+	if config.Evaluator == nil {
+		return nil, fmt.Errorf("A search needs an evaluator!")
+	}
+	if config.Iterations <= 0 && config.Time <= 0 {
+		return nil, fmt.Errorf("A search needs an iteration or time budget!")
+	}
+	return &MCTS{config: config, rand: rand.New(rand.NewSource(config.Seed))}, nil
+}
+
+func (m *MCTS) Config() MCTSConfig {
+	return m.config
+}
+
+// A position in the tree.  Decision nodes have a piece to place and choose
+// between placements.  Chance nodes have none and deal one.
+type mctsNode struct {
+	board   *tetris.Board
+	current string // the piece to place, "" for a chance node
+	held    string
+	queue   []string // the known pieces after current
+
+	// Deals the pieces after the queue.  Only ever cloned, so nodes share it.
+	random tetris.Randomizer
+
+	visits int
+	edges  []*mctsEdge // placements, nil until expanded
+	dealt  [len(tetris.Kinds)]*mctsNode
+}
+
+// A placement from a decision node.
+type mctsEdge struct {
+	move  Move
+	score float64
+	skip  int // the number of queued pieces used besides the one placed
+	child *mctsNode
+
+	visits int
+	total  float64
+}
+
+// Returns the best move for the state, using the tree from the previous search
+// if the state is in it.
+func (m *MCTS) Search(state State) (*MCTSResult, error) {
+	return m.SearchContext(context.Background(), state)
+}
+
+// Returns the best move like Search, stopping early when the context is done.
+// An error is returned if no simulation was run.
+func (m *MCTS) SearchContext(ctx context.Context, state State) (*MCTSResult, error) {
+	if state.Board == nil {
+		return nil, fmt.Errorf("A search needs a board!")
+	}
+
+	random := state.Randomizer
+	if random == nil {
+		random = tetris.NewPureRandomizer(0)
+	}
+	random = random.Clone()
+
+	result := &MCTSResult{}
+	if root := m.find(state); root != nil {
+		m.reveal(root, state.Queue, random)
+		m.root = root
+		result.Reused = true
+	} else {
+		m.root = &mctsNode{board: state.Board, current: state.Active, held: state.Held, queue: state.Queue, random: random}
+	}
+
+	m.expand(m.root)
+	if len(m.root.edges) == 0 {
+		m.root = nil
+		return nil, fmt.Errorf("No placement found for %s!", state.Active)
+	}
+
+	var deadline time.Time
+	if m.config.Time > 0 {
+		deadline = time.Now().Add(m.config.Time)
+	}
+	for m.config.Iterations <= 0 || result.Iterations < m.config.Iterations {
+		if ctx.Err() != nil || (!deadline.IsZero() && !time.Now().Before(deadline)) {
+			break
+		}
+		m.simulate()
+		result.Iterations++
+	}
+	if result.Iterations == 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	best := m.root.edges[0]
+	for _, e := range m.root.edges[1:] {
+		if e.visits > best.visits || (e.visits == best.visits && e.mean() > best.mean()) {
+			best = e
+		}
+	}
+	result.Move, result.Visits, result.Expected = best.move, best.visits, best.mean()
+	return result, nil
+}
+
+func (e *mctsEdge) mean() float64 {
+	if e.visits == 0 {
+		return math.Inf(-1)
+	}
+	return e.total / float64(e.visits)
+}
+
+// Runs one simulation from the root, adding a node to the tree.
+func (m *MCTS) simulate() {
+	var path []*mctsEdge
+	var scores []float64
+
+	n := m.root
+	for {
+		n = m.deal(n)
+		if n == nil {
+			break
+		}
+		n.visits++
+		m.expand(n)
+		if len(n.edges) == 0 {
+			scores = append(scores, lossScore)
+			n = nil
+			break
+		}
+
+		e := m.choose(n)
+		path = append(path, e)
+		scores = append(scores, e.score)
+		fresh := e.child == nil
+		if fresh {
+			e.child = m.after(n, e)
+		}
+		n = e.child
+		if fresh {
+			break
+		}
+	}
+
+	if n != nil {
+		scores = append(scores, m.rollout(n)...)
+	}
+
+	// Each edge is credited with its own score and every one after it.
+	total := 0.0
+	for i := len(scores) - 1; i >= 0; i-- {
+		total += scores[i]
+		if i < len(path) {
+			path[i].visits++
+			path[i].total += total
+		}
+	}
+}
+
+// Returns the decision node a simulation reaches from the given node: the node
+// itself for a decision node, otherwise the child for a kind dealt from its
+// distribution.
+func (m *MCTS) deal(n *mctsNode) *mctsNode {
+	if n.current != "" {
+		return n
+	}
+
+	i := m.sample(n.random)
+	if n.dealt[i] == nil {
+		random := n.random.Clone()
+		random.Advance(tetris.Kinds[i])
+		n.dealt[i] = &mctsNode{board: n.board, current: tetris.Kinds[i], held: n.held, random: random}
+	}
+	return n.dealt[i]
+}
+
+// Picks a kind from the randomizer's distribution, returning its index in
+// Kinds.
+func (m *MCTS) sample(random tetris.Randomizer) int {
+	d := random.Distribution()
+	roll := m.rand.Float64()
+	i := 0
+	for ; i < len(d)-1; i++ {
+		if roll < d[i] {
+			break
+		}
+		roll -= d[i]
+	}
+
+	// Rounding can leave the roll past the last kind with any chance.
+	for d[i] == 0 {
+		i--
+	}
+	return i
+}
+
+// Finds the placements of a decision node if they are not yet known.
+func (m *MCTS) expand(n *mctsNode) {
+	if n.edges != nil || n.current == "" {
+		return
+	}
+
+	n.edges = make([]*mctsEdge, 0)
+	m.addEdges(n, n.current, 0, false)
+	if !m.config.Hold {
+		return
+	}
+	if n.held != "" {
+		if n.held != n.current {
+			m.addEdges(n, n.held, 0, true)
+		}
+	} else if len(n.queue) > 0 {
+		m.addEdges(n, n.queue[0], 1, true)
+	}
+	sortEdges(n)
+}
+
+// Orders a node's edges so untried moves are tried best scoring first.
+func sortEdges(n *mctsNode) {
+	sort.SliceStable(n.edges, func(i, j int) bool {
+		return n.edges[i].score > n.edges[j].score
+	})
+}
+
+func (m *MCTS) addEdges(n *mctsNode, kind string, skip int, hold bool) {
+	rs := m.config.Rotation
+	orient, row, col := rs.Spawn(kind, n.board.Width())
+	for _, p := range tetris.FindPlacements(rs, n.board, kind, orient, row, col) {
+		score := m.config.Evaluator.Score(n.board, p)
+		n.edges = append(n.edges, &mctsEdge{move: Move{hold, p}, score: score, skip: skip})
+	}
+}
+
+// Picks the edge to follow from a decision node with UCT, taking any untried
+// edge first.
+func (m *MCTS) choose(n *mctsNode) *mctsEdge {
+	var best *mctsEdge
+	bestValue := math.Inf(-1)
+	for _, e := range n.edges {
+		if e.visits == 0 {
+			return e
+		}
+		value := e.mean() + m.config.Exploration*math.Sqrt(math.Log(float64(n.visits))/float64(e.visits))
+		if value > bestValue {
+			best, bestValue = e, value
+		}
+	}
+	return best
+}
+
+// Returns the node reached by following an edge.
+func (m *MCTS) after(n *mctsNode, e *mctsEdge) *mctsNode {
+	held := n.held
+	if e.move.Hold {
+		held = n.current
+	}
+
+	child := &mctsNode{board: e.move.Placement.Board, held: held, queue: n.queue[e.skip:], random: n.random}
+	if len(child.queue) > 0 {
+		child.current, child.queue = child.queue[0], child.queue[1:]
+	}
+	return child
+}
+
+// Plays on from a node with the default policy, returning the score of each
+// placement.  The rollout ends early if a piece cannot be placed.
+func (m *MCTS) rollout(n *mctsNode) []float64 {
+	board, current, queue, random := n.board, n.current, n.queue, n.random
+	var scores []float64
+	for i := 0; i < m.config.RolloutDepth; i++ {
+		if current == "" {
+			if len(queue) > 0 {
+				current, queue = queue[0], queue[1:]
+			} else {
+				current = tetris.Kinds[m.sample(random)]
+				random = random.Clone()
+				random.Advance(current)
+			}
+		}
+
+		p, score := m.drop(board, current)
+		if p == nil {
+			scores = append(scores, lossScore)
+			break
+		}
+		scores = append(scores, score)
+		board, current = p.Board, ""
+	}
+	return scores
+}
+
+// Returns the best scoring placement reached by rotating and shifting a piece
+// at the top of the board then dropping it, or nil if there is none.
+func (m *MCTS) drop(b *tetris.Board, kind string) (*tetris.Placement, float64) {
+	rs := m.config.Rotation
+	_, row, _ := rs.Spawn(kind, b.Width())
+	pr, pc := rs.Pivot(kind)
+
+	var best *tetris.Placement
+	bestScore := math.Inf(-1)
+	for orient := 0; orient < rs.NumStates(kind); orient++ {
+		t, _ := tetris.NewTetrominoFor(rs, kind, orient)
+		for col := -2; col < b.Width()+2; col++ {
+			if tetris.CheckPlacement(rs, b, t, row, col) != nil {
+				continue
+			}
+			placed, landed, err := tetris.PlaceInLastRow(rs, b, t, row, col)
+			if err != nil {
+				continue
+			}
+
+			p := &tetris.Placement{Kind: kind, Orient: orient, Row: landed, Col: col, Board: placed}
+			cell := 0
+			for i := 0; i < 4; i++ {
+				for j := 0; j < 4; j++ {
+					if t.Data()[i][j] {
+						p.Cells[cell] = [2]int{landed - pr + i, col - pc + j}
+						cell++
+					}
+				}
+			}
+			if p.Cells[0][0] < 0 {
+				continue
+			}
+			p.Lines = tetris.ClearFullLines(placed)
+
+			if score := m.config.Evaluator.Score(b, p); score > bestScore {
+				best, bestScore = p, score
+			}
+		}
+	}
+	return best, bestScore
+}
+
+// Finds the node for a state among the root and the positions one move from
+// it, or nil if the tree does not hold it.
+func (m *MCTS) find(state State) *mctsNode {
+	if m.root == nil {
+		return nil
+	}
+	if matches(m.root, state) {
+		return m.root
+	}
+	for _, e := range m.root.edges {
+		if e.child == nil {
+			continue
+		}
+		if matches(e.child, state) {
+			return e.child
+		}
+		for _, dealt := range e.child.dealt {
+			if dealt != nil && matches(dealt, state) {
+				return dealt
+			}
+		}
+	}
+	return nil
+}
+
+// Returns true if the node is at the state with every piece it knows of
+// matching the start of the state's queue.
+func matches(n *mctsNode, state State) bool {
+	if n.current != state.Active || n.held != state.Held || len(n.queue) > len(state.Queue) ||
+		!n.board.Equal(state.Board) {
+		return false
+	}
+	for i, kind := range n.queue {
+		if state.Queue[i] != kind {
+			return false
+		}
+	}
+	return true
+}
+
+// Brings a subtree up to date with pieces that have since become known.  The
+// queue holds the pieces known to follow the node's current one, and random
+// deals the pieces after them.  Chance nodes for a known piece are replaced by
+// the branch that dealt it, and the other branches are dropped.  A node
+// expanded with an empty hold slot before its next piece was known gains the
+// moves holding into that piece.
+func (m *MCTS) reveal(n *mctsNode, queue []string, random tetris.Randomizer) {
+	canHold := m.config.Hold && n.edges != nil && n.held == "" && len(n.queue) == 0 && len(queue) > 0
+	n.queue, n.random = queue, random
+	if canHold {
+		m.addEdges(n, queue[0], 1, true)
+		sortEdges(n)
+	}
+	for _, e := range n.edges {
+		if e.child == nil {
+			continue
+		}
+
+		rest := queue[e.skip:]
+		child := e.child
+		if child.current == "" {
+			if len(rest) == 0 {
+				child.random = random
+				continue
+			}
+			chance := child
+			child = nil
+			if i := tetris.KindIndex(rest[0]); i >= 0 {
+				child = chance.dealt[i]
+			}
+			e.child = child
+			if child == nil {
+				continue
+			}
+		}
+		m.reveal(child, rest[1:], random)
+	}
+}
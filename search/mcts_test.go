@@ -0,0 +1,155 @@
+package search
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/paulcoyle/tetris"
+)
+
+func newTestMCTS(t *testing.T, config MCTSConfig) *MCTS {
+	m, err := NewMCTS(config)
+	if err != nil {
+		t.Fatalf("MCTS should be created: %s", err)
+	}
+	return m
+}
+
+func testMCTSConfig() MCTSConfig {
+	config := DefaultMCTSConfig()
+	config.Iterations = 200
+	config.RolloutDepth = 2
+	return config
+}
+
+func TestNewMCTSChecksConfig(t *testing.T) {
+	config := DefaultMCTSConfig()
+	config.Rotation = nil
+	if _, err := NewMCTS(config); err == nil {
+		t.Error("A search without a rotation system should be an error")
+	}
+
+	config = DefaultMCTSConfig()
+	config.Evaluator = nil
+	if _, err := NewMCTS(config); err == nil {
+		t.Error("A search without an evaluator should be an error")
+	}
+
+	config = DefaultMCTSConfig()
+	config.Iterations = 0
+	if _, err := NewMCTS(config); err == nil {
+		t.Error("A search without a budget should be an error")
+	}
+}
+
+func TestMCTSTakesTetris(t *testing.T) {
+	m := newTestMCTS(t, testMCTSConfig())
+	result, err := m.Search(State{Board: wellBoard(), Active: "I", Queue: []string{"O"}})
+	if err != nil {
+		t.Fatalf("Search should succeed: %s", err)
+	}
+	if result.Move.Placement.Lines != 4 {
+		t.Errorf("The I should go down the well, went to %v", result.Move.Placement.Cells)
+	}
+	if result.Iterations != 200 || result.Visits == 0 {
+		t.Errorf("The search should run its budget, ran %d", result.Iterations)
+	}
+}
+
+func TestMCTSIsDeterministic(t *testing.T) {
+	state := State{Board: wellBoard(), Active: "T", Randomizer: tetris.NewBagRandomizer(3)}
+	a, _ := newTestMCTS(t, testMCTSConfig()).Search(state)
+	b, _ := newTestMCTS(t, testMCTSConfig()).Search(state)
+	if a.Visits != b.Visits || a.Expected != b.Expected || a.Move.Placement.Cells != b.Move.Placement.Cells {
+		t.Error("Searches with the same seed should give the same result")
+	}
+}
+
+func TestMCTSReusesTree(t *testing.T) {
+	config := testMCTSConfig()
+	config.Hold = false
+	m := newTestMCTS(t, config)
+
+	board, _ := tetris.NewBoard(10, 20)
+	random := tetris.NewBagRandomizer(8)
+	state := State{Board: board, Active: random.Next(), Queue: []string{random.Next()}, Randomizer: random}
+
+	first, _ := m.Search(state)
+	if first.Reused {
+		t.Error("The first search should build a new tree")
+	}
+
+	// Play the chosen move and reveal the next preview.
+	next := State{Board: first.Move.Placement.Board, Active: state.Queue[0], Randomizer: random.Clone()}
+	next.Queue = []string{next.Randomizer.Next()}
+	second, _ := m.Search(next)
+	if !second.Reused {
+		t.Error("The tree should be kept when the dealt piece was sampled")
+	}
+
+	other, _ := tetris.NewBoard(10, 20)
+	other.SetRow(19, true)
+	third, _ := m.Search(State{Board: other, Active: "T"})
+	if third.Reused {
+		t.Error("A state outside the tree should start a new one")
+	}
+}
+
+func TestMCTSRevealAddsHold(t *testing.T) {
+	m := newTestMCTS(t, testMCTSConfig())
+	board, _ := tetris.NewBoard(10, 20)
+	state := State{Board: board, Active: "T", Randomizer: tetris.NewBagRandomizer(5)}
+	if _, err := m.Search(state); err != nil {
+		t.Fatalf("Search should succeed: %s", err)
+	}
+
+	// The same state with its next piece now known is found in the tree.
+	state.Queue = []string{"I"}
+	result, err := m.Search(state)
+	if err != nil || !result.Reused {
+		t.Fatalf("The tree should be kept when the queue is revealed: %v", err)
+	}
+	held := 0
+	for _, e := range m.root.edges {
+		if e.move.Hold {
+			held++
+			if e.move.Placement.Kind != "I" || e.visits == 0 {
+				t.Errorf("A hold move should place the revealed I and be tried, placed %s in %d visits", e.move.Placement.Kind, e.visits)
+				break
+			}
+		}
+	}
+	if held == 0 {
+		t.Error("Revealing the next piece should add the moves that hold")
+	}
+}
+
+func TestMCTSTimeBudget(t *testing.T) {
+	config := testMCTSConfig()
+	config.Iterations = 0
+	config.Time = 50 * time.Millisecond
+	m := newTestMCTS(t, config)
+
+	result, err := m.Search(State{Board: wellBoard(), Active: "T"})
+	if err != nil || result.Iterations == 0 {
+		t.Errorf("A timed search should run some simulations, ran %d: %v", result.Iterations, err)
+	}
+}
+
+func TestMCTSErrors(t *testing.T) {
+	m := newTestMCTS(t, testMCTSConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.SearchContext(ctx, State{Board: wellBoard(), Active: "T"}); err != context.Canceled {
+		t.Errorf("A cancelled search should return the context's error, got %v", err)
+	}
+
+	board, _ := tetris.NewBoard(10, 4)
+	board.SetRow(0, true)
+	board.SetRow(1, true)
+	if _, err := m.Search(State{Board: board, Active: "T"}); err == nil {
+		t.Error("A piece that cannot spawn should be an error")
+	}
+}
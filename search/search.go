@@ -0,0 +1,368 @@
+// Package search chooses where to place tetrominoes by looking ahead through
+// the preview queue.
+package search
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/paulcoyle/tetris"
+)
+
+// Settings for a search.
+type Config struct {
+	Rotation  tetris.RotationSystem
+	Evaluator *tetris.Evaluator
+
+	// The number of positions kept after each piece is placed.
+	BeamWidth int
+
+	// The number of pieces to place, including the active one.  The search
+	// stops early if the queue runs out.
+	Depth int
+
+	// Whether to consider swapping pieces with the hold slot.
+	Hold bool
+
+	// The number of goroutines placing pieces at once.  Values of 1 or less
+	// search on the calling goroutine.  The result does not depend on it.
+	Workers int
+}
+
+// Returns a beam search of width 16 and depth 3 using hold, the SRS and the
+// El-Tetris weights, with a worker for each CPU.
+func DefaultConfig() Config {
+	return Config{
+		Rotation:  tetris.SRS,
+		Evaluator: tetris.NewEvaluator(tetris.ElTetrisWeights),
+		BeamWidth: 16,
+		Depth:     3,
+		Hold:      true,
+		Workers:   runtime.NumCPU(),
+	}
+}
+
+// The position to search from.
+type State struct {
+	Board  *tetris.Board
+	Active string   // the kind of piece in play
+	Held   string   // the kind in the hold slot, "" when empty
+	Queue  []string // the previews, next first
+
+	// Deals the pieces after the queue.  Only Expectimax uses it, looking at
+	// clones of it to weigh what could come next.  When nil every kind is
+	// taken to be equally likely.
+	Randomizer tetris.Randomizer
+}
+
+// A choice of what to do with the active piece.
+type Move struct {
+	// Whether to hold first.  The placement is then of the held piece, or of
+	// the next in the queue when the hold slot was empty.
+	Hold bool
+
+	Placement *tetris.Placement
+}
+
+// The outcome of a search.
+type Result struct {
+	Move  Move
+	Score float64 // the sum of the evaluations along the best line found
+	Depth int     // the number of pieces placed along the best line
+	Nodes int     // the number of positions evaluated
+	Hits  int     // the number of boards whose placements were cached
+
+	// Hits can vary between searches with more than one worker, as workers
+	// may both miss on a board the other is about to cache.
+}
+
+// Finds moves with a beam search: the positions after each piece are scored
+// by summing the evaluator's scores of the placements leading to them and only
+// the best BeamWidth go on to place the next piece.
+type Searcher struct {
+	config Config
+}
+
+// Creates a searcher with the given settings.
+func NewSearcher(config Config) (*Searcher, error) {
+	if config.Rotation == nil {
+		return nil, fmt.Errorf("A search needs a rotation system!")
+	}
+	if config.Evaluator == nil {
+		return nil, fmt.Errorf("A search needs an evaluator!")
+	}
+	if config.BeamWidth < 1 {
+		return nil, fmt.Errorf("Beam width %d is less than 1!", config.BeamWidth)
+	}
+	if config.Depth < 1 {
+		return nil, fmt.Errorf("Depth %d is less than 1!", config.Depth)
+	}
+	return &Searcher{config}, nil
+}
+
+func (s *Searcher) Config() Config {
+	return s.config
+}
+
+// Returns the best move for the state.  The same state always gives the same
+// result.  An error is returned if the active piece cannot be placed.
+func (s *Searcher) Search(state State) (*Result, error) {
+	return s.SearchContext(context.Background(), state)
+}
+
+// Returns the best move for the state like Search, stopping early when the
+// context is done.  The positions after each piece are only compared once
+// all of them have been found, so a search cut short returns the best move
+// of the deepest level it finished, with Depth saying which.  An error is
+// returned only if the first level was not finished.
+func (s *Searcher) SearchContext(ctx context.Context, state State) (*Result, error) {
+	if state.Board == nil {
+		return nil, fmt.Errorf("A search needs a board!")
+	}
+
+	result := &Result{}
+	c := &cache{entries: make(map[uint64][]scored)}
+	beam := []*node{{board: state.Board, current: state.Active, held: state.Held}}
+	for depth := 1; depth <= s.config.Depth; depth++ {
+		levels, ok := s.expandAll(ctx, c, beam, state.Queue)
+		if !ok {
+			break
+		}
+
+		var children []*node
+		for _, level := range levels {
+			children = append(children, level...)
+		}
+		result.Nodes += len(children)
+		if len(children) == 0 {
+			break
+		}
+
+		sort.SliceStable(children, func(i, j int) bool {
+			return children[i].score > children[j].score
+		})
+		beam = s.prune(children, state.Queue)
+
+		best := beam[0]
+		result.Move, result.Score, result.Depth = best.first, best.score, depth
+		if best.current == "" {
+			break
+		}
+	}
+	result.Hits = c.hits
+
+	if result.Depth == 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("No placement found for %s!", state.Active)
+	}
+	return result, nil
+}
+
+// Expands every node of the beam, spreading them over the workers.  The
+// children of each node are returned in the beam's order, so the outcome is
+// the same however many workers there are.  Returns false if the context was
+// done before all were expanded.
+func (s *Searcher) expandAll(ctx context.Context, c *cache, beam []*node, queue []string) ([][]*node, bool) {
+	levels := make([][]*node, len(beam))
+	done := s.each(ctx, len(beam), func(i int) {
+		levels[i] = s.expand(c, beam[i], queue)
+	})
+	return levels, done
+}
+
+// Calls job for every number from 0 to n-1 on up to Workers goroutines,
+// returning once all have finished.  Returns false if the context was done
+// before every job was started or by the time they finished.
+func (s *Searcher) each(ctx context.Context, n int, job func(int)) bool {
+	workers := s.config.Workers
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			if ctx.Err() != nil {
+				return false
+			}
+			job(i)
+		}
+		return ctx.Err() == nil
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				job(i)
+			}
+		}()
+	}
+
+	done := true
+	for i := 0; i < n && done; i++ {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			done = false
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return done && ctx.Err() == nil
+}
+
+// Keeps the best BeamWidth of the sorted children, skipping any that reach the
+// same position as a better one.
+func (s *Searcher) prune(children []*node, queue []string) []*node {
+	beam := make([]*node, 0, s.config.BeamWidth)
+	seen := make(map[uint64]bool)
+	for _, n := range children {
+		if len(beam) == s.config.BeamWidth {
+			break
+		}
+		key := n.key(queue)
+		if !seen[key] {
+			seen[key] = true
+			beam = append(beam, n)
+		}
+	}
+	return beam
+}
+
+// A position in the search.
+type node struct {
+	board   *tetris.Board
+	current string // the piece to place next, "" when the queue is spent
+	held    string
+	next    int // index in the queue of the piece after current
+	score   float64
+	first   Move // the move made from the root to reach here
+}
+
+// Returns the transposition key of the node's position.
+func (n *node) key(queue []string) uint64 {
+	var rest []string
+	if n.next < len(queue) {
+		rest = queue[n.next:]
+	}
+	return tetris.HashState(n.board, n.current, n.held, rest)
+}
+
+// Returns the positions reached by placing the node's piece, and with hold the
+// piece it would swap for.
+func (s *Searcher) expand(c *cache, n *node, queue []string) []*node {
+	if n.current == "" {
+		return nil
+	}
+
+	children := s.place(c, n, n.current, n.held, n.next, false, queue)
+	if !s.config.Hold {
+		return children
+	}
+	if n.held != "" {
+		if n.held != n.current {
+			children = append(children, s.place(c, n, n.held, n.current, n.next, true, queue)...)
+		}
+	} else if n.next < len(queue) {
+		children = append(children, s.place(c, n, queue[n.next], n.current, n.next+1, true, queue)...)
+	}
+	return children
+}
+
+// Returns the positions reached by placing the given kind, leaving held in the
+// hold slot and the queue from next onwards.
+func (s *Searcher) place(c *cache, n *node, kind, held string, next int, hold bool, queue []string) []*node {
+	var current string
+	if next < len(queue) {
+		current = queue[next]
+	}
+
+	// The root's placements are returned to the caller so they are always
+	// made on its board rather than a cached one that only looks the same.
+	var placements []scored
+	if n.first.Placement == nil {
+		placements = s.score(n.board, kind)
+	} else {
+		placements = c.placements(s, n.board, kind)
+	}
+
+	children := make([]*node, 0, len(placements))
+	for _, sp := range placements {
+		p := sp.placement
+		child := &node{
+			board:   p.Board,
+			current: current,
+			held:    held,
+			next:    next + 1,
+			score:   n.score + sp.score,
+			first:   n.first,
+		}
+		if n.first.Placement == nil {
+			child.first = Move{hold, p}
+		}
+		children = append(children, child)
+	}
+	return children
+}
+
+// A placement and the evaluator's score for it.
+type scored struct {
+	placement *tetris.Placement
+	score     float64
+}
+
+// Returns the placements of the given kind on the board with their scores.
+func (s *Searcher) score(b *tetris.Board, kind string) []scored {
+	rs := s.config.Rotation
+	orient, row, col := rs.Spawn(kind, b.Width())
+
+	placements := tetris.FindPlacements(rs, b, kind, orient, row, col)
+	result := make([]scored, len(placements))
+	for i, p := range placements {
+		result[i] = scored{p, s.config.Evaluator.Score(b, p)}
+	}
+	return result
+}
+
+// The placements already scored during a search, shared by its workers.  The
+// same board is often reached by placing the same pieces in a different
+// order.  Entries are keyed by the HashState of the board and the kind
+// placed, which covers only which blocks are set as that is all placing and
+// scoring depends on.
+type cache struct {
+	mu      sync.Mutex
+	entries map[uint64][]scored
+	hits    int
+}
+
+// Returns the scored placements of the given kind on the board, finding them
+// if they are not yet cached.
+func (c *cache) placements(s *Searcher, b *tetris.Board, kind string) []scored {
+	key := tetris.HashState(b, kind, "", nil)
+
+	c.mu.Lock()
+	placements, ok := c.entries[key]
+	if ok {
+		c.hits++
+	}
+	c.mu.Unlock()
+	if ok {
+		return placements
+	}
+
+	// Workers racing to the same board find the same placements, so either
+	// may be kept.
+	placements = s.score(b, kind)
+	c.mu.Lock()
+	c.entries[key] = placements
+	c.mu.Unlock()
+	return placements
+}
@@ -0,0 +1,205 @@
+package search
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/paulcoyle/tetris"
+)
+
+func newTestSearcher(t *testing.T, config Config) *Searcher {
+	s, err := NewSearcher(config)
+	if err != nil {
+		t.Fatalf("Searcher should be created: %s", err)
+	}
+	return s
+}
+
+func wellBoard() *tetris.Board {
+	board, _ := tetris.StringArrayToBoard([]string{
+		"|          |",
+		"|          |",
+		"|          |",
+		"|          |",
+		"|######### |",
+		"|######### |",
+		"|######### |",
+		"|######### |",
+	})
+	return board
+}
+
+func TestNewSearcherChecksConfig(t *testing.T) {
+	config := DefaultConfig()
+	config.Rotation = nil
+	if _, err := NewSearcher(config); err == nil {
+		t.Error("A search without a rotation system should be an error")
+	}
+
+	config = DefaultConfig()
+	config.Evaluator = nil
+	if _, err := NewSearcher(config); err == nil {
+		t.Error("A search without an evaluator should be an error")
+	}
+
+	config = DefaultConfig()
+	config.BeamWidth = 0
+	if _, err := NewSearcher(config); err == nil {
+		t.Error("A search with no beam should be an error")
+	}
+
+	config = DefaultConfig()
+	config.Depth = 0
+	if _, err := NewSearcher(config); err == nil {
+		t.Error("A search with no depth should be an error")
+	}
+}
+
+func TestSearchTakesTetris(t *testing.T) {
+	s := newTestSearcher(t, DefaultConfig())
+	result, err := s.Search(State{Board: wellBoard(), Active: "I", Queue: []string{"O", "T"}})
+	if err != nil {
+		t.Fatalf("Search should succeed: %s", err)
+	}
+	if result.Move.Hold || result.Move.Placement.Lines != 4 {
+		t.Errorf("The I should go down the well, went to %v", result.Move.Placement.Cells)
+	}
+	if result.Depth != 3 {
+		t.Errorf("The search should place 3 pieces, placed %d", result.Depth)
+	}
+}
+
+func TestSearchHoldsForBetterPiece(t *testing.T) {
+	s := newTestSearcher(t, DefaultConfig())
+	result, err := s.Search(State{Board: wellBoard(), Active: "S", Held: "I"})
+	if err != nil {
+		t.Fatalf("Search should succeed: %s", err)
+	}
+	if !result.Move.Hold || result.Move.Placement.Kind != "I" {
+		t.Error("The search should hold the S and place the I")
+	}
+
+	config := DefaultConfig()
+	config.Hold = false
+	s = newTestSearcher(t, config)
+	result, _ = s.Search(State{Board: wellBoard(), Active: "S", Held: "I"})
+	if result.Move.Hold || result.Move.Placement.Kind != "S" {
+		t.Error("Without hold the search should place the S")
+	}
+}
+
+func TestSearchHoldsIntoEmptySlot(t *testing.T) {
+	s := newTestSearcher(t, DefaultConfig())
+	result, _ := s.Search(State{Board: wellBoard(), Active: "S", Queue: []string{"I"}})
+	if !result.Move.Hold || result.Move.Placement.Kind != "I" {
+		t.Error("The search should hold the S and place the I from the queue")
+	}
+}
+
+func TestSearchIsDeterministic(t *testing.T) {
+	board, _ := tetris.NewBoard(10, 20)
+	state := State{Board: board, Active: "T", Queue: []string{"S", "Z", "L", "J"}}
+	config := DefaultConfig()
+	config.Depth = 5
+
+	a, _ := newTestSearcher(t, config).Search(state)
+	b, _ := newTestSearcher(t, config).Search(state)
+	if a.Score != b.Score || a.Nodes != b.Nodes || a.Move.Hold != b.Move.Hold ||
+		a.Move.Placement.Cells != b.Move.Placement.Cells {
+		t.Error("Searching the same state should give the same result")
+	}
+}
+
+func TestSearchStopsAtEndOfQueue(t *testing.T) {
+	board, _ := tetris.NewBoard(10, 20)
+	config := DefaultConfig()
+	config.Depth = 10
+	config.Hold = false
+
+	result, _ := newTestSearcher(t, config).Search(State{Board: board, Active: "T", Queue: []string{"O"}})
+	if result.Depth != 2 {
+		t.Errorf("The search should stop after the queue, placed %d", result.Depth)
+	}
+}
+
+func TestSearchWithNoPlacement(t *testing.T) {
+	board, _ := tetris.NewBoard(10, 4)
+	board.SetRow(0, true)
+	board.SetRow(1, true)
+
+	s := newTestSearcher(t, DefaultConfig())
+	if _, err := s.Search(State{Board: board, Active: "T"}); err == nil {
+		t.Error("A piece that cannot spawn should be an error")
+	}
+	if _, err := s.Search(State{Active: "T"}); err == nil {
+		t.Error("A search without a board should be an error")
+	}
+}
+
+func randomState(seed int64, pieces int) State {
+	board, _ := tetris.NewBoard(10, 20)
+	random := tetris.NewBagRandomizer(seed)
+	state := State{Board: board, Active: random.Next()}
+	for i := 0; i < pieces; i++ {
+		state.Queue = append(state.Queue, random.Next())
+	}
+	return state
+}
+
+func TestParallelSearchMatchesSequential(t *testing.T) {
+	config := DefaultConfig()
+	config.Depth = 4
+	config.Workers = 1
+	sequential := newTestSearcher(t, config)
+	config.Workers = 8
+	parallel := newTestSearcher(t, config)
+
+	for seed := int64(1); seed <= 5; seed++ {
+		state := randomState(seed, 5)
+		a, errA := sequential.Search(state)
+		b, errB := parallel.Search(state)
+		if errA != nil || errB != nil {
+			t.Fatalf("Searches should succeed: %v, %v", errA, errB)
+		}
+		if a.Score != b.Score || a.Nodes != b.Nodes || a.Depth != b.Depth ||
+			a.Move.Hold != b.Move.Hold || a.Move.Placement.Cells != b.Move.Placement.Cells {
+			t.Errorf("Seed %d should give the same result with workers", seed)
+		}
+	}
+}
+
+func TestSearchCachesTranspositions(t *testing.T) {
+	board, _ := tetris.NewBoard(10, 20)
+	s := newTestSearcher(t, DefaultConfig())
+	result, _ := s.Search(State{Board: board, Active: "O", Queue: []string{"O", "T"}})
+	if result.Hits == 0 {
+		t.Error("Placing either O should reach a board already searched")
+	}
+}
+
+func TestSearchContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s := newTestSearcher(t, DefaultConfig())
+	if _, err := s.SearchContext(ctx, randomState(1, 5)); err != context.Canceled {
+		t.Errorf("A cancelled search should return the context's error, got %v", err)
+	}
+}
+
+func TestSearchContextReturnsBestSoFar(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	config := DefaultConfig()
+	config.BeamWidth = 200
+	config.Depth = 1000
+	result, err := newTestSearcher(t, config).SearchContext(ctx, randomState(1, 1000))
+	if err != nil {
+		t.Fatalf("A search past its deadline should return its best so far: %s", err)
+	}
+	if result.Depth < 1 || result.Depth >= config.Depth || result.Move.Placement == nil {
+		t.Errorf("The search should stop part way, placed %d", result.Depth)
+	}
+}
@@ -0,0 +1,115 @@
+package tetris
+
+// Classifies how a T tetromino locking at (row, col) on the board was spun
+// into place using the 3-corner rule.  Only a T whose last action was a
+// rotation can spin.  If at least three of the four blocks diagonal to the T's
+// centre are set (the walls and floor count as set) the lock is a spin: a full
+// one when both corners either side of the T's point are set, otherwise a
+// mini.  A mini is upgraded to a full spin when the rotation used the fifth
+// kick test, as with the SRS T-spin triple kick.  The board must not yet hold
+// the locked tetromino.
+func DetectTSpin(rs RotationSystem, b *Board, t *Tetromino, row, col int, last Action, kick int) Spin {
+	if t.Kind() != "T" || !isRotation(last) {
+		return SpinNone
+	}
+
+	ci, cj, di, dj, ok := tCentre(t.Data())
+	if !ok {
+		return SpinNone
+	}
+	pr, pc := rs.Pivot(t.Kind())
+	r, c := row-pr+ci, col-pc+cj
+
+	corners := 0
+	for _, dr := range []int{-1, 1} {
+		for _, dc := range []int{-1, 1} {
+			if cornerSet(b, r+dr, c+dc) {
+				corners++
+			}
+		}
+	}
+	if corners < 3 {
+		return SpinNone
+	}
+
+	// The front corners flank the block the T points with.
+	var front int
+	if di != 0 {
+		front = countSet(b, [2]int{r + di, c - 1}, [2]int{r + di, c + 1})
+	} else {
+		front = countSet(b, [2]int{r - 1, c + dj}, [2]int{r + 1, c + dj})
+	}
+	if front == 2 || kick == 4 {
+		return SpinFull
+	}
+	return SpinMini
+}
+
+// Classifies a lock like DetectTSpin.  With allSpin set, any other kind of
+// tetromino that was rotated into a place it can't move left, right or up
+// out of also counts, as a mini spin.
+func DetectSpin(rs RotationSystem, b *Board, t *Tetromino, row, col int, last Action, kick int, allSpin bool) Spin {
+	if t.Kind() == "T" {
+		return DetectTSpin(rs, b, t, row, col, last, kick)
+	}
+	if !allSpin || !isRotation(last) {
+		return SpinNone
+	}
+
+	if CheckPlacement(rs, b, t, row, col-1) == nil ||
+		CheckPlacement(rs, b, t, row, col+1) == nil ||
+		CheckPlacement(rs, b, t, row-1, col) == nil {
+		return SpinNone
+	}
+	return SpinMini
+}
+
+func isRotation(a Action) bool {
+	return a == RotateCW || a == RotateCCW || a == Rotate180
+}
+
+// Finds the centre of a T shape, the block with three neighbours, and the
+// direction of the one it points with.
+func tCentre(d *TetrominoData) (int, int, int, int, bool) {
+	set := func(i, j int) bool {
+		return i >= 0 && i < 4 && j >= 0 && j < 4 && d[i][j]
+	}
+	dirs := [4][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
+
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			if !d[i][j] {
+				continue
+			}
+			for _, dir := range dirs {
+				if set(i+dir[0], j+dir[1]) && !set(i-dir[0], j-dir[1]) &&
+					set(i+dir[1], j+dir[0]) && set(i-dir[1], j-dir[0]) {
+					return i, j, dir[0], dir[1], true
+				}
+			}
+		}
+	}
+	return 0, 0, 0, 0, false
+}
+
+// Returns true if the block is set or lies beyond the walls or floor.  Blocks
+// above the board are open.
+func cornerSet(b *Board, row, col int) bool {
+	if row < 0 {
+		return false
+	}
+	if col < 0 || col >= b.Width() || row >= b.Height() {
+		return true
+	}
+	return b.isSet(row, col)
+}
+
+func countSet(b *Board, blocks ...[2]int) int {
+	n := 0
+	for _, blk := range blocks {
+		if cornerSet(b, blk[0], blk[1]) {
+			n++
+		}
+	}
+	return n
+}
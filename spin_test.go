@@ -0,0 +1,156 @@
+package tetris
+
+import (
+	"testing"
+)
+
+func tsdBoard() *Board {
+	board, _ := StringArrayToBoard([]string{
+		"|          |",
+		"|          |",
+		"|###       |",
+		"|##   #####|",
+		"|### ######|",
+	})
+	return board
+}
+
+func TestTSpinDouble(t *testing.T) {
+	tet, _ := NewTetrominoFor(SRS, "T", 2)
+	if spin := DetectTSpin(SRS, tsdBoard(), tet, 3, 3, RotateCW, 0); spin != SpinFull {
+		t.Errorf("Rotating into the slot should be a full T-spin, was %s", spin)
+	}
+}
+
+func TestTSpinNeedsRotation(t *testing.T) {
+	tet, _ := NewTetrominoFor(SRS, "T", 2)
+	for _, last := range []Action{MoveLeft, SoftDrop, HardDrop} {
+		if spin := DetectTSpin(SRS, tsdBoard(), tet, 3, 3, last, -1); spin != SpinNone {
+			t.Errorf("A T last moved by %s should not spin, was %s", last, spin)
+		}
+	}
+}
+
+func TestTSpinNeedsThreeCorners(t *testing.T) {
+	board, _ := StringArrayToBoard([]string{
+		"|          |",
+		"|          |",
+		"|          |",
+		"|##   #####|",
+		"|### ######|",
+	})
+	tet, _ := NewTetrominoFor(SRS, "T", 2)
+	if spin := DetectTSpin(SRS, board, tet, 3, 3, RotateCW, 0); spin != SpinNone {
+		t.Errorf("Two corners should not be a spin, was %s", spin)
+	}
+}
+
+func miniBoard() *Board {
+	board, _ := StringArrayToBoard([]string{
+		"|    |",
+		"|    |",
+		"|    |",
+		"|    |",
+		"| ###|",
+	})
+	return board
+}
+
+func TestTSpinMini(t *testing.T) {
+	tet, _ := NewTetrominoFor(SRS, "T", 1)
+	if spin := DetectTSpin(SRS, miniBoard(), tet, 3, 0, RotateCCW, 0); spin != SpinMini {
+		t.Errorf("Only one front corner should be a mini, was %s", spin)
+	}
+}
+
+func TestTSpinMiniUpgradedByFifthKick(t *testing.T) {
+	tet, _ := NewTetrominoFor(SRS, "T", 1)
+	if spin := DetectTSpin(SRS, miniBoard(), tet, 3, 0, RotateCCW, 4); spin != SpinFull {
+		t.Errorf("A mini using the fifth kick should be full, was %s", spin)
+	}
+}
+
+func TestTSpinInOtherSystems(t *testing.T) {
+	tet, _ := NewTetrominoFor(ARS, "T", 0)
+	if spin := DetectTSpin(ARS, tsdBoard(), tet, 3, 3, RotateCW, 0); spin != SpinFull {
+		t.Errorf("ARS T pointing down into the slot should be full, was %s", spin)
+	}
+}
+
+func TestTCentreFollowsShape(t *testing.T) {
+	// ARS keeps the upturned T on the bottom of its box, so its centre is
+	// below the pivot.
+	tet, _ := NewTetrominoFor(ARS, "T", 2)
+	i, j, di, dj, ok := tCentre(tet.Data())
+	if !ok || i != 2 || j != 2 || di != -1 || dj != 0 {
+		t.Errorf("Upturned ARS T should centre on (2,2) pointing up, got (%d,%d) pointing (%d,%d)", i, j, di, dj)
+	}
+}
+
+func wellBoard() *Board {
+	board, _ := StringArrayToBoard([]string{
+		"|###|",
+		"|# #|",
+		"|# #|",
+		"|# #|",
+		"|# #|",
+	})
+	return board
+}
+
+func TestAllSpinImmobile(t *testing.T) {
+	tet, _ := NewTetrominoFor(SRS, "I", 1)
+	if spin := DetectSpin(SRS, wellBoard(), tet, 2, 1, RotateCW, 2, true); spin != SpinMini {
+		t.Errorf("An immobile I should be an all-spin mini, was %s", spin)
+	}
+	if spin := DetectSpin(SRS, wellBoard(), tet, 2, 1, RotateCW, 2, false); spin != SpinNone {
+		t.Errorf("Without all-spin only T can spin, was %s", spin)
+	}
+	if spin := DetectSpin(SRS, wellBoard(), tet, 2, 1, MoveRight, -1, true); spin != SpinNone {
+		t.Errorf("An all-spin needs a rotation, was %s", spin)
+	}
+}
+
+func TestAllSpinMobile(t *testing.T) {
+	board, _ := NewBoard(5, 5)
+	tet, _ := NewTetrominoFor(SRS, "S", 0)
+	if spin := DetectSpin(SRS, board, tet, 4, 2, RotateCW, 0, true); spin != SpinNone {
+		t.Errorf("A piece free to move should not spin, was %s", spin)
+	}
+}
+
+func TestGameScoresTSpinDouble(t *testing.T) {
+	config := DefaultGameConfig(1)
+	config.Board = tsdBoard()
+	config.Randomizer = &fixedRandomizer{kinds: []string{"T"}}
+	g := newTestGame(t, config)
+
+	g.RotateCW()
+	g.MoveLeft()
+	g.SoftDrop()
+	g.SoftDrop()
+	g.RotateCW()
+	res := g.HardDrop()
+
+	if res.Lock == nil || res.Lock.Spin != SpinFull || res.Lock.Lines != 2 {
+		t.Fatalf("Expected a T-spin double, got %+v", res.Lock)
+	}
+	if res.Lock.Points != 1200 {
+		t.Errorf("T-spin double should score 1200, scored %d", res.Lock.Points)
+	}
+}
+
+func TestGameDropAfterRotationIsNotASpin(t *testing.T) {
+	config := DefaultGameConfig(1)
+	config.Board = tsdBoard()
+	config.Randomizer = &fixedRandomizer{kinds: []string{"T"}}
+	g := newTestGame(t, config)
+
+	g.RotateCW()
+	g.MoveLeft()
+	g.RotateCCW()
+	res := g.HardDrop()
+	if res.Lock == nil || res.Lock.Spin != SpinNone {
+		t.Errorf("Falling after the rotation should not spin, got %+v", res.Lock)
+	}
+}
@@ -0,0 +1,66 @@
+package tetris
+
+// The four rotation states of every tetromino under the Super Rotation System
+// in the same integerial format as tet_orients.  States are ordered spawn (0),
+// right (R), two (2) and left (L), so incrementing the orientation rotates
+// clockwise.  JLSTZ live in the 3x3 box whose centre sits on the pivot, I in
+// the full 4x4 box and O, which never moves, in the top of it.
+var srs_orients = map[string][]int{
+	"O": {0x6600, 0x6600, 0x6600, 0x6600},
+	"I": {0x0f00, 0x2222, 0x00f0, 0x4444},
+	"S": {0x3600, 0x2310, 0x0360, 0x4620},
+	"Z": {0x6300, 0x1320, 0x0630, 0x2640},
+	"L": {0x1700, 0x2230, 0x0740, 0x6220},
+	"J": {0x4700, 0x3220, 0x0710, 0x2260},
+	"T": {0x2700, 0x2320, 0x0720, 0x2620},
+}
+
+// The SRS wall kick tests in the (x, y) notation of the guideline where y
+// points up, indexed by the state rotated from then the state rotated to.
+var srs_jlstz_tests = [4][4][][2]int{
+	0: {1: {{0, 0}, {-1, 0}, {-1, 1}, {0, -2}, {-1, -2}}, 3: {{0, 0}, {1, 0}, {1, 1}, {0, -2}, {1, -2}}},
+	1: {0: {{0, 0}, {1, 0}, {1, -1}, {0, 2}, {1, 2}}, 2: {{0, 0}, {1, 0}, {1, -1}, {0, 2}, {1, 2}}},
+	2: {1: {{0, 0}, {-1, 0}, {-1, 1}, {0, -2}, {-1, -2}}, 3: {{0, 0}, {1, 0}, {1, 1}, {0, -2}, {1, -2}}},
+	3: {2: {{0, 0}, {-1, 0}, {-1, -1}, {0, 2}, {-1, 2}}, 0: {{0, 0}, {-1, 0}, {-1, -1}, {0, 2}, {-1, 2}}},
+}
+
+var srs_i_tests = [4][4][][2]int{
+	0: {1: {{0, 0}, {-2, 0}, {1, 0}, {-2, -1}, {1, 2}}, 3: {{0, 0}, {-1, 0}, {2, 0}, {-1, 2}, {2, -1}}},
+	1: {0: {{0, 0}, {2, 0}, {-1, 0}, {2, 1}, {-1, -2}}, 2: {{0, 0}, {-1, 0}, {2, 0}, {-1, 2}, {2, -1}}},
+	2: {1: {{0, 0}, {1, 0}, {-2, 0}, {1, -2}, {-2, 1}}, 3: {{0, 0}, {2, 0}, {-1, 0}, {2, 1}, {-1, -2}}},
+	3: {2: {{0, 0}, {-2, 0}, {1, 0}, {-2, -1}, {1, 2}}, 0: {{0, 0}, {1, 0}, {-2, 0}, {1, -2}, {-2, 1}}},
+}
+
+var (
+	srs_jlstz_kicks = toKicks(&srs_jlstz_tests)
+	srs_i_kicks     = toKicks(&srs_i_tests)
+)
+
+// Converts a table of (x, y) kick tests into row and column offsets.
+func toKicks(tests *[4][4][][2]int) *[4][4][]Kick {
+	var kicks [4][4][]Kick
+	for from := range tests {
+		for to, xys := range tests[from] {
+			for _, xy := range xys {
+				kicks[from][to] = append(kicks[from][to], Kick{-xy[1], xy[0]})
+			}
+		}
+	}
+	return &kicks
+}
+
+// Returns the SRS kick tests, in order, for rotating the given kind of
+// tetromino between two states.  Rotations that are not a quarter turn (and
+// any rotation of O) only test the unkicked position.
+func srsKicks(kind string, from, to int) []Kick {
+	table := srs_jlstz_kicks
+	if kind == "I" {
+		table = srs_i_kicks
+	}
+
+	kicks := table[from][to]
+	if kind == "O" || kicks == nil {
+		return noKicks
+	}
+	return kicks
+}
@@ -0,0 +1,117 @@
+package tetris
+
+import (
+	"testing"
+)
+
+func TestSRSTetrominoHasFourStates(t *testing.T) {
+	for _, kind := range []string{"I", "O", "T", "S", "Z", "J", "L"} {
+		tet, err := NewTetrominoFor(SRS, kind, 3)
+		if err != nil {
+			t.Errorf("State 3 should be valid for SRS %s", kind)
+			continue
+		}
+
+		start := *tet.Data()
+		for i := 0; i < 4; i++ {
+			tet.RotateFwd()
+		}
+		if !tet.Data().Equal(&start) || tet.Orient() != 3 {
+			t.Errorf("Four rotations of SRS %s should return to the starting state", kind)
+		}
+	}
+}
+
+func TestSRSTetrominoInvalidState(t *testing.T) {
+	tet, err := NewTetrominoFor(SRS, "S", 4)
+	if err == nil {
+		t.Error("An error should be non-nil for an invalid state")
+	}
+	if tet != nil {
+		t.Error("Returned tetromino should be nil")
+	}
+}
+
+func TestSRSKicksConvertToRowsAndCols(t *testing.T) {
+	kicks := SRS.Kicks("T", 0, 1)
+	expected := []Kick{{0, 0}, {0, -1}, {-1, -1}, {2, 0}, {2, -1}}
+	if len(kicks) != len(expected) {
+		t.Fatalf("Expected %d kicks, got %d", len(expected), len(kicks))
+	}
+	for i := range expected {
+		if kicks[i] != expected[i] {
+			t.Errorf("Kick %d should be %v, was %v", i, expected[i], kicks[i])
+		}
+	}
+
+	if len(SRS.Kicks("O", 0, 1)) != 1 {
+		t.Error("O should only test the unkicked position")
+	}
+	if len(SRS.Kicks("T", 0, 2)) != 1 {
+		t.Error("Half turns should only test the unkicked position")
+	}
+}
+
+func TestRotateSRSWithoutKick(t *testing.T) {
+	board, _ := NewBoard(10, 20)
+	tet, _ := NewTetrominoFor(SRS, "T", 0)
+
+	row, col, kick, err := Rotate(SRS, board, tet, 10, 4, 1)
+	if err != nil {
+		t.Fatal("Rotation on an empty board should succeed")
+	}
+	if row != 10 || col != 4 || kick != 0 {
+		t.Errorf("Expected (10,4) with kick 0, got (%d,%d) with kick %d", row, col, kick)
+	}
+	if tet.Orient() != 1 {
+		t.Error("Tetromino should now be in state 1")
+	}
+}
+
+func TestRotateSRSWallKick(t *testing.T) {
+	board, _ := NewBoard(10, 20)
+	tet, _ := NewTetrominoFor(SRS, "I", 1)
+
+	row, col, kick, err := Rotate(SRS, board, tet, 10, 9, 1)
+	if err != nil {
+		t.Fatal("Rotation against the right wall should kick")
+	}
+	if row != 10 || col != 8 || kick != 1 {
+		t.Errorf("Expected (10,8) with kick 1, got (%d,%d) with kick %d", row, col, kick)
+	}
+}
+
+func TestRotateSRSFloorKick(t *testing.T) {
+	board, _ := NewBoard(10, 20)
+	tet, _ := NewTetrominoFor(SRS, "T", 0)
+
+	row, col, kick, err := Rotate(SRS, board, tet, 19, 4, 1)
+	if err != nil {
+		t.Fatal("Rotation on the floor should kick")
+	}
+	if row != 18 || col != 3 || kick != 2 {
+		t.Errorf("Expected (18,3) with kick 2, got (%d,%d) with kick %d", row, col, kick)
+	}
+}
+
+func TestRotateSRSBlocked(t *testing.T) {
+	board, _ := StringArrayToBoard([]string{
+		"|###  ####|",
+		"|##    ###|",
+		"|#########|",
+		"|#########|",
+	})
+	tet, _ := NewTetrominoFor(SRS, "I", 0)
+	before := *tet
+
+	_, _, kick, err := Rotate(SRS, board, tet, 1, 4, 1)
+	if err == nil {
+		t.Error("Rotation with every kick blocked should fail")
+	}
+	if kick != -1 {
+		t.Error("No kick should be reported for a failed rotation")
+	}
+	if tet.Orient() != before.Orient() || !tet.Data().Equal(before.Data()) {
+		t.Error("A failed rotation should leave the tetromino unchanged")
+	}
+}
@@ -0,0 +1,127 @@
+package tbp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/paulcoyle/tetris"
+	"github.com/paulcoyle/tetris/search"
+)
+
+// The game as the front-end last described it.
+type State struct {
+	Board      *tetris.Board // Width by Height, row 0 at the top
+	Queue      []string      // the active piece then the previews
+	Hold       string        // "" when nothing is held
+	Combo      int
+	BackToBack bool
+}
+
+// Returns a deep copy of the state.
+func (s *State) Copy() *State {
+	c := *s
+	c.Board = s.Board.Copy()
+	c.Queue = append([]string(nil), s.Queue...)
+	return &c
+}
+
+// Builds the state from a start message.
+func newState(m *message) (*State, error) {
+	if len(m.Board) == 0 || len(m.Board[0]) == 0 {
+		return nil, fmt.Errorf("The start message has no board!")
+	}
+
+	height, width := len(m.Board), len(m.Board[0])
+	b, err := tetris.NewBoard(width, height)
+	if err != nil {
+		return nil, err
+	}
+	for y, row := range m.Board {
+		if len(row) != width {
+			return nil, fmt.Errorf("Board row %d has %d cells, expected %d!", y, len(row), width)
+		}
+		for x, cell := range row {
+			if cell != nil {
+				b.SetCell(height-1-y, x, tetris.KindCell(*cell))
+			}
+		}
+	}
+
+	s := &State{Board: b, Queue: append([]string(nil), m.Queue...), Combo: m.Combo, BackToBack: m.BackToBack}
+	if m.Hold != nil {
+		s.Hold = *m.Hold
+	}
+	return s, nil
+}
+
+// Applies a move the front-end says was played.  A move of a piece other than
+// the active one means it was held first.
+func (s *State) play(m Move) error {
+	if len(s.Queue) == 0 {
+		return fmt.Errorf("Move of %s played with an empty queue!", m.Location.Kind)
+	}
+
+	kind := m.Location.Kind
+	if kind != s.Queue[0] {
+		if s.Hold == "" {
+			s.Hold, s.Queue = s.Queue[0], s.Queue[1:]
+		} else {
+			s.Hold, s.Queue[0] = s.Queue[0], s.Hold
+		}
+		if len(s.Queue) == 0 || s.Queue[0] != kind {
+			return fmt.Errorf("Move of %s played but it is neither active nor held!", kind)
+		}
+	}
+
+	cells, err := m.Location.Cells(s.Board.Height())
+	if err != nil {
+		return err
+	}
+	for _, c := range cells {
+		if set, err := s.Board.Block(c[0], c[1]); err != nil || set {
+			return fmt.Errorf("Move of %s to (%d,%d) does not fit the board!", kind, m.Location.X, m.Location.Y)
+		}
+	}
+	for _, c := range cells {
+		s.Board.SetCell(c[0], c[1], tetris.KindCell(kind))
+	}
+	s.Queue = s.Queue[1:]
+
+	if lines := tetris.ClearFullLines(s.Board); lines > 0 {
+		s.Combo++
+		s.BackToBack = lines == 4 || m.Spin == spinNames[tetris.SpinFull] || m.Spin == spinNames[tetris.SpinMini]
+	} else {
+		s.Combo = 0
+	}
+	return nil
+}
+
+// Chooses moves for the front-end to play.
+type Bot interface {
+	// Returns the moves to suggest, best first, or none to give up.  The
+	// bot should stop early and return when the context is done.
+	Suggest(ctx context.Context, state *State) ([]search.Move, error)
+}
+
+// A bot suggesting the best move found by a beam search.  The searcher must
+// use the SRS, as TBP locations follow it.
+type SearchBot struct {
+	Searcher *search.Searcher
+}
+
+func (b *SearchBot) Suggest(ctx context.Context, state *State) ([]search.Move, error) {
+	if len(state.Queue) == 0 {
+		return nil, nil
+	}
+
+	result, err := b.Searcher.SearchContext(ctx, search.State{
+		Board:  state.Board,
+		Active: state.Queue[0],
+		Held:   state.Hold,
+		Queue:  state.Queue[1:],
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []search.Move{result.Move}, nil
+}
@@ -0,0 +1,109 @@
+package tbp
+
+import (
+	"testing"
+)
+
+func TestNewStateFromStart(t *testing.T) {
+	g, s := "G", "S"
+	board := make([][]*string, Height)
+	for y := range board {
+		board[y] = make([]*string, Width)
+	}
+	board[0][0], board[1][9] = &g, &s
+
+	state, err := newState(&message{Board: board, Queue: []string{"T", "I"}, Hold: &s, Combo: 2})
+	if err != nil {
+		t.Fatalf("State should be built: %s", err)
+	}
+	if state.Board.Width() != Width || state.Board.Height() != Height {
+		t.Errorf("The board should be %dx%d", Width, Height)
+	}
+	if set, _ := state.Board.Block(39, 0); !set {
+		t.Error("The bottom left block should be set")
+	}
+	if cell, _ := state.Board.Cell(38, 9); cell.Kind() != "S" {
+		t.Errorf("The second row's right block should be an S, is %s", cell)
+	}
+	if state.Hold != "S" || len(state.Queue) != 2 || state.Combo != 2 {
+		t.Errorf("The hold, queue and combo should be kept, got %+v", state)
+	}
+
+	if _, err := newState(&message{}); err == nil {
+		t.Error("A start without a board should be an error")
+	}
+}
+
+func newEmptyState(t *testing.T, queue ...string) *State {
+	board := make([][]*string, Height)
+	for y := range board {
+		board[y] = make([]*string, Width)
+	}
+	state, err := newState(&message{Board: board, Queue: queue})
+	if err != nil {
+		t.Fatalf("State should be built: %s", err)
+	}
+	return state
+}
+
+func TestStatePlay(t *testing.T) {
+	state := newEmptyState(t, "I", "O", "T")
+	if err := state.play(Move{Location{"I", "north", 1, 0}, "none"}); err != nil {
+		t.Fatalf("The I should be played: %s", err)
+	}
+	for col := 0; col < 4; col++ {
+		if set, _ := state.Board.Block(39, col); !set {
+			t.Errorf("The I should cover column %d of the bottom row", col)
+		}
+	}
+	if len(state.Queue) != 2 || state.Queue[0] != "O" {
+		t.Errorf("The O should be active, queue is %v", state.Queue)
+	}
+
+	// Playing the T holds the O first.
+	if err := state.play(Move{Location{"T", "north", 5, 0}, "none"}); err != nil {
+		t.Fatalf("The T should be played from behind the O: %s", err)
+	}
+	if state.Hold != "O" || len(state.Queue) != 0 {
+		t.Errorf("The O should be held and the queue empty, got %q and %v", state.Hold, state.Queue)
+	}
+
+	state.Queue = []string{"I"}
+	if err := state.play(Move{Location{"O", "north", 7, 0}, "none"}); err != nil {
+		t.Fatalf("The held O should be played: %s", err)
+	}
+	if state.Hold != "I" {
+		t.Errorf("The I should be swapped into the hold, got %q", state.Hold)
+	}
+}
+
+func TestStatePlayRejectsBadMoves(t *testing.T) {
+	state := newEmptyState(t, "I", "O")
+	if err := state.play(Move{Location{"T", "north", 4, 0}, "none"}); err == nil {
+		t.Error("A piece neither active nor held should be an error")
+	}
+
+	state = newEmptyState(t, "I", "O")
+	if err := state.play(Move{Location{"I", "north", 1, -1}, "none"}); err == nil {
+		t.Error("A move below the floor should be an error")
+	}
+}
+
+func TestStatePlayClears(t *testing.T) {
+	state := newEmptyState(t, "I", "I", "O")
+	for col := 0; col < 6; col++ {
+		state.Board.SetBlock(39, col+4, true)
+	}
+	if err := state.play(Move{Location{"I", "north", 1, 0}, "none"}); err != nil {
+		t.Fatalf("The I should be played: %s", err)
+	}
+	if set, _ := state.Board.Block(39, 5); set || state.Combo != 1 || state.BackToBack {
+		t.Errorf("The bottom row should clear as a single, combo %d", state.Combo)
+	}
+	if err := state.play(Move{Location{"I", "north", 1, 0}, "none"}); err != nil {
+		t.Fatalf("The second I should be played: %s", err)
+	}
+	if state.Combo != 0 {
+		t.Errorf("A lock without a clear should end the combo, got %d", state.Combo)
+	}
+}
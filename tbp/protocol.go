@@ -0,0 +1,174 @@
+// Package tbp lets bots play through the Tetris Bot Protocol: JSON messages,
+// one per line, exchanged with a front-end over a pair of streams.
+package tbp
+
+import (
+	"fmt"
+
+	"github.com/paulcoyle/tetris"
+)
+
+// The width and height of the boards sent by TBP front-ends.
+const (
+	Width  = 10
+	Height = 40
+)
+
+// Where a piece locks, in TBP's terms: x grows to the right from the left
+// column and y upwards from the bottom row, and (x, y) is the piece's centre
+// of rotation under SRS true rotation.
+type Location struct {
+	Kind        string `json:"type"`
+	Orientation string `json:"orientation"`
+	X           int    `json:"x"`
+	Y           int    `json:"y"`
+}
+
+// A move sent to or received from a front-end.
+type Move struct {
+	Location Location `json:"location"`
+	Spin     string   `json:"spin"`
+}
+
+// Orientation names indexed by SRS rotation state.
+var orientations = [...]string{"north", "east", "south", "west"}
+
+var spinNames = [...]string{
+	tetris.SpinNone: "none",
+	tetris.SpinMini: "mini",
+	tetris.SpinFull: "full",
+}
+
+// The (x, y) of each block of each kind facing north, relative to its centre.
+// The other orientations are clockwise turns of these about the centre.
+var northCells = map[string][4][2]int{
+	"I": {{-1, 0}, {0, 0}, {1, 0}, {2, 0}},
+	"O": {{0, 0}, {1, 0}, {0, 1}, {1, 1}},
+	"T": {{-1, 0}, {0, 0}, {1, 0}, {0, 1}},
+	"S": {{-1, 0}, {0, 0}, {0, 1}, {1, 1}},
+	"Z": {{-1, 1}, {0, 1}, {0, 0}, {1, 0}},
+	"J": {{-1, 1}, {-1, 0}, {0, 0}, {1, 0}},
+	"L": {{1, 1}, {-1, 0}, {0, 0}, {1, 0}},
+}
+
+// Returns the offsets of the blocks of the given kind in the given SRS
+// rotation state.
+func offsets(kind string, state int) ([4][2]int, bool) {
+	cells, ok := northCells[kind]
+	if !ok || state < 0 || state >= len(orientations) {
+		return cells, false
+	}
+	for i := 0; i < state; i++ {
+		for j, c := range cells {
+			cells[j] = [2]int{c[1], -c[0]}
+		}
+	}
+	return cells, true
+}
+
+// Returns the SRS rotation state named by a TBP orientation, or -1.
+func orientationState(name string) int {
+	for i, o := range orientations {
+		if o == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Returns the board blocks, as (row, col), covered by a piece at the location
+// on a board of the given height.
+func (l Location) Cells(height int) ([4][2]int, error) {
+	var cells [4][2]int
+	state := orientationState(l.Orientation)
+	if state < 0 {
+		return cells, fmt.Errorf("Unknown orientation %q!", l.Orientation)
+	}
+	offs, ok := offsets(l.Kind, state)
+	if !ok {
+		return cells, fmt.Errorf("Unknown piece %q!", l.Kind)
+	}
+
+	for i, o := range offs {
+		cells[i] = [2]int{height - 1 - (l.Y + o[1]), l.X + o[0]}
+	}
+	return cells, nil
+}
+
+// Returns the TBP move for an SRS placement on a board of the given height.
+func MoveOf(p *tetris.Placement, height int) (Move, error) {
+	offs, ok := offsets(p.Kind, p.Orient)
+	if !ok {
+		return Move{}, fmt.Errorf("Placement of %s in state %d has no TBP location!", p.Kind, p.Orient)
+	}
+
+	// Try each block as the one at the first offset until every offset lands
+	// on a block.
+	for _, c := range p.Cells {
+		x, y := c[1]-offs[0][0], height-1-c[0]-offs[0][1]
+		loc := Location{p.Kind, orientations[p.Orient], x, y}
+		if cells, _ := loc.Cells(height); sameCells(cells, p.Cells) {
+			move := Move{Location: loc, Spin: spinNames[tetris.SpinNone]}
+			if int(p.Spin) < len(spinNames) {
+				move.Spin = spinNames[p.Spin]
+			}
+			return move, nil
+		}
+	}
+	return Move{}, fmt.Errorf("Placement of %s at %v does not match its state %d!", p.Kind, p.Cells, p.Orient)
+}
+
+// Returns true if both hold the same blocks in any order.
+func sameCells(a, b [4][2]int) bool {
+	for _, c := range a {
+		found := false
+		for _, d := range b {
+			found = found || c == d
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// A message from the front-end.  Only the fields of its type are set.
+type message struct {
+	Type string `json:"type"`
+
+	// rules
+	Randomizer string `json:"randomizer"`
+
+	// start
+	Hold       *string     `json:"hold"`
+	Queue      []string    `json:"queue"`
+	Combo      int         `json:"combo"`
+	BackToBack bool        `json:"back_to_back"`
+	Board      [][]*string `json:"board"`
+
+	// play
+	Move *Move `json:"move"`
+
+	// new_piece
+	Piece string `json:"piece"`
+}
+
+// Messages to the front-end.
+type (
+	bare struct {
+		Type string `json:"type"`
+	}
+
+	infoMessage struct {
+		Type     string   `json:"type"`
+		Name     string   `json:"name"`
+		Version  string   `json:"version"`
+		Author   string   `json:"author"`
+		Features []string `json:"features"`
+	}
+
+	suggestionMessage struct {
+		Type  string `json:"type"`
+		Moves []Move `json:"moves"`
+	}
+)
@@ -0,0 +1,68 @@
+package tbp
+
+import (
+	"testing"
+
+	"github.com/paulcoyle/tetris"
+)
+
+func TestLocationCells(t *testing.T) {
+	cells, err := Location{"T", "north", 4, 0}.Cells(Height)
+	if err != nil {
+		t.Fatalf("Cells should be found: %s", err)
+	}
+	expected := [4][2]int{{39, 3}, {39, 4}, {39, 5}, {38, 4}}
+	if cells != expected {
+		t.Errorf("A north T on the floor should cover %v, got %v", expected, cells)
+	}
+
+	cells, _ = Location{"I", "east", 0, 1}.Cells(Height)
+	expected = [4][2]int{{37, 0}, {38, 0}, {39, 0}, {40, 0}}
+	if cells != expected {
+		t.Errorf("An east I should hang down from its centre, expected %v, got %v", expected, cells)
+	}
+
+	if _, err := (Location{"T", "up", 4, 0}).Cells(Height); err == nil {
+		t.Error("An unknown orientation should be an error")
+	}
+	if _, err := (Location{"X", "north", 4, 0}).Cells(Height); err == nil {
+		t.Error("An unknown piece should be an error")
+	}
+}
+
+func TestMoveOfMatchesPlacements(t *testing.T) {
+	b, _ := tetris.NewBoard(Width, Height)
+	for _, kind := range tetris.Kinds {
+		orient, row, col := tetris.SRS.Spawn(kind, Width)
+		for _, p := range tetris.FindPlacements(tetris.SRS, b, kind, orient, row, col) {
+			move, err := MoveOf(p, Height)
+			if err != nil {
+				t.Errorf("%s at %v should have a move: %s", kind, p.Cells, err)
+				continue
+			}
+			cells, _ := move.Location.Cells(Height)
+			if !sameCells(cells, p.Cells) {
+				t.Errorf("%s at %v became %+v covering %v", kind, p.Cells, move.Location, cells)
+			}
+		}
+	}
+}
+
+func TestMoveOfFlatI(t *testing.T) {
+	b, _ := tetris.NewBoard(Width, Height)
+	orient, row, col := tetris.SRS.Spawn("I", Width)
+	for _, p := range tetris.FindPlacements(tetris.SRS, b, "I", orient, row, col) {
+		if p.Cells[0] != [2]int{39, 0} || p.Cells[3] != [2]int{39, 3} {
+			continue
+		}
+		move, _ := MoveOf(p, Height)
+		if move.Location != (Location{"I", "north", 1, 0}) && move.Location != (Location{"I", "south", 2, 0}) {
+			t.Errorf("A flat I in the corner should be centred on (1,0) or (2,0), got %+v", move.Location)
+		}
+		if move.Spin != "none" {
+			t.Errorf("A dropped I should not spin, got %s", move.Spin)
+		}
+		return
+	}
+	t.Error("A flat I in the corner should be found")
+}
@@ -0,0 +1,176 @@
+package tbp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/paulcoyle/tetris/search"
+)
+
+// What the bot tells the front-end about itself.
+type Info struct {
+	Name    string
+	Version string
+	Author  string
+}
+
+// A message read from the front-end, or the error that ended reading.
+type incoming struct {
+	msg *message
+	err error // io.EOF once the stream ends
+}
+
+// Plays as the bot for the front-end reading from r and writing to w, until
+// the front-end quits or closes r.  The bot is sent the state after every
+// start, play and new_piece message and asked for moves on suggest; a stop,
+// start or quit that arrives while it is thinking cancels it and no
+// suggestion is sent.  Unknown messages are ignored.  Returns an error if a
+// message cannot be read or does not fit the state, or w fails.
+func Run(r io.Reader, w io.Writer, bot Bot, info Info) error {
+	s := &session{bot: bot, enc: json.NewEncoder(w), in: make(chan incoming), done: make(chan struct{})}
+	defer close(s.done)
+	go s.read(r)
+
+	err := s.send(infoMessage{"info", info.Name, info.Version, info.Author, []string{}})
+	for err == nil {
+		var in incoming
+		if len(s.pending) > 0 {
+			in, s.pending = s.pending[0], s.pending[1:]
+		} else {
+			in = <-s.in
+		}
+
+		var quit bool
+		quit, err = s.handle(in)
+		if quit {
+			break
+		}
+	}
+	return err
+}
+
+type session struct {
+	bot     Bot
+	enc     *json.Encoder
+	in      chan incoming
+	done    chan struct{} // closed when Run returns
+	pending []incoming    // read while the bot was thinking
+	state   *State        // nil until the first start and after a stop
+}
+
+// Reads messages until the stream ends or Run returns.
+func (s *session) read(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for {
+		in := incoming{err: io.EOF}
+		if scanner.Scan() {
+			in = incoming{msg: &message{}}
+			if err := json.Unmarshal(scanner.Bytes(), in.msg); err != nil {
+				in = incoming{err: fmt.Errorf("Message %q is not valid: %s", scanner.Text(), err)}
+			}
+		} else if err := scanner.Err(); err != nil {
+			in.err = err
+		}
+
+		select {
+		case s.in <- in:
+		case <-s.done:
+			return
+		}
+		if in.err != nil {
+			return
+		}
+	}
+}
+
+func (s *session) send(v interface{}) error {
+	return s.enc.Encode(v)
+}
+
+// Handles a message, returning true when the session is over.
+func (s *session) handle(in incoming) (bool, error) {
+	if in.err == io.EOF {
+		return true, nil
+	} else if in.err != nil {
+		return true, in.err
+	}
+
+	m := in.msg
+	switch m.Type {
+	case "rules":
+		// The bot only looks at the queue, so it can play with any
+		// randomizer and never answers unsupported_rules.
+		return false, s.send(bare{"ready"})
+	case "start":
+		state, err := newState(m)
+		s.state = state
+		return false, err
+	case "stop":
+		s.state = nil
+	case "quit":
+		return true, nil
+	case "play":
+		if s.state == nil || m.Move == nil {
+			return false, nil
+		}
+		return false, s.state.play(*m.Move)
+	case "new_piece":
+		if s.state != nil {
+			s.state.Queue = append(s.state.Queue, m.Piece)
+		}
+	case "suggest":
+		return s.suggest()
+	}
+	return false, nil
+}
+
+type suggestion struct {
+	moves []search.Move
+	err   error
+}
+
+// Asks the bot for moves and sends them, unless the front-end stops, starts
+// over or quits meanwhile.  Other messages are kept until it is done.
+func (s *session) suggest() (bool, error) {
+	if s.state == nil {
+		return false, s.send(suggestionMessage{"suggestion", []Move{}})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	result := make(chan suggestion, 1)
+	state := s.state.Copy()
+	go func() {
+		moves, err := s.bot.Suggest(ctx, state)
+		result <- suggestion{moves, err}
+	}()
+
+	for {
+		select {
+		case r := <-result:
+			moves := []Move{}
+			for _, m := range r.moves {
+				move, err := MoveOf(m.Placement, state.Board.Height())
+				if err != nil {
+					return true, err
+				}
+				moves = append(moves, move)
+			}
+			// A bot that failed to find a move gives up.
+			return false, s.send(suggestionMessage{"suggestion", moves})
+
+		case in := <-s.in:
+			if in.err == nil && in.msg.Type != "stop" && in.msg.Type != "start" && in.msg.Type != "quit" {
+				s.pending = append(s.pending, in)
+				continue
+			}
+			cancel()
+			<-result
+			return s.handle(in)
+		}
+	}
+}
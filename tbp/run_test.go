@@ -0,0 +1,218 @@
+package tbp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/paulcoyle/tetris"
+	"github.com/paulcoyle/tetris/search"
+)
+
+// Set in the environment of the test binary when it is run as the bot.
+const botEnv = "TBP_TEST_BOT"
+
+// Runs the bot on stdin and stdout when the test binary is started by
+// newFrontEnd, so the protocol is tested across real process boundaries.
+func TestMain(m *testing.M) {
+	if os.Getenv(botEnv) == "1" {
+		config := search.DefaultConfig()
+		config.BeamWidth, config.Depth = 4, 2
+		searcher, _ := search.NewSearcher(config)
+		if err := Run(os.Stdin, os.Stdout, &SearchBot{searcher}, Info{Name: "test"}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// A fake front-end driving the bot in another process.
+type frontEnd struct {
+	t     *testing.T
+	cmd   *exec.Cmd
+	in    io.WriteCloser
+	out   *bufio.Scanner
+	board *tetris.Board
+	queue []string
+	hold  string
+}
+
+func newFrontEnd(t *testing.T) *frontEnd {
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), botEnv+"=1")
+	cmd.Stderr = os.Stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("The bot should start: %s", err)
+	}
+
+	board, _ := tetris.NewBoard(Width, Height)
+	return &frontEnd{t: t, cmd: cmd, in: in, out: bufio.NewScanner(out), board: board}
+}
+
+func (f *frontEnd) send(line string) {
+	if _, err := io.WriteString(f.in, line+"\n"); err != nil {
+		f.t.Fatalf("Sending %s failed: %s", line, err)
+	}
+}
+
+func (f *frontEnd) receive(v interface{}) string {
+	if !f.out.Scan() {
+		f.t.Fatalf("The bot closed its output: %v", f.out.Err())
+	}
+	if err := json.Unmarshal(f.out.Bytes(), v); err != nil {
+		f.t.Fatalf("The bot sent invalid JSON %q: %s", f.out.Text(), err)
+	}
+	return f.out.Text()
+}
+
+// Checks a move against the front-end's own state and plays it.
+func (f *frontEnd) play(m Move) {
+	kind := m.Location.Kind
+	if kind != f.queue[0] {
+		if f.hold == "" {
+			f.hold, f.queue = f.queue[0], f.queue[1:]
+		} else {
+			f.hold, f.queue[0] = f.queue[0], f.hold
+		}
+		if f.queue[0] != kind {
+			f.t.Fatalf("The bot played %s which is neither active nor held", kind)
+		}
+	}
+	f.queue = f.queue[1:]
+
+	cells, err := m.Location.Cells(Height)
+	if err != nil {
+		f.t.Fatalf("The bot sent an invalid location: %s", err)
+	}
+	for _, c := range cells {
+		if set, err := f.board.Block(c[0], c[1]); err != nil || set {
+			f.t.Fatalf("The bot's %+v does not fit the board", m.Location)
+		}
+	}
+	for _, c := range cells {
+		f.board.SetBlock(c[0], c[1], true)
+	}
+	below := false
+	for _, c := range cells {
+		if c[0] == Height-1 {
+			below = true
+		} else if set, _ := f.board.Block(c[0]+1, c[1]); set {
+			below = true
+		}
+	}
+	if !below {
+		f.t.Errorf("The bot's %+v is floating", m.Location)
+	}
+	tetris.ClearFullLines(f.board)
+
+	data, _ := json.Marshal(m)
+	f.send(`{"type":"play","move":` + string(data) + `}`)
+}
+
+func TestRunPlaysWithFrontEnd(t *testing.T) {
+	f := newFrontEnd(t)
+
+	var info infoMessage
+	f.receive(&info)
+	if info.Type != "info" || info.Name != "test" || info.Features == nil {
+		t.Errorf("The bot should introduce itself, sent %+v", info)
+	}
+
+	f.send(`{"type":"rules","randomizer":"seven_bag"}`)
+	var ready bare
+	if f.receive(&ready); ready.Type != "ready" {
+		t.Fatalf("The bot should be ready, sent %s", ready.Type)
+	}
+
+	random := tetris.NewBagRandomizer(3)
+	for i := 0; i < 6; i++ {
+		f.queue = append(f.queue, random.Next())
+	}
+	queue, _ := json.Marshal(f.queue)
+	rows := make([]string, Height)
+	for y := range rows {
+		rows[y] = "[" + strings.TrimSuffix(strings.Repeat("null,", Width), ",") + "]"
+	}
+	f.send(`{"type":"start","hold":null,"queue":` + string(queue) + `,"combo":0,"back_to_back":false,` +
+		`"board":[` + strings.Join(rows, ",") + `]}`)
+
+	for i := 0; i < 30; i++ {
+		f.send(`{"type":"suggest"}`)
+		var suggestion suggestionMessage
+		line := f.receive(&suggestion)
+		if suggestion.Type != "suggestion" || len(suggestion.Moves) == 0 {
+			t.Fatalf("The bot should suggest a move, sent %s", line)
+		}
+		f.play(suggestion.Moves[0])
+
+		next := random.Next()
+		f.queue = append(f.queue, next)
+		f.send(`{"type":"new_piece","piece":"` + next + `"}`)
+	}
+
+	for row := 0; row < Height-8; row++ {
+		for col := 0; col < Width; col++ {
+			if set, _ := f.board.Block(row, col); set {
+				t.Fatalf("The bot should keep the stack low, reached row %d", row)
+			}
+		}
+	}
+
+	f.send(`{"type":"quit"}`)
+	if err := f.cmd.Wait(); err != nil {
+		t.Errorf("The bot should exit cleanly on quit: %s", err)
+	}
+}
+
+// A bot that thinks until it is stopped.
+type stubbornBot struct {
+	started chan bool
+}
+
+func (b *stubbornBot) Suggest(ctx context.Context, state *State) ([]search.Move, error) {
+	b.started <- true
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestRunStopCancelsSuggestion(t *testing.T) {
+	inR, inW := io.Pipe()
+	var out strings.Builder
+	bot := &stubbornBot{make(chan bool, 1)}
+	done := make(chan error)
+	go func() {
+		done <- Run(inR, &out, bot, Info{Name: "stubborn"})
+	}()
+
+	board := `[` + strings.TrimSuffix(strings.Repeat(`[null,null],`, 2), ",") + `]`
+	io.WriteString(inW, `{"type":"start","hold":null,"queue":["T"],"board":`+board+"}\n")
+	io.WriteString(inW, `{"type":"suggest"}`+"\n")
+	<-bot.started
+	io.WriteString(inW, `{"type":"stop"}`+"\n")
+	io.WriteString(inW, `{"type":"suggest"}`+"\n")
+	io.WriteString(inW, `{"type":"quit"}`+"\n")
+
+	if err := <-done; err != nil {
+		t.Fatalf("Run should end cleanly: %s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || lines[1] != `{"type":"suggestion","moves":[]}` {
+		t.Errorf("Only the info and an empty suggestion after the stop should be sent, got %q", lines)
+	}
+}
@@ -17,6 +17,10 @@ var tet_orients = map[string][]int{
 	"T": {0x0720, 0x2320, 0x2700, 0x2620},
 }
 
+// The kinds of tetromino in a fixed order, for anything that needs to list
+// or number them.
+var Kinds = [...]string{"I", "O", "T", "S", "Z", "J", "L"}
+
 type TetrominoData [4][4]bool
 
 func (d *TetrominoData) Equal(other *TetrominoData) bool {
@@ -67,21 +71,30 @@ func (b *TetrominoBounds) Equal(other *TetrominoBounds) bool {
 type Tetromino struct {
 	kind   string
 	orient int
+	rs     RotationSystem // supplies the shape of each orientation
 	data   *TetrominoData
 	bounds *TetrominoBounds
+	masks  [4]uint64 // per-row bitmasks of data, bit n set for column n
 }
 
+// Creates a tetromino using the orientations of the Pason contest.
 func NewTetromino(kind string, orient int) (*Tetromino, error) {
-	orients, ok := tet_orients[kind]
-	if !ok {
+	return NewTetrominoFor(Pason, kind, orient)
+}
+
+// Creates a tetromino whose orientations are the rotation states of the given
+// rotation system.
+func NewTetrominoFor(rs RotationSystem, kind string, orient int) (*Tetromino, error) {
+	states := rs.NumStates(kind)
+	if states < 1 {
 		return nil, fmt.Errorf("Supplied tetromino kind %s is not valid", kind)
 	}
-	if orient < 0 || orient > (len(orients)-1) {
+	if orient < 0 || orient > (states-1) {
 		return nil, fmt.Errorf("Orientation %d for tetromino kind %s is not valid", orient, kind)
 	}
 
-	tet := &Tetromino{kind, orient, nil, nil}
-	tet.setData(intToTetData(orients[orient]))
+	tet := &Tetromino{kind: kind, orient: orient, rs: rs}
+	tet.setData(rs.Shape(kind, orient))
 
 	return tet, nil
 }
@@ -100,6 +113,11 @@ func (t *Tetromino) Orient() int {
 	return t.orient
 }
 
+// Returns the rotation system the tetromino was created for.
+func (t *Tetromino) System() RotationSystem {
+	return t.rs
+}
+
 // Rotates "forward" (increments orientation by 1) and returns new orientation
 func (t *Tetromino) RotateFwd() int {
 	return t.rotate(1)
@@ -111,12 +129,12 @@ func (t *Tetromino) RotateBack() int {
 }
 
 func (t *Tetromino) rotate(delta int) int {
-	olen := len(tet_orients[t.kind])
+	olen := t.rs.NumStates(t.kind)
 	t.orient = (t.orient + delta) % olen
 	if t.orient < 0 {
 		t.orient = t.orient + olen
 	}
-	t.setData(intToTetData(tet_orients[t.kind][t.orient]))
+	t.setData(t.rs.Shape(t.kind, t.orient))
 	return t.orient
 }
 
@@ -131,6 +149,20 @@ func (t *Tetromino) Data() *TetrominoData {
 func (t *Tetromino) setData(data *TetrominoData) {
 	t.data = data
 	t.updateBounds()
+	t.updateMasks()
+}
+
+// For the current orientation, this method updates the row bitmasks used to
+// test the tetromino against a board's rows in a single operation.
+func (t *Tetromino) updateMasks() {
+	for row := 0; row < 4; row++ {
+		t.masks[row] = 0
+		for col := 0; col < 4; col++ {
+			if t.data[row][col] {
+				t.masks[row] |= 1 << uint(col)
+			}
+		}
+	}
 }
 
 // For the current orientation, this method updates the bounds field of the
@@ -0,0 +1,73 @@
+package tune
+
+import (
+	"github.com/paulcoyle/tetris"
+)
+
+// Settings for the games weights are judged by.
+type GameConfig struct {
+	Width    int
+	Height   int
+	Rotation tetris.RotationSystem
+
+	// Creates the randomizer dealing a game's pieces from its seed.
+	Randomizer func(seed int64) tetris.Randomizer
+
+	// Ends a game after this many pieces, zero to play until topping out.
+	MaxPieces int
+}
+
+// Returns games on a 10x20 board using the SRS and 7-bag, capped at 500
+// pieces.
+func DefaultGameConfig() GameConfig {
+	return GameConfig{
+		Width:      10,
+		Height:     20,
+		Rotation:   tetris.SRS,
+		Randomizer: tetris.NewBagRandomizer,
+		MaxPieces:  500,
+	}
+}
+
+// How a game went.
+type Outcome struct {
+	Lines  int
+	Pieces int  // the number of pieces placed
+	Over   bool // whether the game ended by topping out
+}
+
+// Plays a game without a display or timing: every piece is placed where the
+// evaluator scores best of all the places it can reach from its spawn
+// position.  The same weights and seed always play the same game.
+func Play(config GameConfig, weights tetris.Weights, seed int64) Outcome {
+	board, err := tetris.NewBoard(config.Width, config.Height)
+	if err != nil {
+		return Outcome{Over: true}
+	}
+	evaluator := tetris.NewEvaluator(weights)
+	random := config.Randomizer(seed)
+	rs := config.Rotation
+
+	var outcome Outcome
+	for config.MaxPieces <= 0 || outcome.Pieces < config.MaxPieces {
+		kind := random.Next()
+		orient, row, col := rs.Spawn(kind, board.Width())
+
+		var best *tetris.Placement
+		var bestScore float64
+		for _, p := range tetris.FindPlacements(rs, board, kind, orient, row, col) {
+			if score := evaluator.Score(board, p); best == nil || score > bestScore {
+				best, bestScore = p, score
+			}
+		}
+		if best == nil {
+			outcome.Over = true
+			break
+		}
+
+		board = best.Board
+		outcome.Lines += best.Lines
+		outcome.Pieces++
+	}
+	return outcome
+}
@@ -0,0 +1,51 @@
+package tune
+
+import (
+	"testing"
+
+	"github.com/paulcoyle/tetris"
+)
+
+func TestPlayIsDeterministic(t *testing.T) {
+	config := DefaultGameConfig()
+	config.MaxPieces = 100
+
+	a := Play(config, tetris.ElTetrisWeights, 7)
+	b := Play(config, tetris.ElTetrisWeights, 7)
+	if a != b {
+		t.Errorf("The same weights and seed should play the same game, got %+v and %+v", a, b)
+	}
+}
+
+func TestPlayStopsAtMaxPieces(t *testing.T) {
+	config := DefaultGameConfig()
+	config.MaxPieces = 100
+
+	outcome := Play(config, tetris.ElTetrisWeights, 1)
+	if outcome.Pieces != 100 || outcome.Over {
+		t.Errorf("El-Tetris should survive 100 pieces, got %+v", outcome)
+	}
+	// Every piece adds 4 blocks, and with 100 pieces on a 10x20 board some
+	// lines must have been cleared.
+	if outcome.Lines < 20 {
+		t.Errorf("El-Tetris should clear at least 20 lines in 100 pieces, cleared %d", outcome.Lines)
+	}
+}
+
+func TestPlayTopsOutWithBadWeights(t *testing.T) {
+	config := DefaultGameConfig()
+	config.MaxPieces = 0
+
+	// Rewarding height and holes stacks straight to the top.
+	var weights tetris.Weights
+	weights[tetris.AggregateHeight] = 1
+	weights[tetris.Holes] = 1
+
+	outcome := Play(config, weights, 1)
+	if !outcome.Over {
+		t.Error("The game should end by topping out")
+	}
+	if outcome.Pieces > 100 {
+		t.Errorf("Stacking up should top out quickly, placed %d pieces", outcome.Pieces)
+	}
+}
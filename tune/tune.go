@@ -0,0 +1,449 @@
+// Package tune searches for evaluator weights that play well, by playing many
+// headless games with each candidate set of weights.
+package tune
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/paulcoyle/tetris"
+)
+
+// How new candidate weights are found from the fitness of the last ones.
+type Method string
+
+const (
+	// Draws candidates from a normal distribution per weight, refitted each
+	// generation to the best of the previous candidates.
+	CrossEntropy Method = "cem"
+
+	// Breeds candidates from the previous generation by tournament
+	// selection, uniform crossover and mutation.
+	Genetic Method = "ga"
+)
+
+// What a game is judged by.
+type Objective string
+
+const (
+	Lines    Objective = "lines"  // lines cleared
+	Survival Objective = "pieces" // pieces placed before topping out
+)
+
+// Settings for tuning.
+type Config struct {
+	Game      GameConfig
+	Method    Method
+	Objective Objective
+
+	Population  int // candidates per generation
+	Generations int // generations to run in all
+	Games       int // games each candidate plays per generation
+
+	// The number of games played at once.
+	Workers int
+
+	// Seeds the candidates drawn and, with the generation, the games played.
+	// Every candidate of a generation plays the same games.
+	Seed int64
+
+	// The standard deviation of the weights first drawn, around zero.
+	InitialStdDev float64
+
+	// For the cross-entropy method: the fraction of candidates refitted to,
+	// and the variance added to every weight after refitting so the search
+	// does not settle too soon.  The added variance falls by NoiseDecay
+	// each generation until it reaches zero.
+	Elite      float64
+	Noise      float64
+	NoiseDecay float64
+
+	// For the genetic algorithm: the number of candidates competing for each
+	// parent, the number of the best candidates kept unchanged, and the
+	// chance and standard deviation of a change to each weight of a child.
+	Tournament    int
+	Elitism       int
+	MutationRate  float64
+	MutationScale float64
+}
+
+// Returns settings for the given method: 20 generations of 50 candidates
+// playing 4 default games each for lines cleared, on every CPU.
+func DefaultConfig(method Method) Config {
+	return Config{
+		Game:          DefaultGameConfig(),
+		Method:        method,
+		Objective:     Lines,
+		Population:    50,
+		Generations:   20,
+		Games:         4,
+		Workers:       runtime.NumCPU(),
+		InitialStdDev: 5,
+		Elite:         0.2,
+		Noise:         4,
+		NoiseDecay:    0.2,
+		Tournament:    3,
+		Elitism:       2,
+		MutationRate:  0.2,
+		MutationScale: 1,
+	}
+}
+
+// The results of a generation.
+type Generation struct {
+	Number      int            `json:"number"`
+	Best        tetris.Weights `json:"best"`
+	BestFitness float64        `json:"best_fitness"`
+	MeanFitness float64        `json:"mean_fitness"`
+}
+
+// The version of the checkpoint format written by Save.
+const CheckpointVersion = 1
+
+// Everything needed to carry on tuning from the end of a generation.  It is
+// saved as JSON.
+type Checkpoint struct {
+	Version    int       `json:"version"`
+	Method     Method    `json:"method"`
+	Objective  Objective `json:"objective"`
+	Generation int       `json:"generation"` // the number of generations run
+
+	// The settings the games and candidates follow from, which a resumed
+	// tuning must share.
+	Seed       int64 `json:"seed"`
+	Candidates int   `json:"candidates"` // the population of each generation
+	Games      int   `json:"games"`
+
+	// The distribution candidates are drawn from by the cross-entropy method.
+	Mean   tetris.Weights `json:"mean"`
+	StdDev tetris.Weights `json:"std_dev"`
+
+	// The candidates the genetic algorithm plays next.
+	Population []tetris.Weights `json:"population,omitempty"`
+
+	// The fittest candidate found so far.
+	Best        tetris.Weights `json:"best"`
+	BestFitness float64        `json:"best_fitness"`
+
+	History []Generation `json:"history"`
+}
+
+// Reads a checkpoint saved by Save.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &Checkpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("Checkpoint %s is not valid: %s", path, err)
+	}
+	if cp.Version != CheckpointVersion {
+		return nil, fmt.Errorf("Checkpoint %s has version %d, expected %d!", path, cp.Version, CheckpointVersion)
+	}
+	return cp, nil
+}
+
+// Writes the checkpoint as JSON.  The file is replaced in one step, so a
+// crash while saving leaves the previous checkpoint in place.
+func (cp *Checkpoint) Save(path string) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Runs the generations of a tuning.
+type Tuner struct {
+	config Config
+	cp     *Checkpoint
+}
+
+// Creates a tuner starting from scratch.
+func NewTuner(config Config) (*Tuner, error) {
+	if err := checkConfig(config); err != nil {
+		return nil, err
+	}
+
+	cp := &Checkpoint{
+		Version:    CheckpointVersion,
+		Method:     config.Method,
+		Objective:  config.Objective,
+		Seed:       config.Seed,
+		Candidates: config.Population,
+		Games:      config.Games,
+	}
+	for i := range cp.StdDev {
+		cp.StdDev[i] = config.InitialStdDev
+	}
+	if config.Method == Genetic {
+		r := rand.New(rand.NewSource(config.Seed))
+		for i := 0; i < config.Population; i++ {
+			cp.Population = append(cp.Population, sample(r, cp.Mean, cp.StdDev))
+		}
+	}
+	return &Tuner{config, cp}, nil
+}
+
+// Creates a tuner carrying on from a checkpoint.  The configuration must have
+// the method, objective, seed, population and games per candidate the
+// checkpoint was made with, and should otherwise be the same for the results
+// to match an uninterrupted run.
+func Resume(config Config, cp *Checkpoint) (*Tuner, error) {
+	if err := checkConfig(config); err != nil {
+		return nil, err
+	}
+	if cp.Version != CheckpointVersion {
+		return nil, fmt.Errorf("Checkpoint has version %d, expected %d!", cp.Version, CheckpointVersion)
+	}
+	if cp.Method != config.Method || cp.Objective != config.Objective {
+		return nil, fmt.Errorf("Checkpoint is for %s by %s, not %s by %s!",
+			cp.Objective, cp.Method, config.Objective, config.Method)
+	}
+	if cp.Seed != config.Seed || cp.Candidates != config.Population || cp.Games != config.Games {
+		return nil, fmt.Errorf("Checkpoint is for seed %d with %d candidates playing %d games, not seed %d with %d playing %d!",
+			cp.Seed, cp.Candidates, cp.Games, config.Seed, config.Population, config.Games)
+	}
+	if cp.Method == Genetic && len(cp.Population) == 0 {
+		return nil, fmt.Errorf("Checkpoint has no population!")
+	}
+	return &Tuner{config, cp.copy()}, nil
+}
+
+func checkConfig(config Config) error {
+	switch {
+	case config.Method != CrossEntropy && config.Method != Genetic:
+		return fmt.Errorf("Unknown tuning method %q!", config.Method)
+	case config.Objective != Lines && config.Objective != Survival:
+		return fmt.Errorf("Unknown objective %q!", config.Objective)
+	case config.Population < 2 || config.Games < 1:
+		return fmt.Errorf("Tuning needs at least 2 candidates playing a game each!")
+	case config.Game.Rotation == nil || config.Game.Randomizer == nil:
+		return fmt.Errorf("Tuning games need a rotation system and randomizer!")
+	case config.Game.MaxPieces <= 0:
+		return fmt.Errorf("Tuning games need a piece limit!")
+	}
+	return nil
+}
+
+// Returns a copy of the state of the tuning.
+func (t *Tuner) Checkpoint() *Checkpoint {
+	return t.cp.copy()
+}
+
+func (cp *Checkpoint) copy() *Checkpoint {
+	c := *cp
+	c.Population = append([]tetris.Weights(nil), cp.Population...)
+	c.History = append([]Generation(nil), cp.History...)
+	return &c
+}
+
+// Returns the fittest weights found so far and their fitness.
+func (t *Tuner) Best() (tetris.Weights, float64) {
+	return t.cp.Best, t.cp.BestFitness
+}
+
+// Returns true once every generation has been run.
+func (t *Tuner) Done() bool {
+	return t.cp.Generation >= t.config.Generations
+}
+
+// Runs generations until done or the context is done, calling after at the
+// end of each one, e.g. to save a checkpoint.  A generation cut short is
+// discarded.
+func (t *Tuner) Run(ctx context.Context, after func(*Generation) error) error {
+	for !t.Done() {
+		gen, err := t.Step(ctx)
+		if err != nil {
+			return err
+		}
+		if after != nil {
+			if err := after(gen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Runs one generation: plays every candidate's games and chooses the next
+// candidates.  If the context is done first the tuner is left unchanged and
+// the context's error is returned.
+func (t *Tuner) Step(ctx context.Context) (*Generation, error) {
+	number := t.cp.Generation
+	r := rand.New(rand.NewSource(t.config.Seed + int64(number+1)*0x9e3779b9))
+
+	candidates := t.cp.Population
+	if t.config.Method == CrossEntropy {
+		candidates = make([]tetris.Weights, t.config.Population)
+		for i := range candidates {
+			candidates[i] = sample(r, t.cp.Mean, t.cp.StdDev)
+		}
+	}
+
+	fitness, err := t.evaluate(ctx, candidates, number)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fittest first, earlier candidates winning ties.
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return fitness[order[i]] > fitness[order[j]]
+	})
+
+	gen := Generation{Number: number, Best: candidates[order[0]], BestFitness: fitness[order[0]]}
+	for _, f := range fitness {
+		gen.MeanFitness += f / float64(len(fitness))
+	}
+	if len(t.cp.History) == 0 || gen.BestFitness > t.cp.BestFitness {
+		t.cp.Best, t.cp.BestFitness = gen.Best, gen.BestFitness
+	}
+
+	if t.config.Method == CrossEntropy {
+		t.refit(candidates, order, number)
+	} else {
+		t.cp.Population = t.breed(r, candidates, fitness, order)
+	}
+	t.cp.Generation++
+	t.cp.History = append(t.cp.History, gen)
+	return &gen, nil
+}
+
+// Plays the generation's games with every candidate, returning each one's
+// mean result.
+func (t *Tuner) evaluate(ctx context.Context, candidates []tetris.Weights, generation int) ([]float64, error) {
+	games := t.config.Games
+	results := make([]float64, len(candidates)*games)
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	workers := t.config.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				seed := t.config.Seed + int64(generation*games+job%games)
+				outcome := Play(t.config.Game, candidates[job/games], seed)
+				if t.config.Objective == Lines {
+					results[job] = float64(outcome.Lines)
+				} else {
+					results[job] = float64(outcome.Pieces)
+				}
+			}
+		}()
+	}
+
+	cancelled := false
+	for job := range results {
+		select {
+		case jobs <- job:
+		case <-ctx.Done():
+			cancelled = true
+		}
+		if cancelled {
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if cancelled || ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	fitness := make([]float64, len(candidates))
+	for job, result := range results {
+		fitness[job/games] += result / float64(games)
+	}
+	return fitness, nil
+}
+
+// Moves the cross-entropy distribution to the elite candidates.
+func (t *Tuner) refit(candidates []tetris.Weights, order []int, generation int) {
+	elite := int(t.config.Elite * float64(len(candidates)))
+	if elite < 1 {
+		elite = 1
+	}
+	noise := math.Max(t.config.Noise-float64(generation)*t.config.NoiseDecay, 0)
+
+	for f := range t.cp.Mean {
+		mean := 0.0
+		for _, i := range order[:elite] {
+			mean += candidates[i][f] / float64(elite)
+		}
+		variance := 0.0
+		for _, i := range order[:elite] {
+			d := candidates[i][f] - mean
+			variance += d * d / float64(elite)
+		}
+		t.cp.Mean[f] = mean
+		t.cp.StdDev[f] = math.Sqrt(variance + noise)
+	}
+}
+
+// Breeds the next generation of the genetic algorithm.
+func (t *Tuner) breed(r *rand.Rand, candidates []tetris.Weights, fitness []float64, order []int) []tetris.Weights {
+	next := make([]tetris.Weights, 0, len(candidates))
+	for _, i := range order {
+		if len(next) >= t.config.Elitism {
+			break
+		}
+		next = append(next, candidates[i])
+	}
+
+	pick := func() tetris.Weights {
+		best := r.Intn(len(candidates))
+		for i := 1; i < t.config.Tournament; i++ {
+			if c := r.Intn(len(candidates)); fitness[c] > fitness[best] {
+				best = c
+			}
+		}
+		return candidates[best]
+	}
+
+	for len(next) < len(candidates) {
+		a, b := pick(), pick()
+		var child tetris.Weights
+		for f := range child {
+			child[f] = a[f]
+			if r.Intn(2) == 1 {
+				child[f] = b[f]
+			}
+			if r.Float64() < t.config.MutationRate {
+				child[f] += r.NormFloat64() * t.config.MutationScale
+			}
+		}
+		next = append(next, child)
+	}
+	return next
+}
+
+// Draws weights from independent normal distributions.
+func sample(r *rand.Rand, mean, stdDev tetris.Weights) tetris.Weights {
+	var w tetris.Weights
+	for f := range w {
+		w[f] = mean[f] + r.NormFloat64()*stdDev[f]
+	}
+	return w
+}
@@ -0,0 +1,178 @@
+package tune
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Returns a quick tuning: small populations playing short games.
+func testConfig(method Method) Config {
+	config := DefaultConfig(method)
+	config.Population = 8
+	config.Generations = 3
+	config.Games = 2
+	config.Workers = 4
+	config.Game.MaxPieces = 30
+	config.Seed = 42
+	return config
+}
+
+func newTestTuner(t *testing.T, config Config) *Tuner {
+	tuner, err := NewTuner(config)
+	if err != nil {
+		t.Fatalf("Tuner should be created: %s", err)
+	}
+	return tuner
+}
+
+func TestNewTunerChecksConfig(t *testing.T) {
+	config := testConfig(CrossEntropy)
+	config.Method = "annealing"
+	if _, err := NewTuner(config); err == nil {
+		t.Error("An unknown method should be an error")
+	}
+
+	config = testConfig(CrossEntropy)
+	config.Objective = "score"
+	if _, err := NewTuner(config); err == nil {
+		t.Error("An unknown objective should be an error")
+	}
+
+	config = testConfig(Genetic)
+	config.Population = 1
+	if _, err := NewTuner(config); err == nil {
+		t.Error("A population of one should be an error")
+	}
+
+	config = testConfig(Genetic)
+	config.Game.MaxPieces = 0
+	if _, err := NewTuner(config); err == nil {
+		t.Error("Games without a piece limit should be an error")
+	}
+}
+
+func TestTunerRunsEveryGeneration(t *testing.T) {
+	for _, method := range []Method{CrossEntropy, Genetic} {
+		tuner := newTestTuner(t, testConfig(method))
+		calls := 0
+		err := tuner.Run(context.Background(), func(gen *Generation) error {
+			if gen.Number != calls {
+				t.Errorf("%s: generation %d should be numbered %d", method, gen.Number, calls)
+			}
+			if gen.BestFitness < gen.MeanFitness {
+				t.Errorf("%s: the best fitness %f is below the mean %f", method, gen.BestFitness, gen.MeanFitness)
+			}
+			calls++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: run should succeed: %s", method, err)
+		}
+		if calls != 3 || !tuner.Done() {
+			t.Errorf("%s: 3 generations should run, ran %d", method, calls)
+		}
+
+		cp := tuner.Checkpoint()
+		_, best := tuner.Best()
+		for _, gen := range cp.History {
+			if gen.BestFitness > best {
+				t.Errorf("%s: the best fitness %f is below generation %d's %f", method, best, gen.Number, gen.BestFitness)
+			}
+		}
+		if method == Genetic && len(cp.Population) != 8 {
+			t.Errorf("%s: the population should stay at 8, is %d", method, len(cp.Population))
+		}
+	}
+}
+
+func TestTunerIsDeterministic(t *testing.T) {
+	for _, method := range []Method{CrossEntropy, Genetic} {
+		config := testConfig(method)
+		a := newTestTuner(t, config)
+		config.Workers = 1
+		b := newTestTuner(t, config)
+		a.Run(context.Background(), nil)
+		b.Run(context.Background(), nil)
+
+		if !reflect.DeepEqual(a.Checkpoint(), b.Checkpoint()) {
+			t.Errorf("%s: tuning should not depend on the number of workers", method)
+		}
+	}
+}
+
+func TestTunerResumesFromCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	for _, method := range []Method{CrossEntropy, Genetic} {
+		config := testConfig(method)
+		whole := newTestTuner(t, config)
+		whole.Run(context.Background(), nil)
+
+		first := newTestTuner(t, config)
+		if _, err := first.Step(context.Background()); err != nil {
+			t.Fatalf("%s: step should succeed: %s", method, err)
+		}
+		if err := first.Checkpoint().Save(path); err != nil {
+			t.Fatalf("%s: checkpoint should save: %s", method, err)
+		}
+
+		cp, err := LoadCheckpoint(path)
+		if err != nil {
+			t.Fatalf("%s: checkpoint should load: %s", method, err)
+		}
+		if !reflect.DeepEqual(cp, first.Checkpoint()) {
+			t.Errorf("%s: the loaded checkpoint differs from the saved one", method)
+		}
+
+		resumed, err := Resume(config, cp)
+		if err != nil {
+			t.Fatalf("%s: tuning should resume: %s", method, err)
+		}
+		resumed.Run(context.Background(), nil)
+		if !reflect.DeepEqual(resumed.Checkpoint(), whole.Checkpoint()) {
+			t.Errorf("%s: a resumed tuning should end as an uninterrupted one", method)
+		}
+	}
+}
+
+func TestResumeChecksCheckpoint(t *testing.T) {
+	tuner := newTestTuner(t, testConfig(CrossEntropy))
+	cp := tuner.Checkpoint()
+
+	if _, err := Resume(testConfig(Genetic), cp); err == nil {
+		t.Error("Resuming with another method should be an error")
+	}
+
+	for name, change := range map[string]func(*Config){
+		"seed":       func(c *Config) { c.Seed++ },
+		"population": func(c *Config) { c.Population++ },
+		"games":      func(c *Config) { c.Games++ },
+	} {
+		config := testConfig(CrossEntropy)
+		change(&config)
+		if _, err := Resume(config, cp); err == nil {
+			t.Errorf("Resuming with another %s should be an error", name)
+		}
+	}
+
+	cp.Version = CheckpointVersion + 1
+	if _, err := Resume(testConfig(CrossEntropy), cp); err == nil {
+		t.Error("Resuming from another version should be an error")
+	}
+}
+
+func TestStepLeavesTunerUnchangedWhenCancelled(t *testing.T) {
+	tuner := newTestTuner(t, testConfig(Genetic))
+	before := tuner.Checkpoint()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := tuner.Step(ctx); err != context.Canceled {
+		t.Errorf("A cancelled step should return the context's error, got %v", err)
+	}
+	if !reflect.DeepEqual(tuner.Checkpoint(), before) {
+		t.Error("A cancelled step should leave the tuner unchanged")
+	}
+}
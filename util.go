@@ -3,7 +3,6 @@ package tetris
 import (
 	"fmt"
 	"math/rand"
-	"time"
 )
 
 // Usage:
@@ -36,14 +35,10 @@ func StringArrayToBoard(rows []string) (*Board, error) {
 	return board, nil
 }
 
-// Generates a random tetromino and returns a reference to it.
+// Generates a random tetromino and returns a reference to it.  The sequence
+// can't be reproduced; use a Randomizer for that.
 func RandomTetromino() *Tetromino {
-	rand.Seed(time.Now().UnixNano())
-	keys := make([]string, 0)
-	for k, _ := range tet_orients {
-		keys = append(keys, k)
-	}
-	kind := keys[rand.Intn(len(keys))]
+	kind := Kinds[rand.Intn(len(Kinds))]
 	orient := rand.Intn(NumTetOrients(kind))
 	tet, _ := NewTetromino(kind, orient)
 	return tet
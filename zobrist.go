@@ -0,0 +1,71 @@
+package tetris
+
+// Zobrist hashing gives every feature of a position a random key and hashes
+// the position as the XOR of the keys of the features it has, so a hash can be
+// updated as features come and go by XORing their keys in and out.  Keys are
+// made by passing a feature's number through SplitMix64 rather than kept in
+// tables, so boards of any size hash the same on every platform.
+
+// Tags keeping the numbers of each kind of feature apart.
+const (
+	zobristBlock uint64 = iota << 60
+	zobristKind
+	zobristPiece
+	zobristHold
+)
+
+func zobrist(feature uint64) uint64 {
+	r := rng{feature}
+	return r.next()
+}
+
+// Returns the key of a set block.
+func zobristKey(row, col int) uint64 {
+	return zobrist(zobristBlock | uint64(uint32(row))<<24 | uint64(uint32(col)))
+}
+
+// Returns the key of a kind of tetromino in a slot: 0 for the active piece, 1
+// for the hold slot and 2 onwards for the queue.  An empty kind has no key.
+func kindKey(slot int, kind string) uint64 {
+	if kind == "" {
+		return 0
+	}
+	index := KindIndex(kind)
+	if index < 0 {
+		index = len(Kinds)
+	}
+	return zobrist(zobristKind | uint64(uint32(slot))<<8 | uint64(index))
+}
+
+// Returns a hash of a position for use as a transposition table key: the
+// blocks set on the board, the kind of the active piece, the kind held ("" for
+// none) and the kinds in the queue in order.  Only the kinds are hashed, not
+// where the active piece is, as searches usually start every piece from its
+// spawn position.
+func HashState(b *Board, active, held string, queue []string) uint64 {
+	hash := b.Hash() ^ kindKey(0, active) ^ kindKey(1, held)
+	for i, kind := range queue {
+		hash ^= kindKey(i+2, kind)
+	}
+	return hash
+}
+
+// Returns a hash of the game's position: its HashState along with where the
+// active piece is and whether hold can be used.  Frame counts, timers and the
+// score are not included.
+func (g *Game) Hash() uint64 {
+	var active, held string
+	var hash uint64
+	if g.active != nil {
+		active = g.active.Kind()
+		hash = zobrist(zobristPiece | uint64(g.active.Orient())<<48 |
+			uint64(uint16(g.row))<<16 | uint64(uint16(g.col)))
+	}
+	if g.held != nil {
+		held = g.held.Kind()
+	}
+	if g.HoldAvailable() {
+		hash ^= zobrist(zobristHold)
+	}
+	return hash ^ HashState(g.board, active, held, g.queue[:g.config.Previews])
+}
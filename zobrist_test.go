@@ -0,0 +1,120 @@
+package tetris
+
+import (
+	"testing"
+)
+
+// Hashes a board from scratch.
+func fullHash(b *Board) uint64 {
+	var hash uint64
+	for row := 0; row < b.Height(); row++ {
+		for col := 0; col < b.Width(); col++ {
+			if b.isSet(row, col) {
+				hash ^= zobristKey(row, col)
+			}
+		}
+	}
+	return hash
+}
+
+func TestBoardHashFollowsChanges(t *testing.T) {
+	for _, width := range []int{10, 70} {
+		board, _ := NewBoard(width, 6)
+		if board.Hash() != 0 {
+			t.Error("An empty board should hash to 0")
+		}
+
+		board.SetBlock(5, 3, true)
+		board.SetCell(4, 1, CellT)
+		board.SetRow(2, true)
+		board.SetCol(width-1, true)
+		board.SetBlock(2, 4, false)
+		board.CopyRow(2, 3)
+		board.SetRow(2, false)
+		board.SetBlock(5, 3, true)
+		if board.Hash() != fullHash(board) {
+			t.Errorf("The hash of a %d wide board should follow every change", width)
+		}
+
+		board.SetRow(5, true)
+		ClearFullLines(board)
+		if board.Hash() != fullHash(board) {
+			t.Errorf("The hash of a %d wide board should follow line clears", width)
+		}
+	}
+}
+
+func TestBoardHashIgnoresCells(t *testing.T) {
+	a, _ := NewBoard(10, 4)
+	b, _ := NewBoard(10, 4)
+	a.SetCell(3, 0, CellI)
+	b.SetCell(3, 0, CellGarbage)
+	if a.Hash() != b.Hash() {
+		t.Error("Boards with the same blocks set should hash the same")
+	}
+
+	b.SetCell(3, 1, CellI)
+	if a.Hash() == b.Hash() || a.Equal(b) {
+		t.Error("Boards with different blocks set should differ")
+	}
+	if c := b.Copy(); c.Hash() != b.Hash() {
+		t.Error("A copy should keep the hash")
+	}
+}
+
+func TestPlaceKeepsHash(t *testing.T) {
+	board, _ := NewBoard(10, 20)
+	tet, _ := NewTetrominoFor(SRS, "T", 0)
+	placed, _ := Place(SRS, board, tet, 19, 4)
+	if placed.Hash() != fullHash(placed) || placed.Hash() == board.Hash() {
+		t.Error("Placing a piece should update the hash of the new board only")
+	}
+}
+
+func TestHashState(t *testing.T) {
+	board, _ := NewBoard(10, 20)
+	base := HashState(board, "T", "", []string{"I", "O"})
+
+	if HashState(board, "T", "", []string{"I", "O"}) != base {
+		t.Error("The same state should hash the same")
+	}
+	if HashState(board, "I", "", []string{"I", "O"}) == base {
+		t.Error("The active kind should change the hash")
+	}
+	if HashState(board, "T", "S", []string{"I", "O"}) == base {
+		t.Error("The held kind should change the hash")
+	}
+	if HashState(board, "T", "", []string{"O", "I"}) == base {
+		t.Error("The order of the queue should change the hash")
+	}
+	if HashState(board, "T", "", nil) != board.Hash()^kindKey(0, "T") {
+		t.Error("An empty hold and queue should add nothing to the hash")
+	}
+}
+
+func TestGameHash(t *testing.T) {
+	a := newInputGame(t, "T", "I", "O")
+	b := newInputGame(t, "T", "I", "O")
+	if a.Hash() != b.Hash() {
+		t.Error("Games in the same position should hash the same")
+	}
+
+	a.MoveLeft()
+	if a.Hash() == b.Hash() {
+		t.Error("Moving the active piece should change the hash")
+	}
+	a.MoveRight()
+	if a.Hash() != b.Hash() {
+		t.Error("Moving back should restore the hash")
+	}
+
+	a.Hold()
+	b.Hold()
+	if a.Hash() != b.Hash() {
+		t.Error("Games that both held should hash the same")
+	}
+	c := newInputGame(t, "I", "T", "O")
+	if a.Hash() == c.Hash() {
+		t.Error("Holding should change the hash even with the same pieces in play")
+	}
+}